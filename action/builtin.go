@@ -0,0 +1,145 @@
+package action
+
+import (
+	"context"
+	"log/slog"
+
+	"wincuts/keyboard/shortcut"
+)
+
+// Env holds the services the built-in actions act on. The application fills it in; actions registered
+// by embedders may ignore it and close over their own services instead.
+type Env struct {
+	Desktops Desktops
+	Modes    Modes
+	Display  DesktopDisplay // Shows the current desktop, e.g. the tray icon; nil shows nothing
+	State    State          // Running system parameters are checked against by Registry.CheckLive; nil skips the checks
+}
+
+// Desktops manages virtual desktops, which it numbers from 0.
+type Desktops interface {
+	CreateNewDesktop()
+	SwitchToDesktop(desktop int)
+	// MoveWindowToDesktop moves the window with the given native handle; zero moves the foreground window.
+	MoveWindowToDesktop(window uintptr, desktop int)
+}
+
+// Modes switches binding modes, see shortcut.Service.
+type Modes interface {
+	EnterMode(name string) error
+	ExitMode()
+}
+
+// DesktopDisplay shows the current desktop, numbered from 1.
+type DesktopDisplay interface {
+	UpdateDesktop(desktop int) error
+}
+
+// builtins returns the actions WinCuts ships with.
+func builtins() []Definition {
+	return []Definition{
+		{
+			Name:        "SwitchDesktop",
+			Description: "Switch to the specified virtual desktop",
+			Category:    "Desktop",
+			Params:      []Param{{Name: "desktop", Type: Desktop, Description: "Desktop number, counting from 1"}},
+			New:         newSwitchDesktop,
+		},
+		{
+			Name:        "MoveWindowToDesktop",
+			Description: "Move the active window to specified desktop and, unless follow is false, switch to it",
+			Category:    "Desktop",
+			Params: []Param{
+				{Name: "desktop", Type: Desktop, Description: "Desktop number, counting from 1"},
+				{Name: "follow", Type: Bool, Description: "Switch to the desktop along with the window", Optional: true, Default: "true"},
+			},
+			New: newMoveWindowToDesktop,
+		},
+		{
+			Name:        "CreateDesktop",
+			Description: "Create a new virtual desktop",
+			Category:    "Desktop",
+			New:         newCreateDesktop,
+		},
+		{
+			Name:        "EnterMode",
+			Description: "Activate the bindings of a named mode",
+			Category:    "Mode",
+			Params:      []Param{{Name: "mode", Type: ModeName, Description: "Name of a mode in the modes section"}},
+			New:         newEnterMode,
+		},
+		{
+			Name:        "ExitMode",
+			Description: "Leave the current mode and return to the default bindings",
+			Category:    "Mode",
+			New:         newExitMode,
+		},
+	}
+}
+
+// desktopParam returns the desktop parameter as the 0-based index of Desktops.
+func desktopParam(args Args) int {
+	return args.Int("desktop") - 1
+}
+
+// showDesktop shows the 0-based desktop on the display of env, if any.
+func (env Env) showDesktop(desktop int) {
+	if env.Display == nil {
+		return
+	}
+	if err := env.Display.UpdateDesktop(desktop + 1); err != nil {
+		slog.Error("failed to update system tray", "error", err)
+	}
+}
+
+func newSwitchDesktop(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+	desktop := desktopParam(args)
+	return func(ctx context.Context, ac shortcut.ActionContext) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		env.Desktops.SwitchToDesktop(desktop)
+		env.showDesktop(desktop)
+		return nil
+	}, nil
+}
+
+func newMoveWindowToDesktop(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+	desktop, follow := desktopParam(args), args.Bool("follow")
+	return func(ctx context.Context, ac shortcut.ActionContext) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Move the window focused when the keys were pressed, even if the action had to wait
+		env.Desktops.MoveWindowToDesktop(ac.Window.Handle, desktop)
+		if follow {
+			env.Desktops.SwitchToDesktop(desktop)
+			env.showDesktop(desktop)
+		}
+		return nil
+	}, nil
+}
+
+func newCreateDesktop(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+	return func(ctx context.Context, ac shortcut.ActionContext) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		env.Desktops.CreateNewDesktop()
+		return nil
+	}, nil
+}
+
+func newEnterMode(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+	mode := args.String("mode")
+	return func(ctx context.Context, ac shortcut.ActionContext) error {
+		return env.Modes.EnterMode(mode)
+	}, nil
+}
+
+func newExitMode(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+	return func(ctx context.Context, ac shortcut.ActionContext) error {
+		env.Modes.ExitMode()
+		return nil
+	}, nil
+}
@@ -0,0 +1,355 @@
+package action
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"wincuts/keyboard/shortcut"
+)
+
+// ParamType describes the values a parameter accepts and converts them for the factory.
+type ParamType struct {
+	Name  string                             // Shown in listings, e.g. "int 1-10"
+	Parse func(value string) (any, error)    // Converts a value; the error says what is expected
+	Live  func(value any, state State) error // Optionally checks a converted value against the running system
+}
+
+// State is the running system that parameters may be checked against, see Definition.CheckLive.
+type State interface {
+	DesktopCount() int // Number of virtual desktops
+}
+
+// Int accepts whole numbers from min to max.
+func Int(min, max int) ParamType {
+	name := fmt.Sprintf("int %d-%d", min, max)
+	if max == math.MaxInt {
+		name = fmt.Sprintf("int from %d", min)
+	}
+	return ParamType{Name: name, Parse: func(value string) (any, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("must be a whole number")
+		}
+		if n < min || n > max {
+			if max == math.MaxInt {
+				return nil, fmt.Errorf("must be %d or more", min)
+			}
+			return nil, fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return n, nil
+	}}
+}
+
+// Enum accepts one of values, ignoring case, and converts it to the value as listed.
+func Enum(values ...string) ParamType {
+	return ParamType{Name: strings.Join(values, "|"), Parse: func(value string) (any, error) {
+		i := slices.IndexFunc(values, func(v string) bool { return strings.EqualFold(v, strings.TrimSpace(value)) })
+		if i < 0 {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		}
+		return values[i], nil
+	}}
+}
+
+// Parameter types.
+var (
+	// Bool accepts true or false, also written yes/no or on/off as in YAML.
+	Bool = ParamType{Name: "bool", Parse: func(value string) (any, error) {
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true", "yes", "on":
+			return true, nil
+		case "false", "no", "off":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be true or false")
+	}}
+	// String accepts any text that is not blank.
+	String = ParamType{Name: "string", Parse: func(value string) (any, error) {
+		if strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("must not be empty")
+		}
+		return value, nil
+	}}
+	// Duration accepts a positive Go duration such as "250ms" or "1m30s".
+	Duration = ParamType{Name: "duration", Parse: func(value string) (any, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("must be a positive duration such as 500ms or 2s")
+		}
+		return d, nil
+	}}
+	// Regex accepts a regular expression and converts it to a *regexp.Regexp.
+	Regex = ParamType{Name: "regex", Parse: func(value string) (any, error) {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be a regular expression: %w", err)
+		}
+		return re, nil
+	}}
+	// WindowSelector accepts windows selected like the when and unless rules of bindings, written as
+	// "process=a.exe,b.exe; class=Name; title=regex" with at least one part, and converts them to a *shortcut.WindowRule.
+	WindowSelector = ParamType{Name: "window", Parse: parseWindowSelector}
+	// Desktop accepts a virtual desktop number counting from 1. Checked live, it must be a desktop that exists.
+	Desktop = ParamType{Name: "desktop", Parse: Int(1, math.MaxInt).Parse, Live: func(value any, state State) error {
+		if count := state.DesktopCount(); value.(int) > count {
+			return fmt.Errorf("there are only %d desktops", count)
+		}
+		return nil
+	}}
+	// ModeName accepts the name of a binding mode.
+	ModeName = ParamType{Name: "mode", Parse: String.Parse}
+)
+
+// parseWindowSelector converts a window selector, see WindowSelector.
+func parseWindowSelector(value string) (any, error) {
+	var rule shortcut.WindowRule
+	for _, part := range strings.Split(value, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val)
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%q must be written as process=..., class=... or title=...", strings.TrimSpace(part))
+		}
+		switch key {
+		case "process":
+			rule.Processes = append(rule.Processes, splitList(val)...)
+		case "class":
+			rule.Classes = append(rule.Classes, splitList(val)...)
+		case "title":
+			re, err := regexp.Compile(val)
+			if err != nil {
+				return nil, fmt.Errorf("invalid title pattern: %w", err)
+			}
+			rule.Title = re
+		default:
+			return nil, fmt.Errorf("unknown window property %q, want process, class or title", key)
+		}
+	}
+	if len(rule.Processes) == 0 && len(rule.Classes) == 0 && rule.Title == nil {
+		return nil, fmt.Errorf("must select a process, class or title")
+	}
+	return &rule, nil
+}
+
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Param describes a parameter of an action.
+type Param struct {
+	Name        string // Name used in the config file, e.g. "desktop"
+	Type        ParamType
+	Description string
+	Optional    bool   // The binding may leave the parameter out
+	Default     string // Value of an optional parameter left out; empty leaves it out of the Args
+}
+
+// Arg is a parameter value written in a binding, by name or by position when Name is empty.
+type Arg struct {
+	Name  string
+	Value string
+}
+
+// ParamError reports a parameter value of a binding that its action does not accept.
+type ParamError struct {
+	Param string // Name of the parameter
+	Value string
+	Index int // Position of the value among the binding's parameters, -1 for a missing parameter
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %v", e.Param, e.Err)
+	}
+	return fmt.Sprintf("%s %q: %v", e.Param, e.Value, e.Err)
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// errMissing reports a required parameter that a binding does not give.
+var errMissing = errors.New("missing required parameter")
+
+// Args holds the converted parameters of a binding by name.
+type Args map[string]any
+
+// Has reports whether the parameter was given or has a default.
+func (a Args) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+// Int returns an Int or Desktop parameter, zero if absent.
+func (a Args) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+// Bool returns a Bool parameter, false if absent.
+func (a Args) Bool(name string) bool {
+	v, _ := a[name].(bool)
+	return v
+}
+
+// String returns a String, ModeName or Enum parameter, empty if absent.
+func (a Args) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+// Duration returns a Duration parameter, zero if absent.
+func (a Args) Duration(name string) time.Duration {
+	v, _ := a[name].(time.Duration)
+	return v
+}
+
+// Regexp returns a Regex parameter, nil if absent.
+func (a Args) Regexp(name string) *regexp.Regexp {
+	v, _ := a[name].(*regexp.Regexp)
+	return v
+}
+
+// Window returns a WindowSelector parameter, nil if absent.
+func (a Args) Window(name string) *shortcut.WindowRule {
+	v, _ := a[name].(*shortcut.WindowRule)
+	return v
+}
+
+// Parse converts the parameters of a binding, given either all by position in the order of d.Params
+// or all by name. A value the action does not accept is reported as a *ParamError.
+func (d Definition) Parse(params []Arg) (Args, error) {
+	named := slices.ContainsFunc(params, func(arg Arg) bool { return arg.Name != "" })
+	given := make(map[string]int) // Index of each parameter's value in params
+	for i, arg := range params {
+		if named != (arg.Name != "") {
+			return nil, fmt.Errorf("parameters must all be given by name or all by position")
+		}
+		if !named {
+			if i >= len(d.Params) {
+				return nil, d.countError()
+			}
+			given[d.Params[i].Name] = i
+			continue
+		}
+		if !slices.ContainsFunc(d.Params, func(p Param) bool { return p.Name == arg.Name }) {
+			return nil, &ParamError{Param: arg.Name, Value: arg.Value, Index: i, Err: fmt.Errorf("unknown parameter, %s", d.paramNames())}
+		}
+		if _, ok := given[arg.Name]; ok {
+			return nil, &ParamError{Param: arg.Name, Value: arg.Value, Index: i, Err: fmt.Errorf("given twice")}
+		}
+		given[arg.Name] = i
+	}
+
+	args := make(Args)
+	for _, param := range d.Params {
+		i, ok := given[param.Name]
+		value := param.Default
+		switch {
+		case ok:
+			value = params[i].Value
+		case !param.Optional:
+			if !named {
+				return nil, d.countError()
+			}
+			return nil, &ParamError{Param: param.Name, Index: -1, Err: errMissing}
+		case value == "":
+			continue
+		default:
+			i = -1
+		}
+		converted, err := param.Type.Parse(value)
+		if err != nil {
+			return nil, &ParamError{Param: param.Name, Value: value, Index: i, Err: err}
+		}
+		args[param.Name] = converted
+	}
+	return args, nil
+}
+
+// CheckLive checks converted parameters against the running system, for the parameter types that can be.
+func (d Definition) CheckLive(args Args, state State) error {
+	for _, param := range d.Params {
+		value, ok := args[param.Name]
+		if !ok || param.Type.Live == nil {
+			continue
+		}
+		if err := param.Type.Live(value, state); err != nil {
+			return &ParamError{Param: param.Name, Value: fmt.Sprint(value), Index: -1, Err: err}
+		}
+	}
+	return nil
+}
+
+// checkParams checks that the parameters have unique names and a type, that the optional ones come last
+// so a list can leave them out, and that their defaults are valid.
+func (d Definition) checkParams() error {
+	seen := make(map[string]bool)
+	for i, param := range d.Params {
+		switch {
+		case param.Name == "":
+			return fmt.Errorf("parameter %d has no name", i+1)
+		case seen[param.Name]:
+			return fmt.Errorf("parameter %s is declared twice", param.Name)
+		case param.Type.Parse == nil:
+			return fmt.Errorf("parameter %s has no type", param.Name)
+		case !param.Optional && i > 0 && d.Params[i-1].Optional:
+			return fmt.Errorf("required parameter %s follows an optional one", param.Name)
+		}
+		seen[param.Name] = true
+		if param.Default != "" {
+			if _, err := param.Type.Parse(param.Default); err != nil {
+				return fmt.Errorf("default of parameter %s: %w", param.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// countError reports parameters given by position that do not fit the definition.
+func (d Definition) countError() error {
+	required := 0
+	for _, param := range d.Params {
+		if !param.Optional {
+			required++
+		}
+	}
+	switch {
+	case len(d.Params) == 0:
+		return fmt.Errorf("%s takes no parameters", d.Name)
+	case required == len(d.Params) && required == 1:
+		return fmt.Errorf("%s requires exactly one parameter", d.Name)
+	case required == len(d.Params):
+		return fmt.Errorf("%s requires %d parameters", d.Name, required)
+	default:
+		return fmt.Errorf("%s takes %d to %d parameters", d.Name, required, len(d.Params))
+	}
+}
+
+// paramNames lists the parameters of the definition for error messages.
+func (d Definition) paramNames() string {
+	if len(d.Params) == 0 {
+		return d.Name + " takes no parameters"
+	}
+	names := make([]string, len(d.Params))
+	for i, param := range d.Params {
+		names[i] = param.Name
+	}
+	return "want " + strings.Join(names, ", ")
+}
@@ -0,0 +1,129 @@
+package action
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParamTypes verifies what each parameter type accepts and converts values to.
+func TestParamTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		typ   ParamType
+		value string
+		want  any
+		err   string
+	}{
+		{name: "int", typ: Int(1, 10), value: " 7 ", want: 7},
+		{name: "int below range", typ: Int(1, 10), value: "0", err: "must be between 1 and 10"},
+		{name: "int not a number", typ: Int(1, 10), value: "seven", err: "must be a whole number"},
+		{name: "enum ignores case", typ: Enum("left", "right"), value: "Right", want: "right"},
+		{name: "enum unknown", typ: Enum("left", "right"), value: "up", err: "must be one of left, right"},
+		{name: "bool", typ: Bool, value: "false", want: false},
+		{name: "bool yes", typ: Bool, value: "yes", want: true},
+		{name: "bool invalid", typ: Bool, value: "maybe", err: "must be true or false"},
+		{name: "string blank", typ: String, value: " ", err: "must not be empty"},
+		{name: "duration", typ: Duration, value: "1m30s", want: 90 * time.Second},
+		{name: "duration negative", typ: Duration, value: "-1s", err: "must be a positive duration"},
+		{name: "regex invalid", typ: Regex, value: "(", err: "must be a regular expression"},
+		{name: "desktop", typ: Desktop, value: "3", want: 3},
+		{name: "desktop zero", typ: Desktop, value: "0", err: "must be 1 or more"},
+		{name: "window unknown property", typ: WindowSelector, value: "exe=code.exe", err: `unknown window property "exe"`},
+		{name: "window empty", typ: WindowSelector, value: " ; ", err: "must select a process, class or title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.typ.Parse(tt.value)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	rule, err := WindowSelector.Parse("process=code.exe, devenv.exe; title=^Admin")
+	require.NoError(t, err)
+	args := Args{"window": rule}
+	assert.Equal(t, []string{"code.exe", "devenv.exe"}, args.Window("window").Processes)
+	assert.True(t, args.Window("window").Title.MatchString("Administrator"))
+}
+
+// TestDefinitionParse verifies that parameters given by position or by name are converted, with
+// errors naming the parameter and value at fault.
+func TestDefinitionParse(t *testing.T) {
+	switchDesktop, ok := Default.Lookup("SwitchDesktop")
+	require.True(t, ok)
+	moveWindow, ok := Default.Lookup("MoveWindowToDesktop")
+	require.True(t, ok)
+	createDesktop, ok := Default.Lookup("CreateDesktop")
+	require.True(t, ok)
+	enterMode, ok := Default.Lookup("EnterMode")
+	require.True(t, ok)
+
+	args, err := switchDesktop.Parse([]Arg{{Value: "3"}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, args.Int("desktop"))
+
+	args, err = moveWindow.Parse([]Arg{{Value: "2"}})
+	require.NoError(t, err)
+	assert.True(t, args.Bool("follow"), "follow defaults to true")
+	args, err = moveWindow.Parse([]Arg{{Name: "follow", Value: "false"}, {Name: "desktop", Value: "3"}})
+	require.NoError(t, err)
+	assert.Equal(t, Args{"desktop": 3, "follow": false}, args, "named parameters may come in any order")
+
+	tests := []struct {
+		name   string
+		def    Definition
+		params []Arg
+		err    string
+		param  string // Parameter a *ParamError must name, if any
+		index  int
+	}{
+		{name: "missing", def: switchDesktop, err: "requires exactly one parameter"},
+		{name: "too many", def: moveWindow, params: []Arg{{Value: "1"}, {Value: "true"}, {Value: "x"}}, err: "takes 1 to 2 parameters"},
+		{name: "none taken", def: createDesktop, params: []Arg{{Value: "1"}}, err: "takes no parameters"},
+		{name: "bad value", def: switchDesktop, params: []Arg{{Value: "zero"}}, err: `desktop "zero": must be a whole number`, param: "desktop"},
+		{name: "bad named value", def: moveWindow, params: []Arg{{Name: "desktop", Value: "2"}, {Name: "follow", Value: "nope"}},
+			err: `follow "nope": must be true or false`, param: "follow", index: 1},
+		{name: "unknown name", def: moveWindow, params: []Arg{{Name: "desk", Value: "2"}}, err: "unknown parameter, want desktop, follow", param: "desk"},
+		{name: "missing name", def: moveWindow, params: []Arg{{Name: "follow", Value: "true"}}, err: "desktop: missing required parameter", param: "desktop", index: -1},
+		{name: "given twice", def: switchDesktop, params: []Arg{{Name: "desktop", Value: "1"}, {Name: "desktop", Value: "2"}}, err: "given twice", param: "desktop", index: 1},
+		{name: "mixed", def: moveWindow, params: []Arg{{Value: "1"}, {Name: "follow", Value: "true"}}, err: "all be given by name or all by position"},
+		{name: "blank mode", def: enterMode, params: []Arg{{Value: " "}}, err: "must not be empty", param: "mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.def.Parse(tt.params)
+			require.ErrorContains(t, err, tt.err)
+			var paramErr *ParamError
+			if tt.param == "" {
+				assert.False(t, errors.As(err, &paramErr))
+				return
+			}
+			require.ErrorAs(t, err, &paramErr)
+			assert.Equal(t, tt.param, paramErr.Param)
+			assert.Equal(t, tt.index, paramErr.Index)
+		})
+	}
+}
+
+// fixedState is a system with a fixed number of desktops.
+type fixedState int
+
+func (s fixedState) DesktopCount() int { return int(s) }
+
+// TestCheckLive verifies that parameters are checked against the running system only when it is known.
+func TestCheckLive(t *testing.T) {
+	env := Env{State: fixedState(4)}
+	assert.NoError(t, Default.CheckLive(env, "SwitchDesktop", []Arg{{Value: "4"}}))
+	assert.ErrorContains(t, Default.CheckLive(env, "SwitchDesktop", []Arg{{Value: "5"}}), "there are only 4 desktops")
+	assert.ErrorContains(t, Default.CheckLive(env, "MoveWindowToDesktop", []Arg{{Name: "desktop", Value: "9"}}), "desktop: there are only 4 desktops")
+	assert.NoError(t, Default.CheckLive(env, "EnterMode", []Arg{{Value: "resize"}}), "mode names are not checked live")
+	assert.NoError(t, Default.CheckLive(Env{}, "SwitchDesktop", []Arg{{Value: "5"}}), "no state skips the checks")
+}
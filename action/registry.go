@@ -0,0 +1,144 @@
+// Package action holds the actions that key bindings can run. Every action is registered once with its
+// name, description, parameters and a factory, so config validation, listings and execution share one definition.
+// Applications embedding WinCuts register their own actions with Register before the configuration is loaded.
+package action
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"wincuts/keyboard/shortcut"
+)
+
+// Factory creates the function run for a binding from its parameters, which Definition.Parse has converted.
+type Factory func(env Env, args Args) (shortcut.KeyBindingFunc, error)
+
+// Definition describes an action that bindings can run.
+type Definition struct {
+	Name        string  // Name used in the config file, e.g. "SwitchDesktop"
+	Description string  // What the action does, for listings and the schema
+	Category    string  // Group for listings, e.g. "Desktop"
+	Params      []Param // Parameters in the order they are given as a list
+	PassKeys    bool    // Let the keys of the binding reach the focused application too
+	New         Factory
+}
+
+// ParamTypes describes the parameters for listings as name:type, e.g. "count:int 1-10" or "[follow:bool]"
+// for an optional one. Parameters named after their type are listed by name only, e.g. "desktop".
+func (d Definition) ParamTypes() []string {
+	types := make([]string, len(d.Params))
+	for i, param := range d.Params {
+		types[i] = param.Name
+		if param.Type.Name != param.Name {
+			types[i] += ":" + param.Type.Name
+		}
+		if param.Optional {
+			types[i] = "[" + types[i] + "]"
+		}
+	}
+	return types
+}
+
+// Registry holds action definitions by name. It is safe for concurrent use.
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]Definition
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{definitions: make(map[string]Definition)}
+}
+
+// Default holds the built-in actions and those registered with Register.
+var Default = newDefaultRegistry()
+
+// newDefaultRegistry creates a registry with the built-in actions.
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, def := range builtins() {
+		if err := r.Register(def); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds an action to the Default registry.
+func Register(def Definition) error {
+	return Default.Register(def)
+}
+
+// Register adds an action. Its name must be unique, it needs a factory and its parameters must be well-formed.
+func (r *Registry) Register(def Definition) error {
+	if def.Name == "" {
+		return fmt.Errorf("action without a name")
+	}
+	if def.New == nil {
+		return fmt.Errorf("action %s has no factory", def.Name)
+	}
+	if err := def.checkParams(); err != nil {
+		return fmt.Errorf("action %s: %w", def.Name, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[def.Name]; ok {
+		return fmt.Errorf("action %s is already registered", def.Name)
+	}
+	def.Params = slices.Clone(def.Params)
+	r.definitions[def.Name] = def
+	return nil
+}
+
+// Lookup returns the definition of the named action.
+func (r *Registry) Lookup(name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.definitions[name]
+	return def, ok
+}
+
+// Definitions returns every definition sorted by name.
+func (r *Registry) Definitions() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := slices.Sorted(maps.Keys(r.definitions))
+	defs := make([]Definition, len(names))
+	for i, name := range names {
+		defs[i] = r.definitions[name]
+	}
+	return defs
+}
+
+// Build converts the parameters of the named action and creates its function.
+func (r *Registry) Build(env Env, name string, params []Arg) (shortcut.KeyBindingFunc, Definition, error) {
+	def, ok := r.Lookup(name)
+	if !ok {
+		return nil, Definition{}, fmt.Errorf("unknown action: %s", name)
+	}
+	args, err := def.Parse(params)
+	if err != nil {
+		return nil, def, err
+	}
+	fn, err := def.New(env, args)
+	if err != nil {
+		return nil, def, fmt.Errorf("%s: %w", name, err)
+	}
+	return fn, def, nil
+}
+
+// CheckLive checks the parameters of the named action against the state of env, e.g. that a desktop exists.
+// Unlike Build it reports values that are valid but may not work right now, so callers usually only warn.
+func (r *Registry) CheckLive(env Env, name string, params []Arg) error {
+	def, ok := r.Lookup(name)
+	if !ok || env.State == nil {
+		return nil
+	}
+	args, err := def.Parse(params)
+	if err != nil {
+		return nil // Reported by Build
+	}
+	return def.CheckLive(args, env.State)
+}
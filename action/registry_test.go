@@ -0,0 +1,98 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/keyboard/shortcut"
+)
+
+// fakeDesktops records the desktop operations of the built-in actions.
+type fakeDesktops struct {
+	created  int
+	switched []int
+	moved    []uintptr
+}
+
+func (f *fakeDesktops) CreateNewDesktop()           { f.created++ }
+func (f *fakeDesktops) SwitchToDesktop(desktop int) { f.switched = append(f.switched, desktop) }
+func (f *fakeDesktops) MoveWindowToDesktop(window uintptr, desktop int) {
+	f.moved = append(f.moved, window)
+}
+
+// fakeModes records the mode changes of the built-in actions.
+type fakeModes struct {
+	mode string
+}
+
+func (f *fakeModes) EnterMode(name string) error { f.mode = name; return nil }
+func (f *fakeModes) ExitMode()                   { f.mode = "" }
+
+// TestRegistryRegister verifies that actions are registered once by name and listed in order.
+func TestRegistryRegister(t *testing.T) {
+	noop := func(env Env, args Args) (shortcut.KeyBindingFunc, error) {
+		return func(context.Context, shortcut.ActionContext) error { return nil }, nil
+	}
+	r := NewRegistry()
+	require.NoError(t, r.Register(Definition{Name: "Zoom", Category: "Window", New: noop}))
+	require.NoError(t, r.Register(Definition{Name: "Beep", New: noop}))
+
+	assert.ErrorContains(t, r.Register(Definition{Name: "Zoom", New: noop}), "already registered")
+	assert.ErrorContains(t, r.Register(Definition{New: noop}), "without a name")
+	assert.ErrorContains(t, r.Register(Definition{Name: "Nothing"}), "has no factory")
+	assert.ErrorContains(t, r.Register(Definition{Name: "Twice", New: noop, Params: []Param{{Name: "n", Type: Bool}, {Name: "n", Type: Bool}}}), "declared twice")
+	assert.ErrorContains(t, r.Register(Definition{Name: "Untyped", New: noop, Params: []Param{{Name: "n"}}}), "has no type")
+	assert.ErrorContains(t, r.Register(Definition{Name: "Late", New: noop, Params: []Param{{Name: "a", Type: Bool, Optional: true}, {Name: "b", Type: Bool}}}), "follows an optional one")
+	assert.ErrorContains(t, r.Register(Definition{Name: "BadDefault", New: noop, Params: []Param{{Name: "n", Type: Int(1, 3), Optional: true, Default: "9"}}}), "default of parameter n")
+
+	var names []string
+	for _, def := range r.Definitions() {
+		names = append(names, def.Name)
+	}
+	assert.Equal(t, []string{"Beep", "Zoom"}, names)
+	def, ok := r.Lookup("Zoom")
+	require.True(t, ok)
+	assert.Equal(t, "Window", def.Category)
+}
+
+// TestBuiltinActions verifies what the built-in actions do to the services of their environment.
+func TestBuiltinActions(t *testing.T) {
+	desktops := &fakeDesktops{}
+	modes := &fakeModes{}
+	env := Env{Desktops: desktops, Modes: modes}
+	run := func(name string, params []Arg, ac shortcut.ActionContext) {
+		t.Helper()
+		fn, def, err := Default.Build(env, name, params)
+		require.NoError(t, err)
+		assert.False(t, def.PassKeys)
+		require.NoError(t, fn(context.Background(), ac))
+	}
+
+	run("SwitchDesktop", []Arg{{Value: "2"}}, shortcut.ActionContext{})
+	run("MoveWindowToDesktop", []Arg{{Value: "4"}}, shortcut.ActionContext{Window: shortcut.Window{Handle: 0x42}})
+	run("MoveWindowToDesktop", []Arg{{Name: "desktop", Value: "3"}, {Name: "follow", Value: "false"}}, shortcut.ActionContext{})
+	run("CreateDesktop", nil, shortcut.ActionContext{})
+	assert.Equal(t, []int{1, 3}, desktops.switched, "desktops are numbered from 0, and follow: false stays put")
+	assert.Equal(t, []uintptr{0x42, 0}, desktops.moved, "the window focused when the keys were pressed moves")
+	assert.Equal(t, 1, desktops.created)
+
+	run("EnterMode", []Arg{{Value: "resize"}}, shortcut.ActionContext{})
+	assert.Equal(t, "resize", modes.mode)
+	run("ExitMode", nil, shortcut.ActionContext{})
+	assert.Empty(t, modes.mode)
+
+	_, _, err := Default.Build(env, "Teleport", nil)
+	assert.ErrorContains(t, err, "unknown action")
+	_, _, err = Default.Build(env, "SwitchDesktop", []Arg{{Value: "x"}})
+	assert.ErrorContains(t, err, `desktop "x": must be a whole number`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fn, _, err := Default.Build(env, "CreateDesktop", nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, fn(ctx, shortcut.ActionContext{}), context.Canceled)
+	assert.Equal(t, 1, desktops.created, "a cancelled action does nothing")
+}
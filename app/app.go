@@ -3,18 +3,23 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
-	"strconv"
+	"regexp"
+	"sync"
 
+	"wincuts/action"
+	"wincuts/clock"
 	"wincuts/config"
 	"wincuts/keyboard"
 	"wincuts/keyboard/shortcut"
 	"wincuts/keyboard/types"
 	"wincuts/systray"
 	"wincuts/virtd"
+	"wincuts/window"
 
 	winapi "github.com/chrsm/winapi"
 	"github.com/chrsm/winapi/user"
@@ -52,10 +57,44 @@ func (v VirtdDesktopManager) SwitchToDesktop(desktopNumber int) {
 	virtd.GoToDesktopNumber(desktopNumber)
 }
 
+// CurrentDesktop implements shortcut.DesktopProvider, counting from 1.
+func (v VirtdDesktopManager) CurrentDesktop() (int, error) {
+	current := virtd.GetCurrentDesktopNumber()
+	if current < 0 {
+		return 0, fmt.Errorf("failed to get the current desktop")
+	}
+	return current + 1, nil
+}
+
 func (v VirtdDesktopManager) MoveWindowToDesktop(window winapi.HWND, desktopNumber int) {
 	virtd.MoveWindowToDesktopNumber(window, desktopNumber)
 }
 
+// actionDesktops adapts a DesktopManager to the desktops the built-in actions act on.
+type actionDesktops struct {
+	DesktopManager
+}
+
+// MoveWindowToDesktop implements action.Desktops, moving the foreground window for a zero handle.
+func (d actionDesktops) MoveWindowToDesktop(window uintptr, desktopNumber int) {
+	hwnd := winapi.HWND(window)
+	if hwnd == 0 {
+		hwnd = user.GetForegroundWindow()
+	}
+	d.DesktopManager.MoveWindowToDesktop(hwnd, desktopNumber)
+}
+
+// DesktopCount implements action.State.
+func (d actionDesktops) DesktopCount() int {
+	return d.GetCurrentDesktopCount()
+}
+
+// actionEnv collects the services the built-in actions act on.
+func actionEnv(dm DesktopManager, traySvc *systray.Service, keybindService *shortcut.Service) action.Env {
+	desktops := actionDesktops{dm}
+	return action.Env{Desktops: desktops, Modes: keybindService, Display: traySvc, State: desktops}
+}
+
 // EnsureMinimumDesktops enforces a minimum available desktop count at runtime.
 // This is crucial for features that depend on several desktops being present, and ensures consistent behavior across environments.
 func EnsureMinimumDesktops(dm DesktopManager, minCount int) {
@@ -65,21 +104,18 @@ func EnsureMinimumDesktops(dm DesktopManager, minCount int) {
 	}
 }
 
-// setupKeyBindings registers keyboard shortcuts for switching desktops and moving windows.
-func setupKeyBindings(dm DesktopManager, traySvc *systray.Service) *shortcut.Service {
-	keyChan := make(chan *shortcut.KeyBindingAction, 100)
-	svc := shortcut.NewService(keyChan, shortcut.NewMatcher())
-
-	// Load configuration
-	cfg, err := config.LoadConfigFromArgs(os.Args)
-	if err != nil {
-		slog.Error("failed to load configuration", "error", err)
-		// Use default config as fallback
-		cfg = config.DefaultConfig()
-	}
+// buildKeyBindings creates the key binding actions of the configured bindings from the action registry.
+// Custom modifiers in their keys are resolved in modifiers.
+func buildKeyBindings(bindings []config.KeyBinding, modifiers *types.Modifiers, env action.Env) []shortcut.KeyBindingAction {
+	var actions []shortcut.KeyBindingAction
 
 	// Register each configured binding
-	for _, binding := range cfg.Shortcuts.Bindings {
+	for _, binding := range bindings {
+		if !binding.IsEnabled() {
+			slog.Debug("skipped disabled shortcut", "keys", binding.Keys, "id", binding.ID, "description", binding.Description)
+			continue
+		}
+
 		// Validate the binding
 		if err := binding.Validate(); err != nil {
 			slog.Error("invalid key binding",
@@ -89,87 +125,211 @@ func setupKeyBindings(dm DesktopManager, traySvc *systray.Service) *shortcut.Ser
 			continue
 		}
 
-		// Create the appropriate action based on the binding type
-		var action func() error
-		var shouldBlock bool
+		// Create the action from its registered definition
+		run, def, err := action.Default.Build(env, binding.Action, binding.Params)
+		if err != nil {
+			slog.Error("failed to create action", "action", binding.Action, "params", binding.Params.String(), "error", err)
+			continue
+		}
+		// Registered regardless, since desktops may be created later
+		if err := action.Default.CheckLive(env, binding.Action, binding.Params); err != nil {
+			slog.Warn("key binding does not fit the current system", "keys", binding.Keys, "action", binding.Action, "error", err)
+		}
 
-		switch binding.Action {
-		case "SwitchDesktop":
-			if len(binding.Params) != 1 {
-				slog.Error("invalid parameters for SwitchDesktop", "params", binding.Params)
-				continue
-			}
-			desktop := parseDesktopNumber(binding.Params[0]) - 1 // Convert to 0-based index
-			action = func() error {
-				dm.SwitchToDesktop(desktop)
-				if err := traySvc.UpdateDesktop(desktop + 1); err != nil {
-					slog.Error("failed to update system tray", "error", err)
-				}
-				return nil
-			}
-			shouldBlock = true
+		steps := binding.GetKeySteps(modifiers)
+		bindingAction := shortcut.NewSequenceBindingAction(steps, run, !def.PassKeys)
+		bindingAction.Window = windowCondition(binding)
+		bindingAction.Trigger = trigger(binding)
+		bindingAction.Repeat = repeat(binding)
+		bindingAction.Modifiers = modifiers
+		bindingAction.ID = binding.ID
+		bindingAction.Description = binding.Description
+		bindingAction.Tags = binding.Tags
+		actions = append(actions, bindingAction)
 
-		case "MoveWindowToDesktop":
-			if len(binding.Params) != 1 {
-				slog.Error("invalid parameters for MoveWindowToDesktop", "params", binding.Params)
-				continue
-			}
-			desktop := parseDesktopNumber(binding.Params[0]) - 1 // Convert to 0-based index
-			action = func() error {
-				foregroundW := user.GetForegroundWindow()
-				dm.MoveWindowToDesktop(foregroundW, desktop)
-				dm.SwitchToDesktop(desktop)
-				if err := traySvc.UpdateDesktop(desktop + 1); err != nil {
-					slog.Error("failed to update system tray", "error", err)
-				}
-				return nil
-			}
-			shouldBlock = true
+		slog.Debug("registered shortcut",
+			"binding", &bindingAction,
+			"action", binding.Action,
+			"trigger", bindingAction.Trigger,
+			"repeat", bindingAction.Repeat)
+	}
 
-		case "CreateDesktop":
-			action = func() error {
-				dm.CreateNewDesktop()
-				return nil
-			}
-			shouldBlock = true
+	return actions
+}
+
+// trigger converts the trigger of a validated binding.
+func trigger(binding config.KeyBinding) shortcut.Trigger {
+	kind, duration, _ := binding.Trigger.Parse()
+	switch kind {
+	case config.TriggerDown:
+		return shortcut.Trigger{Kind: shortcut.TriggerDown}
+	case config.TriggerHold:
+		return shortcut.Trigger{Kind: shortcut.TriggerHold, Duration: duration}
+	case config.TriggerDouble:
+		return shortcut.Trigger{Kind: shortcut.TriggerDouble, Duration: duration}
+	}
+	return shortcut.Trigger{Kind: shortcut.TriggerUp}
+}
+
+// repeat converts the repeat policy of a validated binding.
+func repeat(binding config.KeyBinding) shortcut.Repeat {
+	kind, interval, _ := binding.Repeat.Parse()
+	switch kind {
+	case config.RepeatFire:
+		return shortcut.Repeat{Kind: shortcut.RepeatFire}
+	case config.RepeatThrottle:
+		return shortcut.Repeat{Kind: shortcut.RepeatThrottle, Interval: interval}
+	}
+	return shortcut.Repeat{Kind: shortcut.RepeatIgnore}
+}
+
+// windowCondition converts the window rules of a validated binding, returning nil for a binding that applies everywhere.
+func windowCondition(binding config.KeyBinding) *shortcut.WindowCondition {
+	if binding.When == nil && binding.Unless == nil {
+		return nil
+	}
+	return &shortcut.WindowCondition{When: windowRule(binding.When), Unless: windowRule(binding.Unless)}
+}
+
+// windowRule converts a validated config window rule.
+func windowRule(rule *config.WindowRule) *shortcut.WindowRule {
+	if rule == nil {
+		return nil
+	}
+	converted := &shortcut.WindowRule{Processes: rule.Process, Classes: rule.Class}
+	if rule.Title != "" {
+		converted.Title = regexp.MustCompile(rule.Title)
+	}
+	return converted
+}
+
+// buildModes creates the binding table of every configured mode.
+func buildModes(cfg *config.Config, modifiers *types.Modifiers, env action.Env) []shortcut.Mode {
+	var modes []shortcut.Mode
+	for name, mode := range cfg.Shortcuts.Modes {
+		modes = append(modes, shortcut.Mode{
+			Name:     name,
+			Timeout:  mode.Timeout,
+			Bindings: buildKeyBindings(mode.Bindings, modifiers, env),
+		})
+	}
+	return modes
+}
 
-		default:
-			slog.Error("unknown action type", "action", binding.Action)
+// buildLayers converts the configured layers, whose names are the custom modifiers in modifiers.
+func buildLayers(cfg *config.Config, modifiers *types.Modifiers) []keyboard.Layer {
+	var layers []keyboard.Layer
+	for name, layer := range cfg.Shortcuts.Layers {
+		modifier, ok := modifiers.LookupKey(name)
+		if !ok || !modifier.IsCustomModifier() {
+			slog.Error("invalid layer", "layer", name)
 			continue
 		}
+		key, tap := layer.GetKeys()
+		layers = append(layers, keyboard.Layer{Key: key, Modifier: modifier, Tap: tap, TapTimeout: layer.TapTimeout})
+	}
+	return layers
+}
+
+// buildRemaps converts the configured remaps, which config validation has checked.
+func buildRemaps(cfg *config.Config, modifiers *types.Modifiers) []keyboard.Remap {
+	var remaps []keyboard.Remap
+	for _, remap := range cfg.Shortcuts.Remap {
+		from, to := remap.GetKeys(modifiers)
+		remaps = append(remaps, keyboard.Remap{From: from, To: to})
+	}
+	return remaps
+}
 
-		// Register the binding
-		svc.RegisterKeyBindingActions(
-			shortcut.NewBindingAction(binding.GetVirtualKeys(), action, shouldBlock),
-		)
+// suspendRule converts the foreground windows that suspend shortcuts.
+func suspendRule(cfg *config.Config) keyboard.SuspendRule {
+	return keyboard.SuspendRule{FullScreen: cfg.Shortcuts.Suspend.FullScreen, Processes: cfg.Shortcuts.Suspend.Process}
+}
 
-		slog.Debug("registered shortcut",
-			"keys", types.NewKeybinding(binding.GetVirtualKeys()...).PrettyString(),
-			"action", binding.Action)
+// executionPolicy converts how the actions of fired shortcuts are run.
+func executionPolicy(cfg *config.Config) shortcut.ExecutionPolicy {
+	policy := shortcut.ExecutionPolicy{Timeout: cfg.Shortcuts.Execution.Timeout, Debounce: cfg.Shortcuts.Execution.Debounce}
+	if cfg.Shortcuts.Execution.Mode == config.ExecutionConcurrent {
+		policy.Mode = shortcut.ExecuteConcurrent
 	}
+	return policy
+}
 
-	return svc
+// dispatchLatest returns a function handing values to apply on a goroutine of its own until ctx is done, so a
+// caller on the keyboard hook thread never waits on the tray. Values handed over while apply is busy collapse into
+// the last one, which is always applied.
+func dispatchLatest[T any](ctx context.Context, apply func(T)) func(T) {
+	var mu sync.Mutex
+	var latest T
+	signal := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signal:
+				mu.Lock()
+				value := latest
+				mu.Unlock()
+				apply(value)
+			}
+		}
+	}()
+	return func(value T) {
+		mu.Lock()
+		latest = value
+		mu.Unlock()
+		select {
+		case signal <- struct{}{}:
+		default: // A signal is pending and will pick up the value
+		}
+	}
 }
 
-// parseDesktopNumber safely converts a string parameter to a desktop number
-func parseDesktopNumber(param string) int {
-	num, err := strconv.Atoi(param)
-	if err != nil {
-		slog.Error("failed to parse desktop number", "param", param, "error", err)
-		return 1
+// applyConfig swaps a reloaded configuration into the running services without restarting the process.
+// The custom modifiers of its layers replace those of the previous configuration, each service naming them from the
+// table it is handed along with the layers and bindings that use them.
+func applyConfig(cfg *config.Config, dm DesktopManager, traySvc *systray.Service, keybindService *shortcut.Service, hook *keyboard.Hook) {
+	env := actionEnv(dm, traySvc, keybindService)
+	config.SetupLogging(cfg)
+	config.LogConflicts(cfg)
+	modifiers := cfg.Shortcuts.Modifiers()
+	hook.SetLayers(modifiers, buildLayers(cfg, modifiers)...)
+	hook.SetRemaps(buildRemaps(cfg, modifiers)...)
+	hook.SetSuspendToggle(cfg.Shortcuts.Suspend.GetToggleKeys())
+	hook.SetSuspendRule(suspendRule(cfg))
+	keybindService.SetSequenceTimeout(cfg.Shortcuts.SequenceTimeout)
+	keybindService.SetExecutionPolicy(executionPolicy(cfg))
+	keybindService.ReplaceKeyBindingActions(buildKeyBindings(cfg.Shortcuts.Bindings, modifiers, env)...)
+	keybindService.ReplaceModes(buildModes(cfg, modifiers, env)...)
+	if err := traySvc.UpdateConfig(cfg.UI.TrayIcon); err != nil {
+		slog.Error("failed to apply tray icon config", "error", err)
 	}
-	return num
+	EnsureMinimumDesktops(dm, cfg.VirtualDesktops.MinimumCount)
 }
 
 // Run aggregates the initialization of system components (desktop environment, keyboard hook, key bindings)
 // and starts the user event loop. This separation of startup functionality enhances testability and maintainability.
-func Run() error {
-	// Load configuration
-	cfg, err := config.LoadConfigFromArgs(os.Args)
+func Run(loader *config.LayeredConfigLoader, version string) error {
+	// Load configuration once; the watcher keeps it current for the lifetime of the process.
+	watcher := config.NewWatcher(loader, clock.New(), config.DefaultWatchInterval)
+	cfg, err := watcher.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 	config.SetupLogging(cfg)
+	slog.Info("starting WinCuts", "version", version)
+	config.LogConflicts(cfg)
+	for _, layer := range loader.Layers() {
+		if layer.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(layer.Path); err != nil {
+			slog.Info("config file not found, watching for it", "layer", layer.Name, "path", layer.Path)
+			continue
+		}
+		slog.Info("using config file", "layer", layer.Name, "path", layer.Path)
+	}
 
 	// Initialize system tray
 	traySvc, err := systray.NewService(cfg.UI.TrayIcon)
@@ -183,20 +343,96 @@ func Run() error {
 	EnsureMinimumDesktops(dm, cfg.VirtualDesktops.MinimumCount)
 	slog.Info("virtual desktops initialized", "count", dm.GetCurrentDesktopCount(), "minimum", cfg.VirtualDesktops.MinimumCount)
 
-
-	keybindService := setupKeyBindings(dm, traySvc)
+	keybindService := shortcut.NewService(make(chan shortcut.ActionContext, 100), shortcut.NewMatcher())
+	// Window-scoped bindings are matched in the keyboard hook, which only reads a snapshot of the foreground window.
+	windows := shortcut.NewWindowCache(window.ForegroundProvider{})
+	windows.Refresh()
+	stopForegroundWatch, err := window.WatchForeground(windows.Refresh)
+	if err != nil {
+		slog.Warn("failed to watch the foreground window", "error", err)
+	} else {
+		defer stopForegroundWatch()
+	}
+	keybindService.SetWindowProvider(windows)
+	keybindService.SetDesktopProvider(dm)
+	env := actionEnv(dm, traySvc, keybindService)
+	keybindService.SetSequenceTimeout(cfg.Shortcuts.SequenceTimeout)
+	keybindService.SetExecutionPolicy(executionPolicy(cfg))
+	modifiers := cfg.Shortcuts.Modifiers()
+	keybindService.RegisterKeyBindingActions(buildKeyBindings(cfg.Shortcuts.Bindings, modifiers, env)...)
+	keybindService.ReplaceModes(buildModes(cfg, modifiers, env)...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Show the steps of a pending key sequence in the tray tooltip until it completes or is cancelled.
+	// The matcher reports them on the hook thread, which must not wait on the tray.
+	showPending := dispatchLatest(ctx, func(steps string) {
+		if err := traySvc.SetPending(steps); err != nil {
+			slog.Error("failed to show pending key sequence", "error", err)
+		}
+	})
+	keybindService.OnSequencePending(func(steps []types.KeyBinding, modifiers *types.Modifiers) {
+		showPending(shortcut.FormatSteps(steps, modifiers))
+	})
+	// Show the active binding mode in the tray tooltip.
+	keybindService.OnModeChange(func(mode string) {
+		if mode == shortcut.DefaultMode {
+			mode = ""
+		}
+		if err := traySvc.SetMode(mode); err != nil {
+			slog.Error("failed to show binding mode", "error", err)
+		}
+	})
 	// Initialize the keyboard hook; early exit if setup fails to ensure proper system state.
-	hook, err := keyboard.NewHook(keybindService)
+	hook, err := keyboard.NewHook(keybindService, keyboard.NewLowLevelHook(), keyboard.SendInputInjector{})
 	if err != nil {
 		return fmt.Errorf("failed to create keyboard hook: %w", err)
 	}
-	hook.Start()
+	hook.SetLayers(modifiers, buildLayers(cfg, modifiers)...)
+	hook.SetRemaps(buildRemaps(cfg, modifiers)...)
+	hook.SetSuspendToggle(cfg.Shortcuts.Suspend.GetToggleKeys())
+	hook.SetSuspendRule(suspendRule(cfg))
+	// Grey out the tray icon while suspended. The hook calls back on its own thread, which must not wait on the tray.
+	hook.OnSuspendChange(func(bool) {
+		go func() {
+			if err := traySvc.SetSuspended(hook.Suspended()); err != nil {
+				slog.Error("failed to show suspend state", "error", err)
+			}
+		}()
+	})
+	// Suspend and resume from the tray menu as with the toggle keys.
+	traySvc.OnSuspendMenu(hook.ToggleSuspend)
+	if err := hook.Start(); err != nil {
+		return err
+	}
+	defer hook.Stop()
 	slog.Info("keyboard hook initialized")
 
+	// Key releases made on the lock screen never reach the hook, so held keys are forgotten on lock and unlock.
+	stopSessionWatch, err := keyboard.WatchSession(func(locked bool) {
+		if locked {
+			hook.ResetKeys("session locked")
+		} else {
+			hook.ResetKeys("session unlocked")
+		}
+	})
+	if err != nil {
+		slog.Warn("failed to watch session lock", "error", err)
+	} else {
+		defer stopSessionWatch()
+	}
+
 	// Register keyboard shortcuts to facilitate rapid desktop management.
 	keybindService.Start()
+	defer keybindService.Stop() // Let the actions already fired finish
 	slog.Info("keyboard shortcuts registered")
 
+	// Watch the config file so edits take effect without a restart.
+	watcher.OnChange(func(cfg *config.Config) {
+		applyConfig(cfg, dm, traySvc, keybindService, hook)
+	})
+	go watcher.Run(ctx)
+	go hook.RunAutoSuspend(ctx, window.ForegroundProvider{})
+
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt)
 	slog.Info("started")
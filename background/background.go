@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"wincuts/app"
+	"wincuts/config"
 
 	"github.com/lxn/win"
 )
@@ -21,12 +22,12 @@ func HideConsoleWindow() {
 }
 
 // RunInBackground starts the application in background mode
-func RunInBackground() error {
+func RunInBackground(loader *config.LayeredConfigLoader, version string) error {
 	// Hide the console window
 	HideConsoleWindow()
 
 	// Run the application
-	if err := app.Run(); err != nil {
+	if err := app.Run(loader, version); err != nil {
 		return fmt.Errorf("application error: %w", err)
 	}
 
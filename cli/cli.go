@@ -0,0 +1,249 @@
+// Package cli implements the WinCuts command tree.
+// Commands report failures as errors and Execute turns them into exit codes, so nothing below main exits the process.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"wincuts/config"
+)
+
+// Exit codes returned by Execute.
+const (
+	ExitOK    = 0 // The command succeeded
+	ExitError = 1 // The command failed
+	ExitUsage = 2 // The command line was invalid
+)
+
+// RunOptions carries the settings for starting the application.
+type RunOptions struct {
+	Loader     *config.LayeredConfigLoader // Loads and reloads the layered configuration
+	Background bool                        // Hide the console window while running
+}
+
+// RunFunc starts the application and blocks until it exits.
+type RunFunc func(opts RunOptions) error
+
+// App is the command line entry point.
+type App struct {
+	Version string
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Env     config.Environment
+	Run     RunFunc
+}
+
+// New creates an App bound to the process streams and environment.
+func New(version string, run RunFunc) *App {
+	return &App{
+		Version: version,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Env:     config.OSEnvironment(),
+		Run:     run,
+	}
+}
+
+// command is a node in the command tree; either flags or subcommands is set.
+type command struct {
+	name        string
+	args        string // Positional argument synopsis shown in usage
+	summary     string
+	flags       func(fs *flag.FlagSet) func(a *App, args []string) error // Registers flags and returns the runner of a leaf command
+	subcommands []*command
+}
+
+// usageError reports an invalid command line; Execute prints the command usage and exits with ExitUsage.
+type usageError struct {
+	cmd  *command
+	path []string
+	msg  string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// Execute runs the command selected by args and returns the process exit code.
+// With no arguments, or when the first argument is a flag, the run command is implied so
+// existing shortcuts such as "WinCuts.exe -background" keep working. The deprecated --print-config and
+// --generate-config flags run "config dump --origins" and "config generate" with a warning.
+func (a *App) Execute(args []string) int {
+	root := a.commands()
+	if legacy, command, rest := legacyArgs(args); legacy != "" {
+		fmt.Fprintf(a.Stderr, "Warning: --%s is deprecated, use \"WinCuts.exe %s\" instead\n", legacy, strings.Join(command, " "))
+		args = append(command, rest...)
+	} else if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelpFlag(args[0]) && !isVersionFlag(args[0])) {
+		args = append([]string{"run"}, args...)
+	} else if isVersionFlag(args[0]) {
+		args = []string{"version"}
+	}
+
+	err := a.dispatch(root, nil, args)
+	var usage *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usage):
+		fmt.Fprintf(a.Stderr, "Error: %s\n\n", usage.msg)
+		a.printUsage(a.Stderr, usage.cmd, usage.path)
+		return ExitUsage
+	default:
+		fmt.Fprintf(a.Stderr, "Error: %s\n", err)
+		return ExitError
+	}
+}
+
+// dispatch walks the command tree and runs the selected leaf command.
+func (a *App) dispatch(cmd *command, path []string, args []string) error {
+	path = append(path, cmd.name)
+	if cmd.flags == nil {
+		if len(args) == 0 || isHelpFlag(args[0]) || args[0] == "help" {
+			a.printUsage(a.Stdout, cmd, path)
+			return flag.ErrHelp
+		}
+		for _, sub := range cmd.subcommands {
+			if sub.name == args[0] {
+				return a.dispatch(sub, path, args[1:])
+			}
+		}
+		return &usageError{cmd: cmd, path: path, msg: fmt.Sprintf("unknown command %q for %q", args[0], strings.Join(path, " "))}
+	}
+
+	fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	run := cmd.flags(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			a.printCommandUsage(a.Stdout, cmd, fs)
+			return err
+		}
+		return &usageError{cmd: cmd, path: path, msg: err.Error()}
+	}
+	err := run(a, fs.Args())
+	var usage *usageError
+	if errors.As(err, &usage) && usage.cmd == nil {
+		usage.cmd, usage.path = cmd, path
+	}
+	return err
+}
+
+// printUsage lists the subcommands of a group command.
+func (a *App) printUsage(w io.Writer, cmd *command, path []string) {
+	name := strings.Join(path, " ")
+	if cmd.flags != nil {
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		cmd.flags(fs)
+		a.printCommandUsage(w, cmd, fs)
+		return
+	}
+	fmt.Fprintf(w, "Usage: %s <command> [flags]\n\nCommands:\n", name)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, sub := range cmd.subcommands {
+		fmt.Fprintf(tw, "  %s\t%s\n", sub.name, sub.summary)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\nRun \"%s <command> --help\" for more information about a command.\n", name)
+}
+
+// printCommandUsage describes a leaf command and its flags.
+func (a *App) printCommandUsage(w io.Writer, cmd *command, fs *flag.FlagSet) {
+	synopsis := fs.Name() + " [flags]"
+	if cmd.args != "" {
+		synopsis += " " + cmd.args
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", synopsis, cmd.summary)
+
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if !hasFlags {
+		return
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fs.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		if name != "" {
+			name = " " + name
+		}
+		if f.DefValue != "" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		fmt.Fprintf(tw, "  --%s%s\t%s\n", f.Name, name, usage)
+	})
+	tw.Flush()
+}
+
+// configFlags registers the flags shared by every command that loads configuration.
+func configFlags(fs *flag.FlagSet) *config.Options {
+	opts := &config.Options{}
+	fs.StringVar(&opts.ConfigPath, "config", "", "path to the config `file` (default: $WINCUTS_CONFIG, %APPDATA%\\WinCuts\\config.yaml, then next to the executable)")
+	fs.StringVar(&opts.LogLevel, "log-level", "", "override the log `level`: DEBUG, INFO, WARN or ERROR")
+	fs.Func("min-desktops", "override the minimum `count` of virtual desktops", func(value string) error {
+		var count int
+		if _, err := fmt.Sscan(value, &count); err != nil {
+			return fmt.Errorf("invalid count %q", value)
+		}
+		opts.MinDesktops = &count
+		return nil
+	})
+	return opts
+}
+
+// noArgs rejects positional arguments for commands that take none.
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return &usageError{msg: fmt.Sprintf("unexpected argument %q", args[0])}
+	}
+	return nil
+}
+
+// legacyArgs recognizes the flags that ran a one-off task before the command tree existed. It returns the flag,
+// the command that replaces it and the remaining arguments that command accepts, or an empty flag if none is set.
+func legacyArgs(args []string) (legacy string, command []string, rest []string) {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return "", nil, nil
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			rest = append(rest, arg) // The value of a config flag
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch name {
+		case "print-config":
+			if hasValue && value == "false" {
+				continue
+			}
+			legacy, command = name, []string{"config", "dump", "--origins"}
+		case "generate-config":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			// "config generate" takes no flags, so the remaining arguments are dropped
+			return name, []string{"config", "generate", value}, nil
+		case "debug", "background":
+			// Flags of the run command; a value can only be given with "="
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return legacy, command, rest
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func isVersionFlag(arg string) bool {
+	return arg == "-v" || arg == "--version"
+}
@@ -0,0 +1,251 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"wincuts/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testApp creates an App with captured output and an environment without discoverable config files.
+func testApp(t *testing.T) (*App, *bytes.Buffer, *bytes.Buffer) {
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	app := &App{
+		Version: "1.2.3",
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Env: config.Environment{
+			Getenv:        func(string) string { return "" },
+			UserConfigDir: func() (string, error) { return dir, nil },
+			Executable:    func() (string, error) { return filepath.Join(dir, "wincuts.exe"), nil },
+			Exists: func(path string) bool {
+				_, err := os.Stat(path)
+				return err == nil
+			},
+		},
+		Run: func(RunOptions) error {
+			t.Fatal("unexpected run")
+			return nil
+		},
+	}
+	return app, &stdout, &stderr
+}
+
+// writeConfig writes a config file into a temporary directory and returns its path.
+func writeConfig(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	return path
+}
+
+func TestExecuteVersion(t *testing.T) {
+	for _, args := range [][]string{{"version"}, {"-v"}, {"--version"}} {
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute(args), args)
+		assert.Equal(t, "WinCuts 1.2.3\n", stdout.String(), args)
+	}
+}
+
+func TestExecuteRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantBackground bool
+		wantDesktops   int
+	}{
+		{name: "no arguments implies run", args: nil, wantBackground: true, wantDesktops: 9},
+		{name: "legacy flags imply run", args: []string{"-debug"}, wantBackground: false, wantDesktops: 9},
+		{name: "explicit run", args: []string{"run", "--background=false"}, wantBackground: false, wantDesktops: 9},
+		{name: "config flags reach the loader", args: []string{"run", "--min-desktops", "3"}, wantBackground: true, wantDesktops: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, _ := testApp(t)
+			var got RunOptions
+			app.Run = func(opts RunOptions) error {
+				got = opts
+				return nil
+			}
+
+			require.Equal(t, ExitOK, app.Execute(tt.args))
+			assert.Equal(t, tt.wantBackground, got.Background)
+			require.NotNil(t, got.Loader)
+			cfg, err := got.Loader.Load()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDesktops, cfg.VirtualDesktops.MinimumCount)
+		})
+	}
+}
+
+func TestExecuteUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "unknown command", args: []string{"frobnicate"}},
+		{name: "unknown subcommand", args: []string{"config", "frobnicate"}},
+		{name: "unknown flag", args: []string{"config", "dump", "--frobnicate"}},
+		{name: "missing argument", args: []string{"config", "generate"}},
+		{name: "unexpected argument", args: []string{"version", "extra"}},
+		{name: "invalid flag value", args: []string{"run", "--min-desktops", "many"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, stdout, stderr := testApp(t)
+			assert.Equal(t, ExitUsage, app.Execute(tt.args))
+			assert.Empty(t, stdout.String())
+			assert.Contains(t, stderr.String(), "Error: ")
+			assert.Contains(t, stderr.String(), "Usage: wincuts")
+		})
+	}
+}
+
+func TestExecuteHelp(t *testing.T) {
+	for _, args := range [][]string{{"--help"}, {"help"}, {"config"}, {"config", "dump", "--help"}} {
+		app, stdout, stderr := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute(args), args)
+		assert.Contains(t, stdout.String(), "Usage: wincuts", args)
+		assert.Empty(t, stderr.String(), args)
+	}
+}
+
+func TestExecuteConfigCommands(t *testing.T) {
+	valid := writeConfig(t, "virtual_desktops:\n  minimum_count: 4\n")
+	invalid := writeConfig(t, "virtual_desktops:\n  minimum_count: -1\n")
+
+	t.Run("validate accepts a valid file", func(t *testing.T) {
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "validate", "--config", valid}))
+		assert.Contains(t, stdout.String(), "valid")
+	})
+
+	t.Run("validate rejects an invalid file", func(t *testing.T) {
+		app, stdout, stderr := testApp(t)
+		assert.Equal(t, ExitError, app.Execute([]string{"config", "validate", "--config", invalid}))
+		assert.Equal(t, "error: "+invalid+":2:18: minimum_count cannot be negative\n", stdout.String())
+		assert.Contains(t, stderr.String(), "invalid configuration")
+	})
+
+	t.Run("validate reports every problem as JSON", func(t *testing.T) {
+		typos := writeConfig(t, "virtual_desktops:\n  minimun_count: 4\nui:\n  tray_icon:\n    bg_opacity: 256\n")
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitError, app.Execute([]string{"config", "validate", "--format", "json", "--config", typos}))
+		var report struct {
+			Valid  bool
+			Errors []config.Diagnostic
+		}
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+		assert.False(t, report.Valid)
+		assert.Equal(t, []config.Diagnostic{
+			{Position: config.Position{File: typos, Line: 2, Column: 3}, Path: "virtual_desktops.minimun_count", Message: `unknown field "minimun_count", did you mean "minimum_count"?`},
+			{Position: config.Position{File: typos, Line: 5, Column: 17}, Path: "ui.tray_icon.bg_opacity", Message: "bg_opacity must be between 0 and 255"},
+		}, report.Errors)
+	})
+
+	t.Run("validate lists conflicts as JSON", func(t *testing.T) {
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "validate", "--format", "json", "--config", valid}))
+		assert.JSONEq(t, `{"valid": true, "errors": [], "conflicts": []}`, stdout.String())
+	})
+
+	t.Run("validate reports conflicting bindings", func(t *testing.T) {
+		shadowed := writeConfig(t, "shortcuts:\n  bindings:\n    - id: lock\n      keys: [LWin, L]\n      action: CreateDesktop\n")
+		app, stdout, stderr := testApp(t)
+		assert.Equal(t, ExitError, app.Execute([]string{"config", "validate", "--config", shadowed}))
+		assert.Contains(t, stdout.String(), `error: mode "default": binding with id "lock" never fires: Windows reserves Win+L`)
+		assert.Contains(t, stderr.String(), "key bindings that never fire: 1")
+	})
+
+	t.Run("validate accepts bindings that only may conflict", func(t *testing.T) {
+		overlapping := writeConfig(t, "shortcuts:\n  bindings:\n    - keys: [Alt, F4]\n      action: CreateDesktop\n")
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "validate", "--config", overlapping}))
+		assert.Contains(t, stdout.String(), "warning: ")
+		assert.Contains(t, stdout.String(), "Configuration is valid")
+	})
+
+	t.Run("dump prints the effective config with origins", func(t *testing.T) {
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "dump", "--origins", "--config", valid}))
+		assert.Contains(t, stdout.String(), "minimum_count: 4 # user: "+valid)
+	})
+
+	t.Run("generate writes the defaults", func(t *testing.T) {
+		app, _, _ := testApp(t)
+		path := filepath.Join(t.TempDir(), "out", "config.yaml")
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "generate", path}))
+		assert.FileExists(t, path)
+	})
+
+	t.Run("legacy print-config flag dumps with origins", func(t *testing.T) {
+		app, stdout, stderr := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"-debug", "--print-config", "--config", valid}))
+		assert.Contains(t, stdout.String(), "minimum_count: 4 # user: "+valid)
+		assert.Contains(t, stderr.String(), `--print-config is deprecated, use "WinCuts.exe config dump --origins"`)
+	})
+
+	t.Run("legacy generate-config flag writes the defaults", func(t *testing.T) {
+		for _, args := range [][]string{{"-generate-config", "%s"}, {"--generate-config=%s"}} {
+			app, _, stderr := testApp(t)
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			for i := range args {
+				args[i] = strings.Replace(args[i], "%s", path, 1)
+			}
+			assert.Equal(t, ExitOK, app.Execute(args), args)
+			assert.FileExists(t, path)
+			assert.Contains(t, stderr.String(), `--generate-config is deprecated, use "WinCuts.exe config generate `+path+`"`)
+		}
+	})
+
+	t.Run("schema is valid JSON", func(t *testing.T) {
+		app, stdout, _ := testApp(t)
+		assert.Equal(t, ExitOK, app.Execute([]string{"config", "schema"}))
+		var schema map[string]any
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &schema))
+		assert.Equal(t, config.SchemaURI, schema["$schema"])
+	})
+}
+
+func TestExecuteListCommands(t *testing.T) {
+	app, stdout, _ := testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"keys", "list"}))
+	assert.Contains(t, stdout.String(), "LAlt+1")
+	assert.Contains(t, stdout.String(), "switch-desktop-1")
+
+	withMode := writeConfig(t, "shortcuts:\n  modes:\n    resize:\n      bindings:\n        - keys: Esc\n          action: ExitMode\n")
+	app, stdout, _ = testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"keys", "list", "--config", withMode}))
+	assert.Regexp(t, `resize\s+Esc\s+ExitMode`, stdout.String())
+
+	scoped := writeConfig(t, "shortcuts:\n  bindings:\n    - keys: Alt+1\n      action: CreateDesktop\n      unless: {process: [wt.exe, cmd.exe]}\n")
+	app, stdout, _ = testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"keys", "list", "--config", scoped}))
+	assert.Contains(t, stdout.String(), "unless process=wt.exe,cmd.exe")
+
+	labelled := writeConfig(t, "shortcuts:\n  bindings:\n    - id: create-desktop\n      merge: replace\n      enabled: false\n      tags: [desktop, rare]\n")
+	app, stdout, _ = testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"keys", "list", "--config", labelled}))
+	assert.Regexp(t, `create-desktop\s+desktop,rare\s+\(disabled\) Create a new virtual desktop`, stdout.String())
+	assert.Regexp(t, `switch-desktop-1\s+Switch to desktop 1`, stdout.String())
+
+	app, stdout, _ = testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"keys", "names"}))
+	assert.Contains(t, stdout.String(), "LALT, LMENU")
+	assert.Contains(t, stdout.String(), "ALT, MENU")
+	assert.Contains(t, stdout.String(), "ESC, ESCAPE")
+
+	app, stdout, _ = testApp(t)
+	assert.Equal(t, ExitOK, app.Execute([]string{"actions", "list"}))
+	assert.Contains(t, stdout.String(), "SwitchDesktop")
+	assert.Contains(t, stdout.String(), "CreateDesktop")
+	assert.Regexp(t, `EnterMode\s+Mode\s+mode\s+Activate`, stdout.String())
+}
@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"wincuts/action"
+	"wincuts/config"
+	"wincuts/keyboard/types"
+)
+
+// commands builds the command tree.
+func (a *App) commands() *command {
+	return &command{
+		name: "wincuts",
+		subcommands: []*command{
+			{name: "run", summary: "Start WinCuts (the default when no command is given)", flags: runCommand},
+			{
+				name:    "config",
+				summary: "Inspect and generate configuration files",
+				subcommands: []*command{
+					{name: "generate", args: "<path>", summary: "Write the default configuration to a file", flags: configGenerateCommand},
+					{name: "validate", summary: "Check the effective configuration for errors", flags: configValidateCommand},
+					{name: "dump", summary: "Print the effective configuration", flags: configDumpCommand},
+					{name: "schema", summary: "Print the JSON Schema of the config file", flags: configSchemaCommand},
+				},
+			},
+			{
+				name:    "keys",
+				summary: "Inspect keyboard shortcuts",
+				subcommands: []*command{
+					{name: "list", summary: "List the configured key bindings", flags: keysListCommand},
+					{name: "names", summary: "List the key names and aliases bindings accept", flags: keysNamesCommand},
+				},
+			},
+			{
+				name:    "actions",
+				summary: "Inspect the actions shortcuts can trigger",
+				subcommands: []*command{
+					{name: "list", summary: "List the available actions", flags: actionsListCommand},
+				},
+			},
+			{name: "version", summary: "Print the version", flags: versionCommand},
+		},
+	}
+}
+
+// loadConfig builds the layered loader for opts and loads the effective configuration.
+func (a *App) loadConfig(opts *config.Options) (*config.Config, error) {
+	loader, err := config.NewConfigLoader(*opts, a.Env)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+func runCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	opts := configFlags(fs)
+	background := fs.Bool("background", true, "run without a console window")
+	debug := fs.Bool("debug", false, "keep the console window open for log output")
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		loader, err := config.NewConfigLoader(*opts, a.Env)
+		if err != nil {
+			return err
+		}
+		return a.Run(RunOptions{Loader: loader, Background: *background && !*debug})
+	}
+}
+
+func configGenerateCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	return func(a *App, args []string) error {
+		if len(args) != 1 {
+			return &usageError{msg: "expected exactly one output path"}
+		}
+		if err := config.GenerateDefaultConfigFile(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Stdout, "Wrote default configuration to %s\n", args[0])
+		return nil
+	}
+}
+
+// validationReport is the result of config validate in JSON format.
+type validationReport struct {
+	Valid     bool                `json:"valid"`
+	Errors    []config.Diagnostic `json:"errors"`
+	Conflicts []config.Conflict   `json:"conflicts"`
+}
+
+func configValidateCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	opts := configFlags(fs)
+	format := fs.String("format", "text", "output `format`: text, or json for editors")
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		if *format != "text" && *format != "json" {
+			return &usageError{msg: fmt.Sprintf("unknown format %q, want text or json", *format)}
+		}
+
+		report := validationReport{Errors: []config.Diagnostic{}, Conflicts: []config.Conflict{}}
+		cfg, err := a.loadConfig(opts)
+		var validation *config.ValidationError
+		switch {
+		case errors.As(err, &validation):
+			report.Errors = validation.Diagnostics
+		case err != nil:
+			report.Errors = []config.Diagnostic{{Message: err.Error()}}
+		default:
+			report.Conflicts = append(report.Conflicts, config.AnalyzeConflicts(cfg)...)
+		}
+		never := 0
+		for _, conflict := range report.Conflicts {
+			if conflict.Severity == config.SeverityError {
+				never++
+			}
+		}
+		report.Valid = len(report.Errors) == 0 && never == 0
+
+		if *format == "json" {
+			enc := json.NewEncoder(a.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			for _, d := range report.Errors {
+				fmt.Fprintf(a.Stdout, "error: %s\n", d)
+			}
+			for _, conflict := range report.Conflicts {
+				fmt.Fprintln(a.Stdout, conflict)
+			}
+			if report.Valid {
+				fmt.Fprintln(a.Stdout, "Configuration is valid")
+			}
+		}
+
+		switch {
+		case len(report.Errors) > 0:
+			return fmt.Errorf("invalid configuration, problems found: %d", len(report.Errors))
+		case never > 0:
+			return fmt.Errorf("key bindings that never fire: %d", never)
+		}
+		return nil
+	}
+}
+
+func configDumpCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	opts := configFlags(fs)
+	withOrigins := fs.Bool("origins", false, "annotate each value with the layer it came from")
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		loader, err := config.NewConfigLoader(*opts, a.Env)
+		if err != nil {
+			return err
+		}
+		cfg, origins, err := loader.LoadWithOrigins()
+		if err != nil {
+			return err
+		}
+		if !*withOrigins {
+			origins = nil
+		}
+		return config.WriteConfig(a.Stdout, cfg, origins)
+	}
+}
+
+func configSchemaCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		return config.WriteSchema(a.Stdout, config.GenerateSchema(&config.DefaultActionProvider{}))
+	}
+}
+
+func keysListCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	opts := configFlags(fs)
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		cfg, err := a.loadConfig(opts)
+		if err != nil {
+			return err
+		}
+		modes := make([]string, 0, len(cfg.Shortcuts.Modes))
+		for name := range cfg.Shortcuts.Modes {
+			modes = append(modes, name)
+		}
+		slices.Sort(modes)
+
+		tw := tabwriter.NewWriter(a.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "MODE\tKEYS\tACTION\tPARAMS\tID\tTAGS\tWINDOWS\tDESCRIPTION")
+		printBindings := func(mode string, bindings []config.KeyBinding) {
+			for _, binding := range bindings {
+				description := binding.Description
+				if !binding.IsEnabled() {
+					description = strings.TrimSpace("(disabled) " + description)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", mode, binding.Keys, binding.Action,
+					binding.Params, binding.ID, strings.Join(binding.Tags, ","), binding.Scope(), description)
+			}
+		}
+		printBindings(config.DefaultModeName, cfg.Shortcuts.Bindings)
+		for _, mode := range modes {
+			printBindings(mode, cfg.Shortcuts.Modes[mode].Bindings)
+		}
+		return tw.Flush()
+	}
+}
+
+func keysNamesCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		// Group the names of each key so aliases are listed next to the Windows name.
+		byKey := map[types.VirtualKey][]string{}
+		for name, vk := range types.KeyNames() {
+			byKey[vk] = append(byKey[vk], name)
+		}
+		vks := make([]types.VirtualKey, 0, len(byKey))
+		for vk := range byKey {
+			vks = append(vks, vk)
+		}
+		slices.Sort(vks)
+
+		tw := tabwriter.NewWriter(a.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CODE\tNAMES")
+		for _, vk := range vks {
+			names := byKey[vk]
+			slices.Sort(names)
+			fmt.Fprintf(tw, "%#04x\t%s\n", uint32(vk), strings.Join(names, ", "))
+		}
+		return tw.Flush()
+	}
+}
+
+func actionsListCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(a.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ACTION\tCATEGORY\tPARAMS\tDESCRIPTION")
+		for _, def := range action.Default.Definitions() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", def.Name, def.Category, strings.Join(def.ParamTypes(), " "), def.Description)
+		}
+		return tw.Flush()
+	}
+}
+
+func versionCommand(fs *flag.FlagSet) func(a *App, args []string) error {
+	return func(a *App, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.Stdout, "WinCuts %s\n", a.Version)
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+// Package clock abstracts the passage of time so that timing-dependent logic
+// (config polling, key timing, debouncing) can be driven deterministically in tests.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time and timers.
+// Production code uses New, tests use a Fake that only moves when told to.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// AfterFunc waits for the duration to elapse and then calls f in its own goroutine.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a handle to a pending AfterFunc call.
+type Timer interface {
+	// Stop prevents the timer from firing. It returns false if the timer already fired or was stopped.
+	Stop() bool
+}
+
+// New returns a Clock backed by the time package.
+func New() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Fake is a manually advanced Clock.
+// Timers fire synchronously from Advance in deadline order, which keeps tests free of sleeps.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake creates a Fake clock starting at the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	fire     func(now time.Time)
+}
+
+// Stop implements Timer.
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, pending := range t.clock.timers {
+		if pending == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Now implements Clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After implements Clock.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	f.schedule(d, func(now time.Time) { ch <- now })
+	return ch
+}
+
+// AfterFunc implements Clock. Unlike the real clock, f runs on the goroutine calling Advance.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.schedule(d, func(time.Time) { fn() })
+}
+
+func (f *Fake) schedule(d time.Duration, fire func(now time.Time)) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, deadline: f.now.Add(d), fire: fire}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Pending returns the number of timers that have not fired yet.
+// Tests use it to wait until a goroutine has started waiting on the clock.
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// Advance moves the clock forward and fires every timer whose deadline has been reached.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	f.mu.Unlock()
+
+	for {
+		f.mu.Lock()
+		sort.SliceStable(f.timers, func(i, j int) bool {
+			return f.timers[i].deadline.Before(f.timers[j].deadline)
+		})
+		if len(f.timers) == 0 || f.timers[0].deadline.After(target) {
+			f.now = target
+			f.mu.Unlock()
+			return
+		}
+		next := f.timers[0]
+		f.timers = f.timers[1:]
+		f.now = next.deadline
+		f.mu.Unlock()
+
+		// Fire outside the lock so callbacks can schedule new timers.
+		next.fire(next.deadline)
+	}
+}
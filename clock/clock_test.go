@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFakeAdvanceFiresTimersInOrder verifies that Advance fires due timers by deadline and leaves later ones pending.
+func TestFakeAdvanceFiresTimersInOrder(t *testing.T) {
+	assert := assert.New(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := NewFake(start)
+
+	var fired []string
+	fake.AfterFunc(30*time.Millisecond, func() { fired = append(fired, "late") })
+	fake.AfterFunc(10*time.Millisecond, func() { fired = append(fired, "early") })
+	fake.AfterFunc(time.Second, func() { fired = append(fired, "never") })
+
+	fake.Advance(50 * time.Millisecond)
+
+	assert.Equal([]string{"early", "late"}, fired, "Timers should fire in deadline order")
+	assert.Equal(start.Add(50*time.Millisecond), fake.Now(), "Now should reflect the advanced time")
+	assert.Equal(1, fake.Pending(), "Timers beyond the advanced time should stay pending")
+}
+
+// TestFakeTimerStop verifies that a stopped timer never fires.
+func TestFakeTimerStop(t *testing.T) {
+	assert := assert.New(t)
+	fake := NewFake(time.Time{})
+
+	var fired bool
+	timer := fake.AfterFunc(time.Millisecond, func() { fired = true })
+
+	assert.True(timer.Stop(), "Stopping a pending timer should report true")
+	assert.False(timer.Stop(), "Stopping twice should report false")
+	fake.Advance(time.Second)
+	assert.False(fired, "Stopped timer should not fire")
+}
+
+// TestFakeAfter verifies that After delivers the deadline on its channel once the clock passes it.
+func TestFakeAfter(t *testing.T) {
+	fake := NewFake(time.Time{})
+	ch := fake.After(time.Second)
+
+	select {
+	case <-ch:
+		t.Fatal("After should not fire before the clock advances")
+	default:
+	}
+
+	fake.Advance(time.Second)
+	assert.Equal(t, time.Time{}.Add(time.Second), <-ch)
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"wincuts/action"
+	"wincuts/keyboard/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyBinding represents a single keyboard shortcut and its associated action
+type KeyBinding struct {
+	ID          string      `yaml:"id,omitempty" json:"id,omitempty"`                   // Stable identifier used to target the binding from override files
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"` // What the binding does, shown in logs and listings
+	Keys        KeyList     `yaml:"keys" json:"keys"`                                   // Keys that make up the binding (e.g., ["LAlt", "LShift", "1"], "Alt+Shift+1" or the sequence ["Alt+W", "3"])
+	Action      string      `yaml:"action" json:"action"`                               // Name of the action to perform (e.g., "SwitchDesktop", "MoveWindowToDesktop")
+	Params      Params      `yaml:"params" json:"params"`                               // Parameters for the action, e.g. ["1"] or {desktop: 1, follow: false}
+	Enabled     *bool       `yaml:"enabled,omitempty" json:"enabled,omitempty"`         // Set to false to turn the binding off without deleting it; on when absent
+	Tags        StringList  `yaml:"tags,omitempty" json:"tags,omitempty"`               // Free-form labels for organizing bindings, e.g. ["desktop", "work"]
+	Merge       string      `yaml:"merge,omitempty" json:"merge,omitempty"`             // Merge directive when layered on another config: append, replace or remove
+	When        *WindowRule `yaml:"when,omitempty" json:"when,omitempty"`               // Only active while the foreground window matches
+	Unless      *WindowRule `yaml:"unless,omitempty" json:"unless,omitempty"`           // Inactive while the foreground window matches, so the keys reach it
+	Trigger     Trigger     `yaml:"trigger,omitempty" json:"trigger,omitempty"`         // When the binding fires: up (default), down, hold(500ms) or double(300ms)
+	Repeat      Repeat      `yaml:"repeat,omitempty" json:"repeat,omitempty"`           // What auto-repeat of held keys does: ignore (default), fire or throttle(150ms)
+}
+
+// WindowRule selects foreground windows for per-application bindings.
+// A window must match every field that is set; a list matches if any entry does.
+type WindowRule struct {
+	Process StringList `yaml:"process,omitempty" json:"process,omitempty"` // Executable names, e.g. "WindowsTerminal.exe"
+	Class   StringList `yaml:"class,omitempty" json:"class,omitempty"`     // Window class names
+	Title   string     `yaml:"title,omitempty" json:"title,omitempty"`     // Regular expression matched against the window title
+}
+
+// Validate implements ConfigValidator for WindowRule.
+func (r *WindowRule) Validate() error {
+	if len(r.Process) == 0 && len(r.Class) == 0 && r.Title == "" {
+		return fmt.Errorf("window rule needs a process, class or title")
+	}
+	if _, err := regexp.Compile(r.Title); err != nil {
+		return fmt.Errorf("invalid title pattern: %w", err)
+	}
+	return nil
+}
+
+// String describes the rule compactly, e.g. "process=code.exe,devenv.exe title=~^Admin".
+func (r *WindowRule) String() string {
+	var parts []string
+	if len(r.Process) > 0 {
+		parts = append(parts, "process="+strings.Join(r.Process, ","))
+	}
+	if len(r.Class) > 0 {
+		parts = append(parts, "class="+strings.Join(r.Class, ","))
+	}
+	if r.Title != "" {
+		parts = append(parts, "title=~"+r.Title)
+	}
+	return strings.Join(parts, " ")
+}
+
+// IsEnabled reports whether the binding is on, which it is unless enabled is set to false.
+func (k *KeyBinding) IsEnabled() bool {
+	return k.Enabled == nil || *k.Enabled
+}
+
+// Scope describes the windows a binding applies to, or returns "" for a binding that applies everywhere.
+func (k *KeyBinding) Scope() string {
+	var parts []string
+	if k.When != nil {
+		parts = append(parts, k.When.String())
+	}
+	if k.Unless != nil {
+		parts = append(parts, "unless "+k.Unless.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// KeyList is the list of key names in a binding.
+// In config files it is written either as a list or in the compact form "Alt+Shift+1".
+// A list whose entries contain "+" is a key sequence: each entry is a step pressed after the previous one,
+// so ["Alt+W", "3"] means Alt+W followed by 3.
+type KeyList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler for KeyList.
+func (k *KeyList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = types.SplitKeys(value.Value)
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for KeyList.
+func (k *KeyList) UnmarshalJSON(data []byte) error {
+	var combo string
+	if err := json.Unmarshal(data, &combo); err == nil {
+		*k = types.SplitKeys(combo)
+		return nil
+	}
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+// IsSequence reports whether the keys are a sequence of steps rather than a single combination.
+func (k KeyList) IsSequence() bool {
+	return slices.ContainsFunc(k, func(key string) bool {
+		return strings.Contains(key, types.KeySeparator)
+	})
+}
+
+// Steps returns the key names of each step. A single combination has one step.
+func (k KeyList) Steps() [][]string {
+	if !k.IsSequence() {
+		return [][]string{k}
+	}
+	steps := make([][]string, len(k))
+	for i, step := range k {
+		steps[i] = types.SplitKeys(step)
+	}
+	return steps
+}
+
+// String formats the keys in the compact form, separating the steps of a sequence with commas.
+func (k KeyList) String() string {
+	if k.IsSequence() {
+		return strings.Join(k, ", ")
+	}
+	return strings.Join(k, types.KeySeparator)
+}
+
+// matches reports whether other refers to the same binding, by id if other has one and otherwise by
+// key combination, window rules and kind of trigger, so per-application and hold or double-tap variants
+// of a shortcut are distinct bindings.
+func (k *KeyBinding) matches(other KeyBinding) bool {
+	if other.ID != "" {
+		return k.ID == other.ID
+	}
+	steps, otherSteps := k.Keys.Steps(), other.Keys.Steps()
+	return slices.EqualFunc(steps, otherSteps, sameStep) &&
+		reflect.DeepEqual(k.When, other.When) && reflect.DeepEqual(k.Unless, other.Unless) &&
+		k.triggerKind() == other.triggerKind()
+}
+
+// triggerKind returns the kind of the binding's trigger, or the raw value if it is invalid.
+func (k *KeyBinding) triggerKind() Trigger {
+	kind, _, err := k.Trigger.Parse()
+	if err != nil {
+		return k.Trigger
+	}
+	return kind
+}
+
+// sameStep reports whether two steps name the same keys in any order.
+func sameStep(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, key := range b {
+		if !slices.ContainsFunc(a, func(existing string) bool {
+			return sameKey(existing, key)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameKey reports whether two key names refer to the same key, so "Alt" matches "LAlt".
+func sameKey(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	validKeys := (&DefaultKeyProvider{}).GetValidKeys()
+	vkA, okA := lookupKey(validKeys, a)
+	vkB, okB := lookupKey(validKeys, b)
+	return okA && okB && vkA == vkB
+}
+
+// identity describes the binding for error messages, preferring the id over the key combination
+// and adding the description if there is one.
+func (k *KeyBinding) identity() string {
+	identity := fmt.Sprintf("for keys %s", k.Keys)
+	if k.ID != "" {
+		identity = fmt.Sprintf("with id %q", k.ID)
+	}
+	if k.Description != "" {
+		identity += fmt.Sprintf(" (%s)", k.Description)
+	}
+	return identity
+}
+
+// GetVirtualKeys converts a slice of key names to VirtualKeys
+func (k *KeyBinding) GetVirtualKeys() []types.VirtualKey {
+	provider := &DefaultKeyProvider{}
+	validKeys := provider.GetValidKeys()
+
+	var keys []types.VirtualKey
+	for _, keyName := range k.Keys {
+		if vk, ok := lookupKey(validKeys, keyName); ok {
+			keys = append(keys, vk)
+		}
+	}
+	return keys
+}
+
+// GetKeySteps converts the keys of each step to a KeyBinding, resolving custom modifiers in modifiers.
+// A single combination has one step.
+func (k *KeyBinding) GetKeySteps(modifiers *types.Modifiers) []types.KeyBinding {
+	var steps []types.KeyBinding
+	for _, step := range k.Keys.Steps() {
+		var keys types.KeyBinding
+		for _, keyName := range step {
+			if vk, ok := modifiers.LookupKey(keyName); ok {
+				keys = append(keys, vk)
+			}
+		}
+		steps = append(steps, keys)
+	}
+	return steps
+}
+
+// Validate checks if a key binding is valid on its own, without the custom modifiers of a config.
+func (k *KeyBinding) Validate() error {
+	return k.validate(nil)
+}
+
+// validate checks a key binding, resolving custom modifiers in modifiers.
+func (k *KeyBinding) validate(modifiers *types.Modifiers) error {
+	// Check if action exists
+	def, exists := action.Default.Lookup(k.Action)
+	if !exists {
+		return fmt.Errorf("unknown action: %s", k.Action)
+	}
+
+	// Check if all keys are valid
+	if len(k.Keys) == 0 {
+		return fmt.Errorf("binding for %s has no keys", k.Action)
+	}
+	for _, step := range k.Keys.Steps() {
+		var seen []types.VirtualKey
+		for _, key := range step {
+			if key == "" {
+				return fmt.Errorf("empty key in %s", k.Keys)
+			}
+			vk, ok := modifiers.LookupKey(key)
+			if !ok {
+				return fmt.Errorf("invalid key: %q", key)
+			}
+			if slices.Contains(seen, vk) {
+				return fmt.Errorf("duplicate key: %q", key)
+			}
+			seen = append(seen, vk)
+		}
+		if k.Keys.IsSequence() && !slices.ContainsFunc(seen, func(vk types.VirtualKey) bool { return !vk.IsModifier() }) {
+			return fmt.Errorf("sequence step %q has no key besides modifiers", strings.Join(step, types.KeySeparator))
+		}
+	}
+
+	for _, tag := range k.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("empty tag")
+		}
+	}
+
+	if k.When != nil {
+		if err := k.When.Validate(); err != nil {
+			return fmt.Errorf("invalid when: %w", err)
+		}
+	}
+	if k.Unless != nil {
+		if err := k.Unless.Validate(); err != nil {
+			return fmt.Errorf("invalid unless: %w", err)
+		}
+	}
+
+	trigger, _, err := k.Trigger.Parse()
+	if err != nil {
+		return err
+	}
+	if trigger != TriggerUp && k.Keys.IsSequence() {
+		return fmt.Errorf("trigger %q is not supported for key sequences, they fire on their last step", k.Trigger)
+	}
+	repeat, _, err := k.Repeat.Parse()
+	if err != nil {
+		return err
+	}
+	if repeat != RepeatIgnore && (k.Keys.IsSequence() || trigger == TriggerHold || trigger == TriggerDouble) {
+		return fmt.Errorf("repeat %q only applies to single combinations triggered up or down", k.Repeat)
+	}
+
+	// Validate parameters against the action's definition
+	if _, err := def.Parse(k.Params); err != nil {
+		return &FieldError{Path: k.Params.path(err), Err: fmt.Errorf("invalid parameters for %s: %w", k.Action, err)}
+	}
+
+	return nil
+}
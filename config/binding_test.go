@@ -0,0 +1,311 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"wincuts/keyboard/types"
+)
+
+// TestKeyBindingValidation tests the validation of key bindings
+func TestKeyBindingValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		keyBinding KeyBinding
+		wantErr    bool
+	}{
+		{
+			name: "valid single key",
+			keyBinding: KeyBinding{
+				Keys:   []string{"1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid modifier + key",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LCtrl", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid key",
+			keyBinding: KeyBinding{
+				Keys:   []string{"invalid"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty binding",
+			keyBinding: KeyBinding{
+				Keys:   []string{},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "aliases and any case",
+			keyBinding: KeyBinding{
+				Keys:   []string{"ctrl", "Alt", "F12"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "duplicate key through alias",
+			keyBinding: KeyBinding{
+				Keys:   []string{"Alt", "menu", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "window rules",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+				When:   &WindowRule{Class: StringList{"ConsoleWindowClass"}, Title: "^Admin"},
+				Unless: &WindowRule{Process: StringList{"WindowsTerminal.exe", "wezterm-gui.exe"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty window rule",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+				Unless: &WindowRule{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid title pattern",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+				When:   &WindowRule{Title: "(unclosed"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid sequence",
+			keyBinding: KeyBinding{
+				Keys:   []string{"Alt+W", "3"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("3"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "sequence with an invalid step",
+			keyBinding: KeyBinding{
+				Keys:   []string{"Alt+W", "Alt+nope"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("3"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "double tap trigger",
+			keyBinding: KeyBinding{
+				Keys:    []string{"LAlt", "1"},
+				Action:  "SwitchDesktop",
+				Params:  PositionalParams("1"),
+				Trigger: "double(250ms)",
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown trigger",
+			keyBinding: KeyBinding{
+				Keys:    []string{"LAlt", "1"},
+				Action:  "SwitchDesktop",
+				Params:  PositionalParams("1"),
+				Trigger: "triple",
+			},
+			wantErr: true,
+		},
+		{
+			name: "trigger on a sequence",
+			keyBinding: KeyBinding{
+				Keys:    []string{"Alt+W", "3"},
+				Action:  "SwitchDesktop",
+				Params:  PositionalParams("3"),
+				Trigger: "down",
+			},
+			wantErr: true,
+		},
+		{
+			name: "throttled repeat",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "1"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("1"),
+				Repeat: "throttle(150)",
+			},
+			wantErr: false,
+		},
+		{
+			name: "repeat on a hold",
+			keyBinding: KeyBinding{
+				Keys:    []string{"LAlt", "1"},
+				Action:  "SwitchDesktop",
+				Params:  PositionalParams("1"),
+				Trigger: "hold",
+				Repeat:  "fire",
+			},
+			wantErr: true,
+		},
+		{
+			name: "sequence step with only modifiers",
+			keyBinding: KeyBinding{
+				Keys:   []string{"Ctrl+Alt", "3"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("3"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty tag",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "3"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("3"),
+				Tags:   StringList{"desktop", " "},
+			},
+			wantErr: true,
+		},
+		{
+			name: "named params",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "LShift", "3"},
+				Action: "MoveWindowToDesktop",
+				Params: Params{{Name: "desktop", Value: "3"}, {Name: "follow", Value: "false"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown named param",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "3"},
+				Action: "SwitchDesktop",
+				Params: Params{{Name: "number", Value: "3"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "desktop zero",
+			keyBinding: KeyBinding{
+				Keys:   []string{"LAlt", "0"},
+				Action: "SwitchDesktop",
+				Params: PositionalParams("0"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.keyBinding.Validate()
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestKeyListUnmarshal tests that keys can be written as a list or in the compact form
+func TestKeyListUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		json     string
+		expected KeyList
+	}{
+		{
+			name:     "list",
+			yaml:     `keys: ["LAlt", "1"]`,
+			json:     `{"keys": ["LAlt", "1"]}`,
+			expected: KeyList{"LAlt", "1"},
+		},
+		{
+			name:     "compact form",
+			yaml:     `keys: "Alt+Shift+1"`,
+			json:     `{"keys": "Alt+Shift+1"}`,
+			expected: KeyList{"Alt", "Shift", "1"},
+		},
+		{
+			name:     "compact form with spaces",
+			yaml:     `keys: Ctrl + Alt + F12`,
+			json:     `{"keys": "Ctrl + Alt + F12"}`,
+			expected: KeyList{"Ctrl", "Alt", "F12"},
+		},
+		{
+			name:     "sequence",
+			yaml:     `keys: ["Alt+W", "3"]`,
+			json:     `{"keys": ["Alt+W", "3"]}`,
+			expected: KeyList{"Alt+W", "3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromYAML KeyBinding
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &fromYAML))
+			assert.Equal(t, tt.expected, fromYAML.Keys)
+
+			var fromJSON KeyBinding
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &fromJSON))
+			assert.Equal(t, tt.expected, fromJSON.Keys)
+		})
+	}
+}
+
+// TestWindowRuleUnmarshal tests that window rule lists can be written as a single string
+func TestWindowRuleUnmarshal(t *testing.T) {
+	var binding KeyBinding
+	require.NoError(t, yaml.Unmarshal([]byte("when: {process: code.exe, class: [A, B]}"), &binding))
+	assert.Equal(t, &WindowRule{Process: StringList{"code.exe"}, Class: StringList{"A", "B"}}, binding.When)
+
+	binding = KeyBinding{}
+	require.NoError(t, json.Unmarshal([]byte(`{"unless": {"process": ["a.exe", "b.exe"], "title": "x"}}`), &binding))
+	assert.Equal(t, &WindowRule{Process: StringList{"a.exe", "b.exe"}, Title: "x"}, binding.Unless)
+}
+
+// TestKeyListSteps tests how key lists split into the steps of a sequence
+func TestKeyListSteps(t *testing.T) {
+	combo := KeyList{"LAlt", "1"}
+	assert.False(t, combo.IsSequence())
+	assert.Equal(t, [][]string{{"LAlt", "1"}}, combo.Steps())
+	assert.Equal(t, "LAlt+1", combo.String())
+
+	sequence := KeyList{"Alt+W", "Shift + 3"}
+	assert.True(t, sequence.IsSequence())
+	assert.Equal(t, [][]string{{"Alt", "W"}, {"Shift", "3"}}, sequence.Steps())
+	assert.Equal(t, "Alt+W, Shift + 3", sequence.String())
+
+	binding := KeyBinding{Keys: KeyList{"Alt+W", "3"}}
+	assert.Equal(t, []types.KeyBinding{{types.VK_MENU, types.VK_W}, {types.VK_3}}, binding.GetKeySteps(nil))
+	assert.True(t, binding.matches(KeyBinding{Keys: KeyList{"menu+w", "3"}}))
+	assert.False(t, binding.matches(KeyBinding{Keys: KeyList{"Alt+W", "4"}}))
+	assert.False(t, binding.matches(KeyBinding{Keys: KeyList{"Alt", "W", "3"}}))
+}
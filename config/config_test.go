@@ -1,17 +1,30 @@
 package config
 
 import (
+	"image/color"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// TestLoadConfigFromArgs tests loading configuration from command line arguments
-func TestLoadConfigFromArgs(t *testing.T) {
+// isolatedEnvironment returns the OS environment without any discoverable config files.
+func isolatedEnvironment(t *testing.T) Environment {
+	env := OSEnvironment()
+	env.Getenv = func(string) string { return "" }
+	dir := t.TempDir()
+	env.UserConfigDir = func() (string, error) { return dir, nil }
+	env.Executable = func() (string, error) { return filepath.Join(dir, "wincuts.exe"), nil }
+	return env
+}
+
+// TestNewConfigLoader tests loading configuration from command line options
+func TestNewConfigLoader(t *testing.T) {
 	// Create a temporary directory for test files
 	tempDir := t.TempDir()
 	configPath := filepath.Join(tempDir, "config.yaml")
@@ -23,7 +36,7 @@ logging:
 virtual_desktops:
   minimum_count: 6
 ui:
-  trayIcon:
+  tray_icon:
     size: 24
     corner_radius: 4
     padding: 2
@@ -32,92 +45,59 @@ ui:
 	err := os.WriteFile(configPath, configData, 0644)
 	require.NoError(t, err)
 
+	minDesktops := 8
 	tests := []struct {
 		name     string
-		args     []string
+		opts     Options
 		expected *Config
 		wantErr  bool
 	}{
 		{
-			name: "default config when no args",
-			args: []string{},
-			expected: &Config{
-				Logging: LogConfig{Level: slog.LevelDebug},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         22,
-					CornerRadius: 4,
-					Padding:      2,
-					BgOpacity:    230,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 9},
-				Shortcuts:       ShortcutsConfig{Bindings: []KeyBinding{}},
-			},
+			name:     "default config when no options",
+			opts:     Options{},
+			expected: DefaultConfig(),
 		},
 		{
 			name: "load from config file",
-			args: []string{"--config", configPath},
-			expected: &Config{
-				Logging: LogConfig{Level: slog.LevelInfo},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         24,
-					CornerRadius: 4,
-					Padding:      2,
-					BgOpacity:    230,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 6},
-				Shortcuts:       ShortcutsConfig{Bindings: []KeyBinding{}},
-			},
+			opts: Options{ConfigPath: configPath},
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.UI.TrayIcon.Size = 24
+				cfg.VirtualDesktops.MinimumCount = 6
+			}),
 		},
 		{
-			name: "override with command line args",
-			args: []string{
-				"--config", configPath,
-				"--log-level", "DEBUG",
-				"--min-desktops", "8",
-			},
-			expected: &Config{
-				Logging: LogConfig{Level: slog.LevelDebug},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         24,
-					CornerRadius: 4,
-					Padding:      2,
-					BgOpacity:    230,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 8},
-				Shortcuts:       ShortcutsConfig{Bindings: []KeyBinding{}},
+			name: "override with command line options",
+			opts: Options{
+				ConfigPath:  configPath,
+				LogLevel:    "debug",
+				MinDesktops: &minDesktops,
 			},
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelDebug
+				cfg.UI.TrayIcon.Size = 24
+				cfg.VirtualDesktops.MinimumCount = 8
+			}),
 		},
 		{
-			name:    "error on missing config file path",
-			args:    []string{"--config"},
-			wantErr: true,
-		},
-		{
-			name:    "error on missing log level",
-			args:    []string{"--log-level"},
-			wantErr: true,
-		},
-		{
-			name:    "error on missing min desktops",
-			args:    []string{"--min-desktops"},
+			name:    "error on missing config file",
+			opts:    Options{ConfigPath: filepath.Join(tempDir, "missing.yaml")},
 			wantErr: true,
 		},
 		{
 			name:    "error on invalid log level",
-			args:    []string{"--log-level", "INVALID"},
-			wantErr: true,
-		},
-		{
-			name:    "error on invalid min desktops",
-			args:    []string{"--min-desktops", "invalid"},
+			opts:    Options{LogLevel: "INVALID"},
 			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			loader := NewArgsConfigLoader(tt.args)
-			cfg, err := loader.Load()
+			loader, err := NewConfigLoader(tt.opts, isolatedEnvironment(t))
+			var cfg *Config
+			if err == nil {
+				cfg, err = loader.Load()
+			}
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -130,79 +110,283 @@ ui:
 	}
 }
 
-// TestMergeConfigs tests configuration merging functionality
-func TestMergeConfigs(t *testing.T) {
+// withDefaults returns the default configuration with the given changes applied.
+func withDefaults(change func(cfg *Config)) *Config {
+	cfg := DefaultConfig()
+	change(cfg)
+	return cfg
+}
+
+// TestOverlayApply tests that only fields present in an override document are merged
+func TestOverlayApply(t *testing.T) {
+	base := withDefaults(func(cfg *Config) {
+		cfg.Logging.Level = slog.LevelInfo
+		cfg.VirtualDesktops.MinimumCount = 4
+	})
+
 	tests := []struct {
 		name     string
-		base     *Config
-		override *Config
+		override string
 		expected *Config
+		wantErr  bool
 	}{
 		{
-			name: "merge all fields",
-			base: &Config{
-				Logging: LogConfig{Level: slog.LevelDebug},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         22,
-					CornerRadius: 4,
-					Padding:      2,
-					BgOpacity:    230,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 4},
-				Shortcuts: ShortcutsConfig{
-					Bindings: []KeyBinding{
-						{
-							Keys:     []string{"LAlt", "1"},
-							Action:   "SwitchDesktop",
-							Params:   []string{"1"},
-						},
-					},
-				},
-			},
-			override: &Config{
-				Logging: LogConfig{Level: slog.LevelInfo},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         24,
-					CornerRadius: 6,
-					Padding:      3,
-					BgOpacity:    200,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 6},
-				Shortcuts: ShortcutsConfig{
-					Bindings: []KeyBinding{
-						{
-							Keys:     []string{"LAlt", "2"},
-							Action:   "SwitchDesktop",
-							Params:   []string{"2"},
-						},
-					},
-				},
-			},
-			expected: &Config{
-				Logging: LogConfig{Level: slog.LevelInfo},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         24,
-					CornerRadius: 6,
-					Padding:      3,
-					BgOpacity:    200,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 6},
-				Shortcuts: ShortcutsConfig{
-					Bindings: []KeyBinding{
-						{
-							Keys:     []string{"LAlt", "2"},
-							Action:   "SwitchDesktop",
-							Params:   []string{"2"},
-						},
-					},
-				},
-			},
+			name:     "empty document keeps base",
+			override: ``,
+			expected: base,
+		},
+		{
+			name:     "empty section keeps base",
+			override: "ui:\n",
+			expected: base,
+		},
+		{
+			name: "zero values are applied when present",
+			override: `
+logging:
+  level: DEBUG
+virtual_desktops:
+  minimum_count: 0
+ui:
+  tray_icon:
+    bg_color: {r: 0, g: 0, b: 0, a: 0}
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelDebug
+				cfg.VirtualDesktops.MinimumCount = 0
+				cfg.UI.TrayIcon.BgColor = color.RGBA{}
+			}),
+		},
+		{
+			name: "partial color keeps other channels",
+			override: `
+ui:
+  tray_icon:
+    text_color: {a: 128}
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.UI.TrayIcon.TextColor = color.RGBA{255, 255, 255, 128}
+			}),
+		},
+		{
+			name:     "sequence timeout is a duration",
+			override: "shortcuts:\n  sequence_timeout: 750ms\n",
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.SequenceTimeout = 750 * time.Millisecond
+			}),
+		},
+		{
+			name: "modes are added to the base",
+			override: `
+shortcuts:
+  modes:
+    resize:
+      timeout: 5s
+      bindings:
+        - keys: H
+          action: ExitMode
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": {
+					Timeout:  5 * time.Second,
+					Bindings: []KeyBinding{{Keys: KeyList{"H"}, Action: "ExitMode"}},
+				}}
+			}),
+		},
+		{
+			name: "binding with same keys replaces base binding in place",
+			override: `
+shortcuts:
+  bindings:
+    - keys: ["lalt", "1"]
+      action: SwitchDesktop
+      params: ["2"]
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings[0] = KeyBinding{Keys: []string{"lalt", "1"}, Action: "SwitchDesktop", Params: PositionalParams("2")}
+			}),
+		},
+		{
+			name: "binding scoped to a window is a separate binding",
+			override: `
+shortcuts:
+  bindings:
+    - keys: ["LAlt", "1"]
+      action: CreateDesktop
+      when:
+        process: code.exe
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings,
+					KeyBinding{Keys: []string{"LAlt", "1"}, Action: "CreateDesktop", When: &WindowRule{Process: StringList{"code.exe"}}})
+			}),
+		},
+		{
+			name: "hold variant of a binding is a separate binding",
+			override: `
+shortcuts:
+  bindings:
+    - keys: ["LAlt", "1"]
+      action: MoveWindowToDesktop
+      params: ["1"]
+      trigger: hold(800ms)
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings,
+					KeyBinding{Keys: []string{"LAlt", "1"}, Action: "MoveWindowToDesktop", Params: PositionalParams("1"), Trigger: "hold(800ms)"})
+			}),
+		},
+		{
+			name: "new binding is appended",
+			override: `
+shortcuts:
+  bindings:
+    - keys: ["LCtrl", "1"]
+      action: SwitchDesktop
+      params: ["1"]
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings,
+					KeyBinding{Keys: []string{"LCtrl", "1"}, Action: "SwitchDesktop", Params: PositionalParams("1")})
+			}),
+		},
+		{
+			name: "replace patches only the written fields",
+			override: `
+shortcuts:
+  bindings:
+    - id: create-desktop
+      merge: replace
+      keys: ["LAlt", "C"]
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				last := len(cfg.Shortcuts.Bindings) - 1
+				cfg.Shortcuts.Bindings[last].Keys = []string{"LAlt", "C"}
+			}),
+		},
+		{
+			name: "remove by id and by keys",
+			override: `
+shortcuts:
+  bindings:
+    - id: create-desktop
+      merge: remove
+    - keys: ["LAlt", "LShift", "9"]
+      merge: remove
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings = cfg.Shortcuts.Bindings[:len(cfg.Shortcuts.Bindings)-2]
+			}),
+		},
+		{
+			name: "compact keys target a binding through aliases",
+			override: `
+shortcuts:
+  bindings:
+    - keys: "lmenu+n"
+      merge: replace
+      params: []
+      action: CreateDesktop
+    - keys: "LAlt+LShift+9"
+      merge: remove
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				last := len(cfg.Shortcuts.Bindings) - 1
+				cfg.Shortcuts.Bindings[last].Keys = KeyList{"lmenu", "n"}
+				cfg.Shortcuts.Bindings = slices.Delete(cfg.Shortcuts.Bindings, last-1, last)
+			}),
+		},
+		{
+			name: "replace disables and relabels a binding by id",
+			override: `
+shortcuts:
+  bindings:
+    - id: switch-desktop-9
+      merge: replace
+      enabled: false
+      description: Taken by the game
+      tags: [gaming]
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				idx := slices.IndexFunc(cfg.Shortcuts.Bindings, func(b KeyBinding) bool { return b.ID == "switch-desktop-9" })
+				disabled := false
+				cfg.Shortcuts.Bindings[idx].Enabled = &disabled
+				cfg.Shortcuts.Bindings[idx].Description = "Taken by the game"
+				cfg.Shortcuts.Bindings[idx].Tags = StringList{"gaming"}
+			}),
+		},
+		{
+			name: "replace mode discards base bindings",
+			override: `
+shortcuts:
+  merge: replace
+  bindings:
+    - keys: ["LAlt", "2"]
+      action: SwitchDesktop
+      params: ["2"]
+`,
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.VirtualDesktops.MinimumCount = 4
+				cfg.Shortcuts.Bindings = []KeyBinding{{Keys: []string{"LAlt", "2"}, Action: "SwitchDesktop", Params: PositionalParams("2")}}
+			}),
+		},
+		{
+			name: "replace of unknown binding fails",
+			override: `
+shortcuts:
+  bindings:
+    - id: does-not-exist
+      merge: replace
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown directive fails",
+			override: `
+shortcuts:
+  bindings:
+    - id: create-desktop
+      merge: upsert
+`,
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := mergeConfigs(tt.base, tt.override)
+			overlay, err := ParseOverlay([]byte(tt.override))
+			require.NoError(t, err)
+
+			got, err := overlay.Apply(base)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
 			assert.Equal(t, tt.expected, got)
 		})
 	}
@@ -221,7 +405,7 @@ logging:
 virtual_desktops:
   minimum_count: 6
 ui:
-  trayIcon:
+  tray_icon:
     size: 24
     corner_radius: 6
     padding: 3
@@ -231,7 +415,7 @@ shortcuts:
     - keys: ["LAlt", "1"]
       action: "SwitchDesktop"
       params: ["1"]
-      category: "Desktop"
+      tags: [desktop]
 `)
 	err := os.WriteFile(configPath, configData, 0644)
 	require.NoError(t, err)
@@ -245,25 +429,15 @@ shortcuts:
 		{
 			name:     "load valid config file",
 			filePath: configPath,
-			expected: &Config{
-				Logging: LogConfig{Level: slog.LevelInfo},
-				UI: UIConfig{TrayIcon: TrayIconConfig{
-					Size:         24,
-					CornerRadius: 6,
-					Padding:      3,
-					BgOpacity:    200,
-				}},
-				VirtualDesktops: VirtualDesktopsConfig{MinimumCount: 6},
-				Shortcuts: ShortcutsConfig{
-					Bindings: []KeyBinding{
-						{
-							Keys:     []string{"LAlt", "1"},
-							Action:   "SwitchDesktop",
-							Params:   []string{"1"},
-						},
-					},
-				},
-			},
+			expected: withDefaults(func(cfg *Config) {
+				cfg.Logging.Level = slog.LevelInfo
+				cfg.UI.TrayIcon.Size = 24
+				cfg.UI.TrayIcon.CornerRadius = 6
+				cfg.UI.TrayIcon.Padding = 3
+				cfg.UI.TrayIcon.BgOpacity = 200
+				cfg.VirtualDesktops.MinimumCount = 6
+				cfg.Shortcuts.Bindings[0] = KeyBinding{Keys: []string{"LAlt", "1"}, Action: "SwitchDesktop", Params: PositionalParams("1"), Tags: StringList{"desktop"}}
+			}),
 		},
 		{
 			name:     "default config for non-existent file",
@@ -288,64 +462,6 @@ shortcuts:
 	}
 }
 
-// TestKeyBindingValidation tests the validation of key bindings
-func TestKeyBindingValidation(t *testing.T) {
-	tests := []struct {
-		name       string
-		keyBinding KeyBinding
-		wantErr    bool
-	}{
-		{
-			name: "valid single key",
-			keyBinding: KeyBinding{
-				Keys:     []string{"1"},
-				Action:   "SwitchDesktop",
-				Params:   []string{"1"},
-			},
-			wantErr: false,
-		},
-		{
-			name: "valid modifier + key",
-			keyBinding: KeyBinding{
-				Keys:     []string{"LCtrl", "1"},
-				Action:   "SwitchDesktop",
-				Params:   []string{"1"},
-			},
-			wantErr: false,
-		},
-		{
-			name: "invalid key",
-			keyBinding: KeyBinding{
-				Keys:     []string{"invalid"},
-				Action:   "SwitchDesktop",
-				Params:   []string{"1"},
-			},
-			wantErr: true,
-		},
-		{
-			name: "empty binding",
-			keyBinding: KeyBinding{
-				Keys:     []string{},
-				Action:   "SwitchDesktop",
-				Params:   []string{"1"},
-			},
-			wantErr: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.keyBinding.Validate()
-
-			if tt.wantErr {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-			}
-		})
-	}
-}
-
 // TestDefaultConfig tests the default configuration values
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
@@ -356,5 +472,6 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 2, cfg.UI.TrayIcon.Padding)
 	assert.Equal(t, uint8(230), cfg.UI.TrayIcon.BgOpacity)
 	assert.Equal(t, 9, cfg.VirtualDesktops.MinimumCount)
+	assert.Equal(t, DefaultSequenceTimeout, cfg.Shortcuts.SequenceTimeout)
 	assert.NotEmpty(t, cfg.Shortcuts.Bindings)
 }
@@ -0,0 +1,383 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+
+	"wincuts/keyboard/types"
+)
+
+// ConflictSeverity grades a Conflict.
+type ConflictSeverity int
+
+const (
+	SeverityInfo    ConflictSeverity = iota // Works as designed but may surprise, e.g. a side-specific binding winning over a generic one
+	SeverityWarning                         // A binding may not fire, or fires in place of something else
+	SeverityError                           // A binding never fires
+)
+
+func (s ConflictSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "info"
+}
+
+// MarshalText implements encoding.TextMarshaler, so severities appear by name in JSON.
+func (s ConflictSeverity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Kinds of Conflict.
+const (
+	ConflictDuplicate = "duplicate" // Same keys, trigger and windows as an earlier binding
+	ConflictOverlap   = "overlap"   // Some of the same keys as another binding
+	ConflictScope     = "scope"     // Same keys as a binding with other window rules that may apply to the same window
+	ConflictPrefix    = "prefix"    // Keys taken by a key sequence that starts with them or completes first
+	ConflictSuspend   = "suspend"   // Keys taken by the suspend toggle
+	ConflictRemap     = "remap"     // Keys remapped before bindings see them
+	ConflictLayer     = "layer"     // Key turned into a layer modifier
+	ConflictMode      = "mode"      // Mode that cannot be left
+	ConflictSystem    = "system"    // Keys of a Windows shortcut
+)
+
+// Conflict is a problem between bindings found by AnalyzeConflicts.
+type Conflict struct {
+	Severity ConflictSeverity `json:"severity"`
+	Kind     string           `json:"kind"`
+	Mode     string           `json:"mode"`              // Mode of the binding, DefaultModeName for the default bindings
+	Binding  string           `json:"binding,omitempty"` // Binding affected, e.g. `with id "switch-desktop-1"`; empty for a mode
+	Message  string           `json:"message"`
+}
+
+func (c Conflict) String() string {
+	if c.Binding == "" {
+		return fmt.Sprintf("%s: mode %q: %s", c.Severity, c.Mode, c.Message)
+	}
+	return fmt.Sprintf("%s: mode %q: binding %s %s", c.Severity, c.Mode, c.Binding, c.Message)
+}
+
+// SystemShortcut is a Windows shortcut that bindings may collide with.
+type SystemShortcut struct {
+	Keys        string // Keys in the compact form, e.g. "Win+D"
+	Description string
+	Reserved    bool // Windows handles the keys before any hook sees them, so a binding never fires
+}
+
+// SystemShortcuts lists well-known Windows shortcuts checked by AnalyzeConflicts.
+var SystemShortcuts = systemShortcuts()
+
+func systemShortcuts() []SystemShortcut {
+	shortcuts := []SystemShortcut{
+		{Keys: "Ctrl+Alt+Delete", Description: "security options", Reserved: true},
+		{Keys: "Win+L", Description: "lock the computer", Reserved: true},
+		{Keys: "Alt+Tab", Description: "switch between windows"},
+		{Keys: "Alt+Shift+Tab", Description: "switch between windows backwards"},
+		{Keys: "Alt+F4", Description: "close the window"},
+		{Keys: "Alt+Esc", Description: "cycle through windows"},
+		{Keys: "Alt+Space", Description: "open the window menu"},
+		{Keys: "Ctrl+Esc", Description: "open Start"},
+		{Keys: "Ctrl+Shift+Esc", Description: "open Task Manager"},
+		{Keys: "Win+A", Description: "open quick settings"},
+		{Keys: "Win+D", Description: "show the desktop"},
+		{Keys: "Win+E", Description: "open File Explorer"},
+		{Keys: "Win+I", Description: "open Settings"},
+		{Keys: "Win+M", Description: "minimize all windows"},
+		{Keys: "Win+R", Description: "open Run"},
+		{Keys: "Win+S", Description: "open search"},
+		{Keys: "Win+V", Description: "open the clipboard history"},
+		{Keys: "Win+X", Description: "open the quick link menu"},
+		{Keys: "Win+Tab", Description: "open Task View"},
+		{Keys: "Win+Period", Description: "open the emoji panel"},
+		{Keys: "Win+Shift+S", Description: "take a screenshot of a region"},
+		{Keys: "Win+PrintScreen", Description: "save a screenshot"},
+		{Keys: "Win+Up", Description: "maximize the window"},
+		{Keys: "Win+Down", Description: "restore or minimize the window"},
+		{Keys: "Win+Left", Description: "snap the window left"},
+		{Keys: "Win+Right", Description: "snap the window right"},
+		{Keys: "Win+Shift+Left", Description: "move the window to the monitor on the left"},
+		{Keys: "Win+Shift+Right", Description: "move the window to the monitor on the right"},
+		{Keys: "Win+Ctrl+D", Description: "add a virtual desktop"},
+		{Keys: "Win+Ctrl+F4", Description: "close the virtual desktop"},
+		{Keys: "Win+Ctrl+Left", Description: "switch to the virtual desktop on the left"},
+		{Keys: "Win+Ctrl+Right", Description: "switch to the virtual desktop on the right"},
+	}
+	for i := 1; i <= 9; i++ {
+		shortcuts = append(shortcuts, SystemShortcut{Keys: fmt.Sprintf("Win+%d", i), Description: fmt.Sprintf("open taskbar app %d", i)})
+	}
+	return shortcuts
+}
+
+// analyzedBinding is an enabled binding with its keys resolved.
+type analyzedBinding struct {
+	KeyBinding
+	steps   []types.KeyBinding
+	trigger Trigger
+}
+
+// sequence reports whether the binding has several steps.
+func (b *analyzedBinding) sequence() bool {
+	return len(b.steps) > 1
+}
+
+// scoped reports whether the binding only applies to windows matching a when rule, which makes it win
+// over bindings that are not.
+func (b *analyzedBinding) scoped() bool {
+	return b.When != nil
+}
+
+// sameScope reports whether both bindings have the same window rules.
+func (b *analyzedBinding) sameScope(other *analyzedBinding) bool {
+	return reflect.DeepEqual(b.When, other.When) && reflect.DeepEqual(b.Unless, other.Unless)
+}
+
+// global reports whether the binding applies to every window.
+func (b *analyzedBinding) global() bool {
+	return b.When == nil && b.Unless == nil
+}
+
+// conflicts collects the findings of AnalyzeConflicts for one mode.
+type conflicts struct {
+	mode      string
+	modifiers *types.Modifiers // Custom modifiers of the config's layers
+	found     []Conflict
+}
+
+func (c *conflicts) add(severity ConflictSeverity, kind string, binding *analyzedBinding, format string, args ...any) {
+	c.found = append(c.found, Conflict{
+		Severity: severity,
+		Kind:     kind,
+		Mode:     c.mode,
+		Binding:  binding.identity(),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// AnalyzeConflicts looks for bindings that shadow each other under the matching rules: duplicates, key
+// sequences taking the keys of other bindings, bindings whose window rules may apply to the same window,
+// bindings whose keys are taken by the suspend toggle, a remap or a layer, modes that cannot be left and
+// bindings on Windows shortcuts. Disabled bindings are skipped. The configuration must be valid.
+func AnalyzeConflicts(cfg *Config) []Conflict {
+	shortcuts := &cfg.Shortcuts
+	modifiers := shortcuts.Modifiers()
+	modes := []string{DefaultModeName}
+	modes = append(modes, slices.Sorted(maps.Keys(shortcuts.Modes))...)
+
+	var found []Conflict
+	for _, mode := range modes {
+		bindings := shortcuts.Bindings
+		if mode != DefaultModeName {
+			bindings = shortcuts.Modes[mode].Bindings
+		}
+		c := &conflicts{mode: mode, modifiers: modifiers}
+		table := analyzeBindings(bindings, modifiers)
+		for i := range table {
+			for j := i + 1; j < len(table); j++ {
+				c.pair(&table[i], &table[j])
+			}
+			c.shared(&table[i], shortcuts)
+		}
+		if mode != DefaultModeName {
+			c.exit(shortcuts.Modes[mode], bindings)
+		}
+		found = append(found, c.found...)
+	}
+	return found
+}
+
+// LogConflicts logs the conflicts of the configuration, see AnalyzeConflicts.
+func LogConflicts(cfg *Config) {
+	for _, conflict := range AnalyzeConflicts(cfg) {
+		level := slog.LevelInfo
+		switch conflict.Severity {
+		case SeverityError:
+			level = slog.LevelError
+		case SeverityWarning:
+			level = slog.LevelWarn
+		}
+		slog.Log(context.Background(), level, "binding conflict", "kind", conflict.Kind, "mode", conflict.Mode, "binding", conflict.Binding, "problem", conflict.Message)
+	}
+}
+
+// analyzeBindings resolves the keys of the enabled bindings.
+func analyzeBindings(bindings []KeyBinding, modifiers *types.Modifiers) []analyzedBinding {
+	var table []analyzedBinding
+	for _, binding := range bindings {
+		if !binding.IsEnabled() {
+			continue
+		}
+		trigger, _, _ := binding.Trigger.Parse()
+		table = append(table, analyzedBinding{KeyBinding: binding, steps: binding.GetKeySteps(modifiers), trigger: trigger})
+	}
+	return table
+}
+
+// pair compares two bindings of the same mode, first listed first.
+func (c *conflicts) pair(first, second *analyzedBinding) {
+	switch {
+	case first.sequence() && second.sequence():
+		c.sequences(first, second)
+	case first.sequence():
+		c.sequenceStart(first, second)
+	case second.sequence():
+		c.sequenceStart(second, first)
+	default:
+		c.combinations(first, second)
+	}
+}
+
+// combinations compares two key combinations. Of two bindings matching the same keys the one scoped to the
+// foreground window wins, then the more specific one, then the one listed first.
+func (c *conflicts) combinations(first, second *analyzedBinding) {
+	a, b := first.steps[0], second.steps[0]
+	if first.trigger != second.trigger || !a.Overlaps(b) {
+		return
+	}
+	if !first.sameScope(second) {
+		if first.scoped() == second.scoped() {
+			c.add(SeverityWarning, ConflictScope, second, "may not fire: binding %s has the same keys and may apply to the same window", first.identity())
+		}
+		return
+	}
+	switch {
+	case a.CoveredBy(b) && b.CoveredBy(a):
+		c.add(SeverityError, ConflictDuplicate, second, "never fires: binding %s has the same keys", first.identity())
+	case a.Specificity() > b.Specificity():
+		c.add(SeverityInfo, ConflictOverlap, second, "does not fire for %s, which go to the more specific binding %s", first.Keys, first.identity())
+	case b.Specificity() > a.Specificity():
+		c.add(SeverityInfo, ConflictOverlap, first, "does not fire for %s, which go to the more specific binding %s", second.Keys, second.identity())
+	default:
+		c.add(SeverityWarning, ConflictOverlap, second, "may not fire: binding %s matches some of the same keys and is listed first", first.identity())
+	}
+}
+
+// sequenceStart compares a key combination with a key sequence, whose first step consumes the keys it shares.
+func (c *conflicts) sequenceStart(sequence, combination *analyzedBinding) {
+	start, keys := sequence.steps[0], combination.steps[0]
+	if !keys.Overlaps(start) || !(sequence.global() || sequence.sameScope(combination)) {
+		return
+	}
+	if keys.CoveredBy(start) {
+		c.add(SeverityError, ConflictPrefix, combination, "never fires: its keys start the sequence of binding %s", sequence.identity())
+	} else {
+		c.add(SeverityWarning, ConflictPrefix, combination, "may not fire: its keys can start the sequence of binding %s", sequence.identity())
+	}
+}
+
+// sequences compares two key sequences. A sequence completes as soon as its last step is pressed, so it
+// takes the keys of longer sequences starting with the same steps.
+func (c *conflicts) sequences(first, second *analyzedBinding) {
+	shorter, longer := first, second
+	if len(second.steps) < len(first.steps) {
+		shorter, longer = second, first
+	}
+	covered := true
+	for i, step := range shorter.steps {
+		if !longer.steps[i].Overlaps(step) {
+			return
+		}
+		covered = covered && longer.steps[i].CoveredBy(step)
+	}
+	if !first.sameScope(second) {
+		if first.scoped() == second.scoped() {
+			c.add(SeverityWarning, ConflictScope, second, "may not fire: binding %s has the same steps and may apply to the same window", first.identity())
+		}
+		return
+	}
+	switch {
+	case len(first.steps) == len(second.steps) && covered && slices.EqualFunc(first.steps, second.steps, types.KeyBinding.CoveredBy):
+		c.add(SeverityError, ConflictDuplicate, second, "never fires: binding %s has the same steps", first.identity())
+	case len(first.steps) == len(second.steps):
+		c.add(SeverityWarning, ConflictOverlap, second, "may not fire: binding %s matches some of the same steps and is listed first", first.identity())
+	case covered:
+		c.add(SeverityError, ConflictPrefix, longer, "never fires: the sequence of binding %s completes first", shorter.identity())
+	default:
+		c.add(SeverityWarning, ConflictPrefix, longer, "may not fire: the sequence of binding %s can complete first", shorter.identity())
+	}
+}
+
+// shared checks a binding against the keys that are taken in every mode: the suspend toggle, remaps,
+// layer keys and Windows shortcuts.
+func (c *conflicts) shared(binding *analyzedBinding, shortcuts *ShortcutsConfig) {
+	start := binding.steps[0]
+
+	if toggle := shortcuts.Suspend.GetToggleKeys(); len(toggle) > 0 {
+		for _, step := range binding.steps {
+			if step.CoveredBy(toggle) {
+				c.add(SeverityError, ConflictSuspend, binding, "never fires: its keys %s toggle suspend", shortcuts.Suspend.Toggle)
+				break
+			} else if step.Overlaps(toggle) {
+				c.add(SeverityWarning, ConflictSuspend, binding, "may not fire: its keys can toggle suspend with %s", shortcuts.Suspend.Toggle)
+				break
+			}
+		}
+	}
+
+	for _, remap := range shortcuts.Remap {
+		from, to := remap.GetKeys(c.modifiers)
+		if c.remapped(binding, remap, from, to) {
+			break
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(shortcuts.Layers)) {
+		layer := shortcuts.Layers[name]
+		key, _ := layer.GetKeys()
+		if slices.ContainsFunc(binding.steps, func(step types.KeyBinding) bool { return step.Contains(key) }) {
+			c.add(SeverityError, ConflictLayer, binding, "never fires: %s is the key of layer %q", layer.Key, name)
+		}
+	}
+
+	for _, system := range SystemShortcuts {
+		keys, err := types.ParseKeyBinding(system.Keys)
+		if err != nil || !start.Overlaps(keys) {
+			continue
+		}
+		if system.Reserved {
+			c.add(SeverityError, ConflictSystem, binding, "never fires: Windows reserves %s to %s", system.Keys, system.Description)
+		} else {
+			c.add(SeverityWarning, ConflictSystem, binding, "takes the Windows shortcut %s to %s", system.Keys, system.Description)
+		}
+	}
+}
+
+// remapped reports a binding using keys that remap replaces before bindings see them, returning
+// whether it reported anything.
+func (c *conflicts) remapped(binding *analyzedBinding, remap RemapConfig, from, to types.KeyBinding) bool {
+	for _, step := range binding.steps {
+		switch {
+		case len(from) == 1 && step.Contains(from[0]), len(from) > 1 && step.CoveredBy(from):
+			c.add(SeverityError, ConflictRemap, binding, "never fires: %s is remapped to %s", remap.From, remap.To)
+			return true
+		case len(from) == 1 && slices.ContainsFunc(step, from[0].Satisfies), len(from) > 1 && step.Overlaps(from):
+			c.add(SeverityWarning, ConflictRemap, binding, "may not fire: %s is remapped to %s", remap.From, remap.To)
+			return true
+		}
+	}
+	return false
+}
+
+// exit reports a mode that has no binding leaving it and no timeout, so only reloading the config gets out.
+func (c *conflicts) exit(mode ModeConfig, bindings []KeyBinding) {
+	if mode.Timeout > 0 {
+		return
+	}
+	leaves := slices.ContainsFunc(bindings, func(binding KeyBinding) bool {
+		return binding.IsEnabled() && (binding.Action == "ExitMode" || binding.Action == "EnterMode")
+	})
+	if !leaves {
+		c.found = append(c.found, Conflict{
+			Severity: SeverityWarning,
+			Kind:     ConflictMode,
+			Mode:     c.mode,
+			Message:  "has no binding to leave it and no timeout",
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/keyboard/types"
+)
+
+// TestAnalyzeConflicts tests that bindings shadowing each other are reported with the binding that loses.
+func TestAnalyzeConflicts(t *testing.T) {
+	binding := func(id string, keys ...string) KeyBinding {
+		return KeyBinding{ID: id, Keys: keys, Action: "CreateDesktop"}
+	}
+	code := &WindowRule{Process: StringList{"code.exe"}}
+	terminal := &WindowRule{Process: StringList{"WindowsTerminal.exe"}}
+
+	type want struct {
+		severity ConflictSeverity
+		kind     string
+		mode     string
+		binding  string
+		message  string
+	}
+	tests := []struct {
+		name   string
+		modify func(s *ShortcutsConfig)
+		want   []want
+	}{
+		{
+			name:   "no conflicts",
+			modify: func(s *ShortcutsConfig) {},
+		},
+		{
+			name: "duplicate",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "K"), binding("b", "K", "LAlt")}
+			},
+			want: []want{{SeverityError, ConflictDuplicate, DefaultModeName, `with id "b"`, `never fires: binding with id "a" has the same keys`}},
+		},
+		{
+			name: "disabled duplicate",
+			modify: func(s *ShortcutsConfig) {
+				disabled := binding("b", "LAlt", "K")
+				disabled.Enabled = new(bool)
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "K"), disabled}
+			},
+		},
+		{
+			name: "duplicate with another trigger",
+			modify: func(s *ShortcutsConfig) {
+				hold := binding("b", "LAlt", "K")
+				hold.Trigger = TriggerHold
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "K"), hold}
+			},
+		},
+		{
+			name: "generic and side-specific modifier",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "Alt", "K"), binding("b", "LAlt", "K")}
+			},
+			want: []want{{SeverityInfo, ConflictOverlap, DefaultModeName, `with id "a"`, `does not fire for LAlt+K, which go to the more specific binding with id "b"`}},
+		},
+		{
+			name: "scoped binding wins over a global one",
+			modify: func(s *ShortcutsConfig) {
+				scoped := binding("b", "LAlt", "K")
+				scoped.When = code
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "K"), scoped}
+			},
+		},
+		{
+			name: "bindings scoped to different windows",
+			modify: func(s *ShortcutsConfig) {
+				a, b := binding("a", "LAlt", "K"), binding("b", "LAlt", "K")
+				a.When, b.When = code, terminal
+				s.Bindings = []KeyBinding{a, b}
+			},
+			want: []want{{SeverityWarning, ConflictScope, DefaultModeName, `with id "b"`, "may apply to the same window"}},
+		},
+		{
+			name: "combination starting a sequence",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "K"), binding("b", "LAlt+K", "J")}
+			},
+			want: []want{{SeverityError, ConflictPrefix, DefaultModeName, `with id "a"`, `never fires: its keys start the sequence of binding with id "b"`}},
+		},
+		{
+			name: "sequence completing first",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "LAlt+K", "J", "L"), binding("b", "LAlt+K", "J")}
+			},
+			want: []want{{SeverityError, ConflictPrefix, DefaultModeName, `with id "a"`, `never fires: the sequence of binding with id "b" completes first`}},
+		},
+		{
+			name: "sequences with other steps",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "LAlt+K", "J"), binding("b", "LAlt+K", "L")}
+			},
+		},
+		{
+			name: "suspend toggle",
+			modify: func(s *ShortcutsConfig) {
+				s.Suspend.Toggle = KeyList{"LCtrl", "LAlt", "Pause"}
+				s.Bindings = []KeyBinding{binding("a", "Ctrl", "Alt", "Pause")}
+			},
+			want: []want{{SeverityWarning, ConflictSuspend, DefaultModeName, `with id "a"`, "can toggle suspend"}},
+		},
+		{
+			name: "remapped key",
+			modify: func(s *ShortcutsConfig) {
+				s.Remap = []RemapConfig{{From: KeyList{"CapsLock"}, To: KeyList{"Esc"}}}
+				s.Bindings = []KeyBinding{binding("a", "LAlt", "CapsLock")}
+			},
+			want: []want{{SeverityError, ConflictRemap, DefaultModeName, `with id "a"`, "never fires: CapsLock is remapped to Esc"}},
+		},
+		{
+			name: "layer key",
+			modify: func(s *ShortcutsConfig) {
+				s.Layers = map[string]LayerConfig{"Hyper": {Key: "CapsLock"}}
+				s.Bindings = []KeyBinding{binding("a", "CapsLock", "K"), binding("b", "Hyper", "K")}
+			},
+			want: []want{{SeverityError, ConflictLayer, DefaultModeName, `with id "a"`, `CapsLock is the key of layer "Hyper"`}},
+		},
+		{
+			name: "mode without a way out",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{{Keys: KeyList{"LAlt", "R"}, Action: "EnterMode", Params: PositionalParams("resize")}}
+				s.Modes = map[string]ModeConfig{"resize": {Bindings: []KeyBinding{binding("a", "H"), binding("b", "H")}}}
+			},
+			want: []want{
+				{SeverityError, ConflictDuplicate, "resize", `with id "b"`, "has the same keys"},
+				{SeverityWarning, ConflictMode, "resize", "", "has no binding to leave it"},
+			},
+		},
+		{
+			name: "reserved Windows shortcut",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "LWin", "L")}
+			},
+			want: []want{{SeverityError, ConflictSystem, DefaultModeName, `with id "a"`, "never fires: Windows reserves Win+L to lock the computer"}},
+		},
+		{
+			name: "Windows shortcut",
+			modify: func(s *ShortcutsConfig) {
+				s.Bindings = []KeyBinding{binding("a", "Alt", "F4")}
+			},
+			want: []want{{SeverityWarning, ConflictSystem, DefaultModeName, `with id "a"`, "takes the Windows shortcut Alt+F4 to close the window"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(&cfg.Shortcuts)
+			require.NoError(t, cfg.Validate())
+
+			conflicts := AnalyzeConflicts(cfg)
+			require.Len(t, conflicts, len(tt.want), "%v", conflicts)
+			for i, want := range tt.want {
+				got := conflicts[i]
+				assert.Equal(t, want.severity, got.Severity)
+				assert.Equal(t, want.kind, got.Kind)
+				assert.Equal(t, want.mode, got.Mode)
+				assert.Equal(t, want.binding, got.Binding)
+				assert.Contains(t, got.Message, want.message)
+			}
+		})
+	}
+}
+
+// TestSystemShortcuts tests that the table of Windows shortcuts only holds valid keys.
+func TestSystemShortcuts(t *testing.T) {
+	for _, system := range SystemShortcuts {
+		_, err := types.ParseKeyBinding(system.Keys)
+		assert.NoError(t, err, system.Keys)
+	}
+}
@@ -7,10 +7,23 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultSequenceTimeout is how long a key sequence waits for its next step by default.
+const DefaultSequenceTimeout = time.Second
+
+// DefaultActionTimeout is how long an action may run by default before it is reported as failed.
+const DefaultActionTimeout = 10 * time.Second
+
+// Default durations of the "hold" and "double" triggers when the binding does not give one.
+const (
+	DefaultHoldDuration      = 500 * time.Millisecond
+	DefaultDoubleTapInterval = 300 * time.Millisecond
+)
+
 // DefaultConfig creates a new Config with default values.
 func DefaultConfig() *Config {
 	return &Config{
@@ -33,7 +46,9 @@ func DefaultConfig() *Config {
 			MinimumCount: 9,
 		},
 		Shortcuts: ShortcutsConfig{
-			Bindings: createDefaultDesktopBindings(),
+			SequenceTimeout: DefaultSequenceTimeout,
+			Bindings:        createDefaultDesktopBindings(),
+			Execution:       defaultExecutionConfig(),
 		},
 	}
 }
@@ -111,7 +126,17 @@ func defaultVirtualDesktopsConfig() VirtualDesktopsConfig {
 // defaultShortcutsConfig provides default keyboard shortcuts
 func defaultShortcutsConfig() ShortcutsConfig {
 	return ShortcutsConfig{
-		Bindings: createDefaultDesktopBindings(),
+		SequenceTimeout: DefaultSequenceTimeout,
+		Bindings:        createDefaultDesktopBindings(),
+		Execution:       defaultExecutionConfig(),
+	}
+}
+
+// defaultExecutionConfig runs actions one at a time with a timeout
+func defaultExecutionConfig() ExecutionConfig {
+	return ExecutionConfig{
+		Mode:    ExecutionSerial,
+		Timeout: DefaultActionTimeout,
 	}
 }
 
@@ -125,24 +150,30 @@ func createDefaultDesktopBindings() []KeyBinding {
 
 		// Switch to desktop binding (Alt + Number)
 		bindings = append(bindings, KeyBinding{
-			Keys:   []string{"LAlt", desktop},
-			Action: "SwitchDesktop",
-			Params: []string{desktop},
+			ID:          "switch-desktop-" + desktop,
+			Description: "Switch to desktop " + desktop,
+			Keys:        []string{"LAlt", desktop},
+			Action:      "SwitchDesktop",
+			Params:      PositionalParams(desktop),
 		})
 
 		// Move window to desktop binding (Alt + Shift + Number)
 		bindings = append(bindings, KeyBinding{
-			Keys:   []string{"LAlt", "LShift", desktop},
-			Action: "MoveWindowToDesktop",
-			Params: []string{desktop},
+			ID:          "move-window-to-desktop-" + desktop,
+			Description: "Move the focused window to desktop " + desktop,
+			Keys:        []string{"LAlt", "LShift", desktop},
+			Action:      "MoveWindowToDesktop",
+			Params:      PositionalParams(desktop),
 		})
 	}
 
 	// Add create desktop binding (Alt + N)
 	bindings = append(bindings, KeyBinding{
-		Keys:   []string{"LAlt", "N"},
-		Action: "CreateDesktop",
-		Params: []string{},
+		ID:          "create-desktop",
+		Description: "Create a new virtual desktop",
+		Keys:        []string{"LAlt", "N"},
+		Action:      "CreateDesktop",
+		Params:      Params{},
 	})
 
 	return bindings
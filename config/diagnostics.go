@@ -0,0 +1,330 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position locates a value in a config file. Line and Column count from 1; zero means unknown.
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.File == "" && p.Column == 0:
+		return fmt.Sprintf("line %d", p.Line)
+	case p.File == "":
+		return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+	case p.Column == 0:
+		return fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Diagnostic is a problem found while loading or validating a configuration, located in the file that
+// set the value at fault when it is known.
+type Diagnostic struct {
+	Position
+	Path    string `json:"path,omitempty"` // Field at fault, e.g. "ui.tray_icon.size" or "shortcuts.bindings[2]"
+	Message string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	location := d.Position.String()
+	if location == "" {
+		location = d.Path
+	}
+	if location == "" {
+		return d.Message
+	}
+	return location + ": " + d.Message
+}
+
+// ValidationError holds every problem found in a configuration.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FieldError is an invalid value of the field at Path, relative to the section that reports it,
+// e.g. "size" from TrayIconConfig.Validate. Its message is the one of Err.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// diagnose flattens err into diagnostics for the field at path: errors joined with errors.Join
+// become one diagnostic each, and a *FieldError extends the path with its field.
+func diagnose(path string, err error) []Diagnostic {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case interface{ Unwrap() []error }:
+		var diagnostics []Diagnostic
+		for _, err := range e.Unwrap() {
+			diagnostics = append(diagnostics, diagnose(path, err)...)
+		}
+		return diagnostics
+	case *FieldError:
+		return diagnose(fieldPath(path, e.Path), e.Err)
+	case *ValidationError:
+		return e.Diagnostics
+	}
+	return []Diagnostic{{Path: path, Message: err.Error()}}
+}
+
+// invalid returns the diagnostics as a *ValidationError, or nil if there are none.
+func invalid(diagnostics []Diagnostic) error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return &ValidationError{Diagnostics: diagnostics}
+}
+
+// fieldPath joins a field path and the path of a field below it, which may start with an index such as "[2]".
+func fieldPath(path, field string) string {
+	if strings.HasPrefix(field, "[") {
+		return path + field
+	}
+	return joinPath(path, field)
+}
+
+// yamlLinePattern matches the line and column that yaml.v3 and the UnmarshalYAML methods put in front of messages.
+var yamlLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+)(?:, column (\d+))?: `)
+
+// yamlErrors converts an error from parsing or decoding the YAML of file into a *ValidationError,
+// taking the line of every message yaml.v3 reports.
+func yamlErrors(file string, err error) error {
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		return err
+	}
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	diagnostics := make([]Diagnostic, len(messages))
+	for i, message := range messages {
+		diagnostics[i] = Diagnostic{Position: Position{File: file}, Message: message}
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			diagnostics[i].Line, _ = strconv.Atoi(m[1])
+			diagnostics[i].Column, _ = strconv.Atoi(m[2])
+			diagnostics[i].Message = message[len(m[0]):]
+		}
+	}
+	return &ValidationError{Diagnostics: diagnostics}
+}
+
+// positions maps field paths such as "ui.tray_icon.size" or "shortcuts.bindings[2].keys" to the place
+// in a config file that set them.
+type positions map[string]Position
+
+// record remembers where file sets node and every value below it, replacing earlier layers. Values set
+// without a file, such as command line overrides, forget the position.
+func (p positions) record(file string, node *yaml.Node, path string) {
+	if file == "" {
+		delete(p, path)
+	} else {
+		p[path] = Position{File: file, Line: node.Line, Column: node.Column}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := joinPath(path, key.Value)
+			if isDirectiveField(childPath) || isNull(value) {
+				continue
+			}
+			p.record(file, value, childPath)
+			if file != "" && value.Kind != yaml.ScalarNode {
+				p[childPath] = Position{File: file, Line: key.Line, Column: key.Column}
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			p.record(file, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+// locate returns the position of the field at path or, if that is unknown, of the closest field containing it.
+func (p positions) locate(path string) Position {
+	for path != "" {
+		if position, ok := p[path]; ok {
+			return position
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return Position{}
+}
+
+// validateAt validates cfg, locating each problem with the positions of the values at fault.
+func validateAt(cfg *Config, at positions) error {
+	err := validateConfig(cfg)
+	var validation *ValidationError
+	if errors.As(err, &validation) {
+		for i := range validation.Diagnostics {
+			if d := &validation.Diagnostics[i]; d.Line == 0 {
+				d.Position = at.locate(d.Path)
+			}
+		}
+	}
+	return err
+}
+
+// checkFields reports the fields of a config document that the configuration does not have, and integers
+// out of the range of their field, like yaml.v3's KnownFields but for every problem at once.
+func checkFields(file string, node *yaml.Node, t reflect.Type, path string) []Diagnostic {
+	at := func(node *yaml.Node, format string, args ...any) Diagnostic {
+		return Diagnostic{
+			Position: Position{File: file, Line: node.Line, Column: node.Column},
+			Path:     path,
+			Message:  fmt.Sprintf(format, args...),
+		}
+	}
+	if isNull(node) {
+		return nil
+	}
+	switch t {
+	case levelType, durationType, stringListType, keyListType, paramsType, triggerType, repeatType:
+		return nil // Scalars or lists checked by their own decoding
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return checkFields(file, node, t.Elem(), path)
+	case reflect.Uint8:
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!int" {
+			if n, err := strconv.ParseInt(node.Value, 0, 64); err == nil && (n < 0 || n > 255) {
+				return []Diagnostic{at(node, "%s must be between 0 and 255", lastField(path))}
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		var diagnostics []Diagnostic
+		for i, item := range node.Content {
+			diagnostics = append(diagnostics, checkFields(file, item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return diagnostics
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var diagnostics []Diagnostic
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			diagnostics = append(diagnostics, checkFields(file, node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))...)
+		}
+		return diagnostics
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		var diagnostics []Diagnostic
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				d := at(key, "unknown field %q", key.Value)
+				d.Path = joinPath(path, key.Value)
+				if suggestion := closestName(key.Value, fields); suggestion != "" {
+					d.Message += fmt.Sprintf(", did you mean %q?", suggestion)
+				}
+				diagnostics = append(diagnostics, d)
+				continue
+			}
+			diagnostics = append(diagnostics, checkFields(file, value, field.Type, joinPath(path, key.Value))...)
+		}
+		return diagnostics
+	}
+	return nil
+}
+
+// yamlFields returns the fields of a struct by their YAML name.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// lastField returns the name of the field at the end of a path.
+func lastField(path string) string {
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// closestName returns the field name closest to a misspelled one, or "" if none is close enough to suggest.
+func closestName(name string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3 // Suggest names at most two edits away
+	for _, candidate := range slices.Sorted(maps.Keys(fields)) {
+		if d := editDistance(strings.ToLower(name), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadDiagnostics tests that loading reports every problem of a config file with its line and column.
+func TestLoadDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []Diagnostic // File is filled in by the test
+	}{
+		{
+			name: "unknown fields",
+			yaml: "virtual_desktops:\n  minimun_count: 4\nui:\n  tray_icon:\n    colour: red\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 2, Column: 3}, Path: "virtual_desktops.minimun_count", Message: `unknown field "minimun_count", did you mean "minimum_count"?`},
+				{Position: Position{Line: 5, Column: 5}, Path: "ui.tray_icon.colour", Message: `unknown field "colour"`},
+			},
+		},
+		{
+			name: "unknown binding field",
+			yaml: "shortcuts:\n  bindings:\n    - keys: Alt+K\n      action: CreateDesktop\n      category: Desktop\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 5, Column: 7}, Path: "shortcuts.bindings[0].category", Message: `unknown field "category"`},
+			},
+		},
+		{
+			name: "opacity out of range",
+			yaml: "ui:\n  tray_icon:\n    bg_opacity: 300\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 3, Column: 17}, Path: "ui.tray_icon.bg_opacity", Message: "bg_opacity must be between 0 and 255"},
+			},
+		},
+		{
+			name: "invalid log level",
+			yaml: "logging:\n  level: LOUD\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 2, Column: 10}, Message: "invalid log level: LOUD, want DEBUG, INFO, WARN or ERROR"},
+			},
+		},
+		{
+			name: "every invalid value",
+			yaml: "ui:\n  tray_icon:\n    size: 8\n    padding: 4\nvirtual_desktops:\n  minimum_count: -1\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 3, Column: 11}, Path: "ui.tray_icon.size", Message: "size must be between 16 and 256"},
+				{Position: Position{Line: 4, Column: 14}, Path: "ui.tray_icon.padding", Message: "padding must be at least 0 and leave room inside the icon"},
+				{Position: Position{Line: 6, Column: 18}, Path: "virtual_desktops.minimum_count", Message: "minimum_count cannot be negative"},
+			},
+		},
+		{
+			name: "invalid binding",
+			yaml: "shortcuts:\n  bindings:\n    - keys: Alt+K\n      action: CreateDesktop\n    - keys: NotAKey\n      action: CreateDesktop\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 5, Column: 7}, Path: "shortcuts.bindings[20]", Message: `invalid binding for keys NotAKey: invalid key: "NotAKey"`},
+			},
+		},
+		{
+			name: "invalid binding in a mode",
+			yaml: "shortcuts:\n  modes:\n    resize:\n      bindings:\n        - keys: Esc\n          action: ExitMode\n        - keys: H\n          action: Frobnicate\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 7, Column: 11}, Path: "shortcuts.modes.resize.bindings[1]", Message: `invalid binding in mode "resize" for keys H: unknown action: Frobnicate`},
+			},
+		},
+		{
+			name: "invalid parameter",
+			yaml: "shortcuts:\n  bindings:\n    - keys: Alt+K\n      action: MoveWindowToDesktop\n      params: [\"2\", maybe]\n" +
+				"  modes:\n    resize:\n      bindings:\n        - keys: H\n          action: SwitchDesktop\n          params:\n            desktop: zero\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 5, Column: 21}, Path: "shortcuts.bindings[19].params[1]", Message: `invalid binding for keys Alt+K: invalid parameters for MoveWindowToDesktop: follow "maybe": must be true or false`},
+				{Position: Position{Line: 12, Column: 22}, Path: "shortcuts.modes.resize.bindings[0].params.desktop", Message: `invalid binding in mode "resize" for keys H: invalid parameters for SwitchDesktop: desktop "zero": must be a whole number`},
+			},
+		},
+		{
+			name: "merge directive in a mode",
+			yaml: "shortcuts:\n  modes:\n    resize:\n      bindings:\n        - keys: Esc\n          action: ExitMode\n          merge: replace\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 7, Column: 11}, Path: "shortcuts.modes.resize.bindings[0].merge", Message: "merge directives only apply to shortcuts.bindings; the bindings of a mode replace those of earlier layers"},
+			},
+		},
+		{
+			name: "every problem at once",
+			yaml: "logging:\n  level: verbose\nui:\n  tray_icon:\n    size: 8\n    bg_opacity: 300\n    colour: red\n" +
+				"shortcuts:\n  bindings:\n    - keys: Alt+K\n      action: SwitchDesktop\n      params: {desktop: x}\n" +
+				"    - keys: [\"Alt+W\", \"3\"]\n      action: CreateDesktop\n      trigger: hold\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 6, Column: 17}, Path: "ui.tray_icon.bg_opacity", Message: "bg_opacity must be between 0 and 255"},
+				{Position: Position{Line: 7, Column: 5}, Path: "ui.tray_icon.colour", Message: `unknown field "colour"`},
+				{Position: Position{Line: 2, Column: 10}, Message: "invalid log level: VERBOSE, want DEBUG, INFO, WARN or ERROR"},
+				{Position: Position{Line: 5, Column: 11}, Path: "ui.tray_icon.size", Message: "size must be between 16 and 256"},
+				{Position: Position{Line: 12, Column: 25}, Path: "shortcuts.bindings[19].params.desktop", Message: `invalid binding for keys Alt+K: invalid parameters for SwitchDesktop: desktop "x": must be a whole number`},
+				{Position: Position{Line: 13, Column: 7}, Path: "shortcuts.bindings[20]", Message: `invalid binding for keys Alt+W, 3: trigger "hold" is not supported for key sequences, they fire on their last step`},
+			},
+		},
+		{
+			name: "bindings that do not merge",
+			yaml: "shortcuts:\n  bindings:\n    - id: nope\n      merge: remove\n    - keys: Alt+K\n      action: CreateDesktop\n      when: [1]\n" +
+				"    - keys: Alt+J\n      action: Frobnicate\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 3}, Message: `no binding with id "nope" to remove`},
+				{Position: Position{Line: 7}, Message: "cannot unmarshal !!seq into config.WindowRule"},
+				{Position: Position{Line: 8, Column: 7}, Path: "shortcuts.bindings[19]", Message: `invalid binding for keys Alt+J: unknown action: Frobnicate`},
+			},
+		},
+		{
+			name: "syntax error",
+			yaml: "ui:\n  tray_icon:\n    size: [24\n",
+			want: []Diagnostic{
+				{Position: Position{Line: 2}, Message: "did not find expected ',' or ']'"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.yaml), 0644))
+
+			_, err := NewFileConfigLoader(path).Load()
+			var validation *ValidationError
+			require.ErrorAs(t, err, &validation)
+			for i := range tt.want {
+				tt.want[i].File = path
+			}
+			assert.Equal(t, tt.want, validation.Diagnostics)
+		})
+	}
+}
+
+// TestLayeredLoadDiagnostics tests that an invalid value is located in the file of the layer that set it.
+func TestLayeredLoadDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	machine := filepath.Join(dir, "machine.yaml")
+	user := filepath.Join(dir, "user.yaml")
+	require.NoError(t, os.WriteFile(machine, []byte("ui:\n  tray_icon:\n    size: 300\n    padding: 2\n"), 0644))
+	require.NoError(t, os.WriteFile(user, []byte("shortcuts:\n  bindings:\n    - id: create-desktop\n      merge: replace\n      params: [\"1\"]\n"), 0644))
+
+	loader := NewLayeredConfigLoader(
+		Layer{Name: LayerMachine, Path: machine, Source: NewFileConfigLoader(machine)},
+		Layer{Name: LayerUser, Path: user, Source: NewFileConfigLoader(user)},
+		Layer{Name: LayerFlags, Source: ValuesSource{"ui.tray_icon.padding": "200"}},
+	)
+	_, err := loader.Load()
+	var validation *ValidationError
+	require.ErrorAs(t, err, &validation)
+	require.Len(t, validation.Diagnostics, 3)
+
+	assert.Equal(t, Position{File: machine, Line: 3, Column: 11}, validation.Diagnostics[0].Position)
+	assert.Equal(t, Position{}, validation.Diagnostics[1].Position, "the padding comes from the command line")
+	assert.Equal(t, "ui.tray_icon.padding: padding must be at least 0 and leave room inside the icon", validation.Diagnostics[1].String())
+	assert.Equal(t, Position{File: user, Line: 5, Column: 7}, validation.Diagnostics[2].Position, "the parameters are located at their field")
+	assert.Contains(t, validation.Diagnostics[2].Message, "CreateDesktop takes no parameters")
+
+	_, _, err = loader.LoadWithOrigins()
+	assert.NoError(t, err, "loading with origins leaves validation to the caller")
+}
+
+// TestEditDistance tests the distance used to suggest field names.
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("size", "size"))
+	assert.Equal(t, 1, editDistance("minimun_count", "minimum_count"))
+	assert.Equal(t, 2, editDistance("paddign", "padding"))
+	assert.Equal(t, 4, editDistance("", "size"))
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// ConfigFileName is the name of the config file looked up in each search directory.
+	ConfigFileName = "config.yaml"
+	// AppDirName is the directory WinCuts uses below the user and machine config roots.
+	AppDirName = "WinCuts"
+	// EnvConfigPath names the environment variable that points at the user config file.
+	EnvConfigPath = "WINCUTS_CONFIG"
+)
+
+// Environment abstracts the parts of the process environment used for config discovery,
+// so the search order can be tested without touching the real user profile.
+type Environment struct {
+	Getenv        func(key string) string
+	UserConfigDir func() (string, error)
+	Executable    func() (string, error)
+	Exists        func(path string) bool
+}
+
+// OSEnvironment returns the Environment of the running process.
+func OSEnvironment() Environment {
+	return Environment{
+		Getenv:        os.Getenv,
+		UserConfigDir: os.UserConfigDir,
+		Executable:    os.Executable,
+		Exists: func(path string) bool {
+			info, err := os.Stat(path)
+			return err == nil && !info.IsDir()
+		},
+	}
+}
+
+// DiscoverUserConfig finds the user config file. The search order is:
+//
+//  1. the explicit path passed with --config
+//  2. the path in the WINCUTS_CONFIG environment variable
+//  3. %APPDATA%\WinCuts\config.yaml (the user config dir)
+//  4. config.yaml next to the executable
+//
+// Explicit paths must exist; the remaining locations are skipped when absent.
+// It returns an empty path when no config file is found.
+func DiscoverUserConfig(explicit string, env Environment) (string, error) {
+	if explicit != "" {
+		if !env.Exists(explicit) {
+			return "", fmt.Errorf("config file not found: %s", explicit)
+		}
+		return explicit, nil
+	}
+
+	if path := env.Getenv(EnvConfigPath); path != "" {
+		if !env.Exists(path) {
+			return "", fmt.Errorf("config file from %s not found: %s", EnvConfigPath, path)
+		}
+		return path, nil
+	}
+
+	for _, path := range userSearchPaths(env) {
+		if env.Exists(path) {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// userSearchPaths lists the implicit locations of the user config file in priority order.
+func userSearchPaths(env Environment) []string {
+	var paths []string
+	if dir, err := env.UserConfigDir(); err == nil && dir != "" {
+		paths = append(paths, filepath.Join(dir, AppDirName, ConfigFileName))
+	}
+	if exe, err := env.Executable(); err == nil && exe != "" {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), ConfigFileName))
+	}
+	return paths
+}
+
+// MachineConfigPath returns the machine-wide config file (%ProgramData%\WinCuts\config.yaml),
+// or an empty path when it does not exist.
+func MachineConfigPath(env Environment) string {
+	root := env.Getenv("ProgramData")
+	if root == "" {
+		return ""
+	}
+	path := filepath.Join(root, AppDirName, ConfigFileName)
+	if !env.Exists(path) {
+		return ""
+	}
+	return path
+}
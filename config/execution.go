@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Execution modes of ExecutionConfig.
+const (
+	ExecutionSerial     = "serial"     // One action at a time in the order the shortcuts fired
+	ExecutionConcurrent = "concurrent" // Every action as soon as its shortcut fires
+)
+
+// ExecutionConfig decides how the actions of fired shortcuts are run.
+type ExecutionConfig struct {
+	Mode     string        `yaml:"mode,omitempty" json:"mode,omitempty"`         // "serial" (default) or "concurrent"
+	Timeout  time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`   // Longest an action may run before it is cancelled and reported as failed, e.g. "10s"; 0 waits forever
+	Debounce time.Duration `yaml:"debounce,omitempty" json:"debounce,omitempty"` // Repeated firings of a shortcut closer together than this run once, e.g. "200ms"
+}
+
+// Validate implements ConfigValidator for ExecutionConfig.
+func (c *ExecutionConfig) Validate() error {
+	switch c.Mode {
+	case "", ExecutionSerial, ExecutionConcurrent:
+	default:
+		return fmt.Errorf("invalid mode %q, want %q or %q", c.Mode, ExecutionSerial, ExecutionConcurrent)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if c.Debounce < 0 {
+		return fmt.Errorf("debounce must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestExecutionConfigValidation tests the validation of the action execution settings
+func TestExecutionConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "defaults", yaml: `{}`},
+		{name: "concurrent", yaml: `{mode: concurrent, timeout: 5s, debounce: 200ms}`},
+		{name: "unknown mode", yaml: `mode: parallel`, wantErr: "invalid mode"},
+		{name: "negative timeout", yaml: `timeout: -1s`, wantErr: "timeout must not be negative"},
+		{name: "negative debounce", yaml: `debounce: -1s`, wantErr: "debounce must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg ExecutionConfig
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &cfg))
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layer names in the order they are applied.
+const (
+	LayerDefaults = "defaults"
+	LayerMachine  = "machine"
+	LayerUser     = "user"
+	LayerFlags    = "flags"
+)
+
+// OverlaySource provides the fields set by one configuration layer.
+type OverlaySource interface {
+	// LoadOverlay loads the fields this layer sets.
+	LoadOverlay() (*Overlay, error)
+}
+
+// Layer is a named OverlaySource. Path is the file backing the layer, if any.
+type Layer struct {
+	Name   string
+	Path   string
+	Source OverlaySource
+}
+
+// label describes the layer in "config dump --origins" output.
+func (l Layer) label() string {
+	if l.Path == "" {
+		return l.Name
+	}
+	return l.Name + ": " + l.Path
+}
+
+// Origins maps a field path such as "ui.tray_icon.size", "shortcuts.bindings[3]" or
+// "shortcuts.modes.resize.bindings[0]" to the layer that set it. Fields missing from the map come from the defaults.
+type Origins map[string]string
+
+// Of returns the layer that set the field at path.
+func (o Origins) Of(path string) string {
+	if origin, ok := o[path]; ok {
+		return origin
+	}
+	return LayerDefaults
+}
+
+// LayeredConfigLoader builds the effective configuration by applying layers on top of the defaults in order,
+// so later layers win for every field they set.
+// This follows the Open/Closed Principle by allowing new sources to be layered without changing the merge logic.
+type LayeredConfigLoader struct {
+	layers []Layer
+}
+
+// NewLayeredConfigLoader creates a loader that applies the layers in the given order.
+func NewLayeredConfigLoader(layers ...Layer) *LayeredConfigLoader {
+	return &LayeredConfigLoader{layers: layers}
+}
+
+// Layers returns the layers in the order they are applied.
+func (l *LayeredConfigLoader) Layers() []Layer {
+	return l.layers
+}
+
+// Load implements ConfigLoader. The configuration is validated, and every problem in it is reported
+// together as a *ValidationError located in the file of the layer that set the value at fault.
+func (l *LayeredConfigLoader) Load() (*Config, error) {
+	cfg, _, at, err := l.load()
+	if cfg == nil {
+		return nil, err
+	}
+	// Problems found while loading come first, then those of the values that did load
+	diagnostics := diagnose("", err)
+	diagnostics = append(diagnostics, diagnose("", validateAt(cfg, at))...)
+	if err := invalid(diagnostics); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadWithOrigins loads the effective configuration and records which layer set each field.
+// Unlike Load it does not validate the configuration, so an invalid one can still be inspected.
+func (l *LayeredConfigLoader) LoadWithOrigins() (*Config, Origins, error) {
+	cfg, origins, _, err := l.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, origins, nil
+}
+
+// load applies the layers, recording which layer set each field and where in its file.
+// Values of a layer's file that do not load are reported together as a *ValidationError, returned along
+// with the configuration loaded without them so it can still be validated.
+func (l *LayeredConfigLoader) load() (*Config, Origins, positions, error) {
+	cfg := DefaultConfig()
+	origins := Origins{}
+	at := positions{}
+
+	type layerBindings struct {
+		layer    Layer
+		bindings []KeyBinding
+		nodes    []*yaml.Node
+	}
+	var applied []layerBindings
+	var diagnostics []Diagnostic
+
+	for _, layer := range l.layers {
+		overlay, err := layer.Source.LoadOverlay()
+		if err != nil {
+			return nil, nil, nil, layer.wrap(err)
+		}
+		next, err := overlay.Apply(cfg)
+		var validation *ValidationError
+		switch {
+		case err == nil:
+		case next != nil && layer.Path != "" && errors.As(err, &validation):
+			diagnostics = append(diagnostics, validation.Diagnostics...)
+		default:
+			return nil, nil, nil, layer.wrap(err)
+		}
+		cfg = next
+		for _, path := range overlay.paths() {
+			origins[path] = layer.label()
+		}
+		if overlay.root != nil {
+			at.record(overlay.file, overlay.root, "")
+		}
+		bindings, nodes := overlay.bindings()
+		applied = append(applied, layerBindings{layer: layer, bindings: bindings, nodes: nodes})
+	}
+
+	// A binding comes from the last layer that added or patched it.
+	for i, binding := range cfg.Shortcuts.Bindings {
+		for _, layer := range applied {
+			for j, override := range layer.bindings {
+				if override.Merge != MergeRemove && binding.matches(override) {
+					origins[bindingPath(i)] = layer.layer.label()
+					at.record(layer.layer.Path, layer.nodes[j], bindingPath(i))
+				}
+			}
+		}
+	}
+
+	return cfg, origins, at, invalid(diagnostics)
+}
+
+// wrap names the layer in an error it caused, unless the error is already located in the layer's file.
+func (l Layer) wrap(err error) error {
+	var validation *ValidationError
+	if l.Path != "" && errors.As(err, &validation) {
+		return err
+	}
+	return fmt.Errorf("%s config: %w", l.label(), err)
+}
+
+// Revision implements Source by combining the revisions of all file-backed layers.
+func (l *LayeredConfigLoader) Revision() (string, error) {
+	var parts []string
+	for _, layer := range l.layers {
+		source, ok := layer.Source.(interface{ Revision() (string, error) })
+		if !ok {
+			continue
+		}
+		revision, err := source.Revision()
+		if err != nil {
+			return "", fmt.Errorf("%s config: %w", layer.Name, err)
+		}
+		parts = append(parts, revision)
+	}
+	return strings.Join(parts, "|"), nil
+}
+
+// ValuesSource is an OverlaySource built from dotted field paths, such as command line overrides
+// {"virtual_desktops.minimum_count": "8"}.
+type ValuesSource map[string]string
+
+// LoadOverlay implements OverlaySource.
+func (v ValuesSource) LoadOverlay() (*Overlay, error) {
+	if len(v) == 0 {
+		return &Overlay{}, nil
+	}
+
+	paths := make([]string, 0, len(v))
+	for path := range v {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, path := range paths {
+		node := root
+		keys := strings.Split(path, ".")
+		for _, key := range keys[:len(keys)-1] {
+			child := mappingValue(node, key)
+			if child == nil {
+				child = &yaml.Node{Kind: yaml.MappingNode}
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+			}
+			node = child
+		}
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: keys[len(keys)-1]},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: v[path]},
+		)
+	}
+	return &Overlay{root: root}, nil
+}
+
+// WriteConfig writes the configuration as YAML, annotating every value with the layer it came from.
+func WriteConfig(w io.Writer, cfg *Config, origins Origins) error {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	annotateOrigins(&node, "", origins)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return enc.Close()
+}
+
+// annotateOrigins adds a line comment with the origin to every leaf value below a mapping node.
+func annotateOrigins(node *yaml.Node, path string, origins Origins) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		fieldPath := joinPath(path, key.Value)
+		switch {
+		case fieldPath == "shortcuts.bindings" || isModeBindings(fieldPath):
+			for j, item := range value.Content {
+				item.HeadComment = origins.Of(fmt.Sprintf("%s[%d]", fieldPath, j))
+			}
+		case value.Kind == yaml.MappingNode:
+			annotateOrigins(value, fieldPath, origins)
+		default:
+			value.LineComment = origins.Of(fieldPath)
+		}
+	}
+}
+
+// bindingPath is the Origins key of the binding at index i.
+func bindingPath(i int) string {
+	return fmt.Sprintf("shortcuts.bindings[%d]", i)
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"wincuts/keyboard/types"
+)
+
+// LayerConfig turns a key into a custom modifier while it is held, named by its key in ShortcutsConfig.Layers.
+// Tapped on its own, the key can send another key instead, e.g. CapsLock held is Hyper and tapped is Esc.
+type LayerConfig struct {
+	Key        string        `yaml:"key" json:"key"`                                     // Key held for the layer, e.g. "CapsLock" or "Space"
+	Tap        string        `yaml:"tap,omitempty" json:"tap,omitempty"`                 // Key sent when the layer key is tapped on its own, e.g. "Esc"
+	TapTimeout time.Duration `yaml:"tap_timeout,omitempty" json:"tap_timeout,omitempty"` // A press held longer never sends the tap key; zero means no limit
+}
+
+// GetKeys returns the virtual keys of a validated layer; tap is zero without a tap key.
+func (l *LayerConfig) GetKeys() (key, tap types.VirtualKey) {
+	key, _ = types.LookupKey(l.Key)
+	if l.Tap != "" {
+		tap, _ = types.LookupKey(l.Tap)
+	}
+	return key, tap
+}
+
+// Modifiers returns the table of custom modifiers named by the layers, defined in name order.
+// Invalid names are left out; Validate reports them.
+func (s *ShortcutsConfig) Modifiers() *types.Modifiers {
+	modifiers := types.NewModifiers()
+	for _, name := range slices.Sorted(maps.Keys(s.Layers)) {
+		modifiers.Define(name) // Errors are reported by validateLayer
+	}
+	return modifiers
+}
+
+// validateLayers checks every layer and defines its name in modifiers, in the same order as Modifiers.
+func (s *ShortcutsConfig) validateLayers(modifiers *types.Modifiers) []error {
+	var errs []error
+	users := make(map[types.VirtualKey]string)
+	for _, name := range slices.Sorted(maps.Keys(s.Layers)) {
+		if err := s.validateLayer(name, users, modifiers); err != nil {
+			errs = append(errs, &FieldError{Path: "layers." + name, Err: err})
+		}
+	}
+	return errs
+}
+
+// validateLayer checks a layer and defines its name in modifiers. Users holds the layers by key so far.
+func (s *ShortcutsConfig) validateLayer(name string, users map[types.VirtualKey]string, modifiers *types.Modifiers) error {
+	layer := s.Layers[name]
+	if _, err := modifiers.Define(name); err != nil {
+		return fmt.Errorf("layer %q: %w", name, err)
+	}
+	key, ok := types.LookupKey(layer.Key)
+	if !ok {
+		return &FieldError{Path: "key", Err: fmt.Errorf("layer %q: invalid key %q", name, layer.Key)}
+	}
+	if key.IsModifier() {
+		return &FieldError{Path: "key", Err: fmt.Errorf("layer %q: %s is already a modifier", name, layer.Key)}
+	}
+	if other, taken := users[key]; taken {
+		return &FieldError{Path: "key", Err: fmt.Errorf("layers %q and %q both use %s", other, name, layer.Key)}
+	}
+	users[key] = name
+	if layer.Tap != "" {
+		if tap, ok := types.LookupKey(layer.Tap); !ok || tap.IsCustomModifier() {
+			return &FieldError{Path: "tap", Err: fmt.Errorf("layer %q: invalid tap key %q", name, layer.Tap)}
+		}
+	}
+	if layer.TapTimeout < 0 {
+		return &FieldError{Path: "tap_timeout", Err: fmt.Errorf("layer %q: tap_timeout cannot be negative", name)}
+	}
+	return nil
+}
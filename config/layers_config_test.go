@@ -0,0 +1,77 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShortcutsLayersValidation tests the validation of keys acting as custom modifiers
+func TestShortcutsLayersValidation(t *testing.T) {
+	hyper := LayerConfig{Key: "CapsLock", Tap: "Esc", TapTimeout: 200 * time.Millisecond}
+
+	tests := []struct {
+		name    string
+		layers  map[string]LayerConfig
+		binding *KeyBinding
+		wantErr string
+	}{
+		{
+			name:    "layer used in a binding",
+			layers:  map[string]LayerConfig{"Hyper": hyper},
+			binding: &KeyBinding{Keys: KeyList{"Hyper+H"}, Action: "SwitchDesktop", Params: PositionalParams("1")},
+		},
+		{
+			name:    "layer name taken by a key",
+			layers:  map[string]LayerConfig{"Esc": hyper},
+			wantErr: "already a key name",
+		},
+		{
+			name:    "unknown key",
+			layers:  map[string]LayerConfig{"Hyper": {Key: "Caps"}},
+			wantErr: `invalid key "Caps"`,
+		},
+		{
+			name:    "modifier as layer key",
+			layers:  map[string]LayerConfig{"Hyper": {Key: "LAlt"}},
+			wantErr: "already a modifier",
+		},
+		{
+			name:    "two layers on one key",
+			layers:  map[string]LayerConfig{"Hyper": hyper, "Super": {Key: "capital"}},
+			wantErr: `layers "Hyper" and "Super" both use`,
+		},
+		{
+			name:    "unknown tap key",
+			layers:  map[string]LayerConfig{"Hyper": {Key: "CapsLock", Tap: "Escape!"}},
+			wantErr: "invalid tap key",
+		},
+		{
+			name:    "negative tap timeout",
+			layers:  map[string]LayerConfig{"Hyper": {Key: "CapsLock", TapTimeout: -time.Second}},
+			wantErr: "tap_timeout cannot be negative",
+		},
+		{
+			name:    "layer of a config validated before",
+			binding: &KeyBinding{Keys: KeyList{"Hyper+J"}, Action: "SwitchDesktop", Params: PositionalParams("1")},
+			wantErr: `invalid key: "Hyper"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Shortcuts.Layers = tt.layers
+			if tt.binding != nil {
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings, *tt.binding)
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEnvironment builds an Environment where only the listed files exist.
+func fakeEnvironment(vars map[string]string, files ...string) Environment {
+	return Environment{
+		Getenv:        func(key string) string { return vars[key] },
+		UserConfigDir: func() (string, error) { return filepath.Join("home", "AppData"), nil },
+		Executable:    func() (string, error) { return filepath.Join("opt", "wincuts", "wincuts.exe"), nil },
+		Exists: func(path string) bool {
+			for _, file := range files {
+				if file == path {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// TestDiscoverUserConfig tests the documented search order for the user config file
+func TestDiscoverUserConfig(t *testing.T) {
+	userDirConfig := filepath.Join("home", "AppData", "WinCuts", "config.yaml")
+	exeDirConfig := filepath.Join("opt", "wincuts", "config.yaml")
+
+	tests := []struct {
+		name     string
+		explicit string
+		vars     map[string]string
+		files    []string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "explicit flag wins",
+			explicit: "flag.yaml",
+			vars:     map[string]string{EnvConfigPath: "env.yaml"},
+			files:    []string{"flag.yaml", "env.yaml", userDirConfig, exeDirConfig},
+			expected: "flag.yaml",
+		},
+		{
+			name:     "environment variable before user dir",
+			vars:     map[string]string{EnvConfigPath: "env.yaml"},
+			files:    []string{"env.yaml", userDirConfig, exeDirConfig},
+			expected: "env.yaml",
+		},
+		{
+			name:     "user config dir before exe dir",
+			files:    []string{userDirConfig, exeDirConfig},
+			expected: userDirConfig,
+		},
+		{
+			name:     "exe dir as last resort",
+			files:    []string{exeDirConfig},
+			expected: exeDirConfig,
+		},
+		{
+			name:     "no config file found",
+			expected: "",
+		},
+		{
+			name:     "missing explicit file is an error",
+			explicit: "missing.yaml",
+			files:    []string{userDirConfig},
+			wantErr:  true,
+		},
+		{
+			name:    "missing environment file is an error",
+			vars:    map[string]string{EnvConfigPath: "missing.yaml"},
+			files:   []string{userDirConfig},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := DiscoverUserConfig(tt.explicit, fakeEnvironment(tt.vars, tt.files...))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, path)
+		})
+	}
+}
+
+// TestNewConfigLoaderLayers tests that defaults, machine, user and flag layers are applied in order
+func TestNewConfigLoaderLayers(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	programData := t.TempDir()
+	appData := t.TempDir()
+	machinePath := filepath.Join(programData, "WinCuts", "config.yaml")
+	userPath := filepath.Join(appData, "WinCuts", "config.yaml")
+	require.NoError(os.MkdirAll(filepath.Dir(machinePath), 0755))
+	require.NoError(os.MkdirAll(filepath.Dir(userPath), 0755))
+
+	require.NoError(os.WriteFile(machinePath, []byte(`
+logging:
+  level: WARN
+virtual_desktops:
+  minimum_count: 4
+ui:
+  tray_icon:
+    size: 30
+`), 0644))
+	require.NoError(os.WriteFile(userPath, []byte(`
+virtual_desktops:
+  minimum_count: 6
+shortcuts:
+  bindings:
+    - id: create-desktop
+      merge: replace
+      keys: ["LAlt", "C"]
+  modes:
+    resize:
+      bindings:
+        - keys: Esc
+          action: ExitMode
+`), 0644))
+
+	env := OSEnvironment()
+	env.Getenv = func(key string) string {
+		if key == "ProgramData" {
+			return programData
+		}
+		return ""
+	}
+	env.UserConfigDir = func() (string, error) { return appData, nil }
+	env.Executable = func() (string, error) { return "", errors.New("not needed") }
+
+	loader, err := NewConfigLoader(Options{LogLevel: "error"}, env)
+	require.NoError(err)
+
+	cfg, origins, err := loader.LoadWithOrigins()
+	require.NoError(err)
+
+	assert.Equal(slog.LevelError, cfg.Logging.Level, "Flags should override every file")
+	assert.Equal(6, cfg.VirtualDesktops.MinimumCount, "User file should override machine file")
+	assert.Equal(30, cfg.UI.TrayIcon.Size, "Machine file should override defaults")
+	assert.Equal(4, cfg.UI.TrayIcon.CornerRadius, "Unset fields should keep defaults")
+
+	assert.Equal(LayerFlags, origins.Of("logging.level"))
+	assert.Equal(LayerUser+": "+userPath, origins.Of("virtual_desktops.minimum_count"))
+	assert.Equal(LayerMachine+": "+machinePath, origins.Of("ui.tray_icon.size"))
+	assert.Equal(LayerDefaults, origins.Of("ui.tray_icon.corner_radius"))
+
+	last := len(cfg.Shortcuts.Bindings) - 1
+	assert.Equal(KeyList{"LAlt", "C"}, cfg.Shortcuts.Bindings[last].Keys)
+	assert.Equal(LayerUser+": "+userPath, origins.Of(bindingPath(last)), "Patched binding should come from the user layer")
+	assert.Equal(LayerDefaults, origins.Of(bindingPath(0)))
+	assert.Equal(LayerUser+": "+userPath, origins.Of("shortcuts.modes.resize.bindings[0]"), "Mode bindings should come from the layer that set them")
+
+	revision, err := loader.Revision()
+	require.NoError(err)
+	assert.NotEmpty(revision, "File layers should contribute to the revision")
+}
+
+// TestNewConfigLoaderMissingUserFile tests that a user file created after startup is picked up.
+func TestNewConfigLoaderMissingUserFile(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	appData := t.TempDir()
+	userPath := filepath.Join(appData, "WinCuts", "config.yaml")
+	env := OSEnvironment()
+	env.Getenv = func(string) string { return "" }
+	env.UserConfigDir = func() (string, error) { return appData, nil }
+	env.Executable = func() (string, error) { return "", errors.New("not needed") }
+
+	loader, err := NewConfigLoader(Options{}, env)
+	require.NoError(err)
+	layers := loader.Layers()
+	require.Len(layers, 2)
+	assert.Equal(Layer{Name: LayerUser, Path: userPath, Source: newYAMLFileSource(userPath)}, layers[0])
+
+	cfg, err := loader.Load()
+	require.NoError(err, "A missing user file should not be an error")
+	assert.Equal(DefaultConfig(), cfg)
+	before, err := loader.Revision()
+	require.NoError(err)
+
+	require.NoError(os.MkdirAll(filepath.Dir(userPath), 0755))
+	require.NoError(os.WriteFile(userPath, []byte("virtual_desktops:\n  minimum_count: 5\n"), 0644))
+	after, err := loader.Revision()
+	require.NoError(err)
+	assert.NotEqual(before, after, "Creating the user file should change the revision")
+	cfg, err = loader.Load()
+	require.NoError(err)
+	assert.Equal(5, cfg.VirtualDesktops.MinimumCount)
+}
+
+// TestYAMLFileSource tests that discovered files load like FileConfigLoader but only in YAML.
+func TestYAMLFileSource(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte("virtual_desktops:\n  minimum_count: 5\n"), 0644))
+	jsonPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte("{}"), 0644))
+
+	overlay, err := newYAMLFileSource(yamlPath).LoadOverlay()
+	require.NoError(t, err)
+	want, err := NewFileConfigLoader(yamlPath).LoadOverlay()
+	require.NoError(t, err)
+	assert.Equal(t, want, overlay)
+
+	_, err = newYAMLFileSource(jsonPath).LoadOverlay()
+	assert.ErrorContains(t, err, "unsupported config file format: .json")
+}
+
+// TestWriteConfig tests that the effective config is printed with the origin of each value
+func TestWriteConfig(t *testing.T) {
+	cfg := withDefaults(func(cfg *Config) {
+		cfg.VirtualDesktops.MinimumCount = 3
+		cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": {Bindings: []KeyBinding{{Keys: KeyList{"Esc"}, Action: "ExitMode"}}}}
+	})
+	origins := Origins{
+		"virtual_desktops.minimum_count":     "user: config.yaml",
+		bindingPath(0):                       "user: config.yaml",
+		"shortcuts.modes.resize.bindings[0]": "machine: config.yaml",
+	}
+
+	var out bytes.Buffer
+	require.NoError(t, WriteConfig(&out, cfg, origins))
+
+	assert.Contains(t, out.String(), "minimum_count: 3 # user: config.yaml")
+	assert.Contains(t, out.String(), "size: 22 # defaults")
+	assert.Contains(t, out.String(), "# user: config.yaml\n    - id: switch-desktop-1")
+	assert.Contains(t, out.String(), "# machine: config.yaml\n        - keys:")
+}
@@ -6,104 +6,86 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
-// LoadConfigFromArgs loads configuration based on command line arguments
-func LoadConfigFromArgs(args []string) (*Config, error) {
-	// Check for generate-config first
-	for i := 1; i < len(args); i++ {
-		if args[i] == "--generate-config" {
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("--generate-config requires a file path")
-			}
-			path := args[i+1]
+// Options are the command line settings that affect configuration loading.
+type Options struct {
+	ConfigPath  string // Explicit config file, overrides discovery
+	LogLevel    string // Log level override, empty to keep the configured level
+	MinDesktops *int   // Minimum desktop count override, nil to keep the configured count
+}
 
-			if err := GenerateDefaultConfigFile(path); err != nil {
-				return nil, fmt.Errorf("failed to generate config file: %w", err)
-			}
-			slog.Info("generated default configuration file", "path", path)
-			os.Exit(0) // Exit after generating config
+// NewConfigLoader builds the layered loader for the given options.
+// Layers are applied in this order: defaults, the machine-wide file, the user file found by
+// DiscoverUserConfig, and finally the log level and minimum desktop overrides. Without a user file the
+// first search location is still added as the user layer, so a file created there later is picked up on reload.
+func NewConfigLoader(opts Options, env Environment) (*LayeredConfigLoader, error) {
+	flags := ValuesSource{}
+	if opts.LogLevel != "" {
+		level := strings.ToUpper(opts.LogLevel)
+		if _, err := parseLogLevel(level); err != nil {
+			return nil, err
 		}
+		flags["logging.level"] = level
+	}
+	if opts.MinDesktops != nil {
+		flags["virtual_desktops.minimum_count"] = fmt.Sprint(*opts.MinDesktops)
 	}
 
-	// Start with default configuration
-	config := DefaultConfig()
-
-	// Parse command line arguments
-	for i := 1; i < len(args); i++ {
-		switch args[i] {
-		case "--config":
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("--config requires a file path")
-			}
-			configPath := args[i+1]
-			i++
-
-			// Load configuration from file
-			fileConfig, err := loadConfigFromFile(configPath)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load config file: %w", err)
-			}
-			config = mergeConfigs(config, fileConfig)
-
-		case "--log-level":
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("--log-level requires a level")
-			}
-			level := args[i+1]
-			i++
-
-			// Parse log level
-			switch strings.ToUpper(level) {
-			case "DEBUG":
-				config.Logging.Level = slog.LevelDebug
-			case "INFO":
-				config.Logging.Level = slog.LevelInfo
-			case "WARN":
-				config.Logging.Level = slog.LevelWarn
-			case "ERROR":
-				config.Logging.Level = slog.LevelError
-			default:
-				return nil, fmt.Errorf("invalid log level: %s", level)
-			}
+	userPath, err := DiscoverUserConfig(opts.ConfigPath, env)
+	if err != nil {
+		return nil, err
+	}
 
-		case "--min-desktops":
-			if i+1 >= len(args) {
-				return nil, fmt.Errorf("--min-desktops requires a number")
-			}
-			var count int
-			if _, err := fmt.Sscanf(args[i+1], "%d", &count); err != nil {
-				return nil, fmt.Errorf("invalid min desktops count: %s", args[i+1])
-			}
-			i++
-			config.VirtualDesktops.MinimumCount = count
+	var layers []Layer
+	if path := MachineConfigPath(env); path != "" {
+		layers = append(layers, Layer{Name: LayerMachine, Path: path, Source: newYAMLFileSource(path)})
+	}
+	if userPath == "" {
+		if paths := userSearchPaths(env); len(paths) > 0 {
+			userPath = paths[0]
 		}
 	}
+	if userPath != "" {
+		layers = append(layers, Layer{Name: LayerUser, Path: userPath, Source: newYAMLFileSource(userPath)})
+	}
+	layers = append(layers, Layer{Name: LayerFlags, Source: flags})
 
-	return config, nil
+	return NewLayeredConfigLoader(layers...), nil
 }
 
-// loadConfigFromFile loads configuration from a file
-func loadConfigFromFile(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+// parseLogLevel converts a level name to slog.Level
+func parseLogLevel(level string) (slog.Level, error) {
+	switch level {
+	case "DEBUG":
+		return slog.LevelDebug, nil
+	case "INFO":
+		return slog.LevelInfo, nil
+	case "WARN":
+		return slog.LevelWarn, nil
+	case "ERROR":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level: %s", level)
 	}
+}
 
-	var config Config
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
-	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, &config); err != nil {
-			return nil, fmt.Errorf("failed to parse YAML config file: %w", err)
-		}
-	default:
+// yamlFileSource is a file-backed OverlaySource that requires the file to be YAML.
+type yamlFileSource struct {
+	*FileConfigLoader
+}
+
+// newYAMLFileSource creates an OverlaySource for a YAML config file.
+func newYAMLFileSource(path string) yamlFileSource {
+	return yamlFileSource{NewFileConfigLoader(path)}
+}
+
+// LoadOverlay implements OverlaySource, refusing files of other formats before reading them like FileConfigLoader.
+func (y yamlFileSource) LoadOverlay() (*Overlay, error) {
+	if ext := strings.ToLower(filepath.Ext(y.filePath)); ext != ".yaml" && ext != ".yml" {
 		return nil, fmt.Errorf("unsupported config file format: %s", ext)
 	}
-
-	return &config, nil
+	return y.FileConfigLoader.LoadOverlay()
 }
 
 // SetupLogging configures the global logger based on config
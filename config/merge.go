@@ -1,59 +1,416 @@
 package config
 
 import (
-	"image/color"
-	"log/slog"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Binding merge directives. They are set per binding with `merge:` and decide how an override
+// binding is combined with the bindings it is layered on.
+const (
+	// MergeAppend adds the binding, replacing any base binding with the same id or key combination.
+	MergeAppend = "append"
+	// MergeReplace patches an existing binding matched by id or key combination.
+	// Only the fields written in the override are changed; it is an error if nothing matches.
+	MergeReplace = "replace"
+	// MergeRemove deletes an existing binding matched by id or key combination.
+	MergeRemove = "remove"
 )
 
-// mergeConfigs merges two configurations, with the override taking precedence
-// while preserving default values for unspecified fields
-func mergeConfigs(base, override *Config) *Config {
-	if override == nil {
-		return base
+// Overlay is a partial configuration document.
+// Unlike a decoded Config it remembers which fields were actually written, so explicit zero values
+// such as `minimum_count: 0`, `level: DEBUG` or a fully transparent color still override the base.
+type Overlay struct {
+	root *yaml.Node // Mapping node of the document, nil for an empty document
+	file string     // File the document was read from, for locating errors; empty if not from a file
+}
+
+// ParseOverlay parses a YAML document into an Overlay.
+func ParseOverlay(data []byte) (*Overlay, error) {
+	return parseOverlayFile("", data)
+}
+
+// parseOverlayFile parses the YAML document of a file into an Overlay, reporting errors as a *ValidationError
+// located in the file.
+func parseOverlayFile(file string, data []byte) (*Overlay, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, yamlErrors(file, err)
+	}
+	overlay, err := newOverlay(&doc)
+	if err != nil {
+		return nil, yamlErrors(file, err)
 	}
+	overlay.file = file
+	return overlay, nil
+}
 
-	result := *base
+// newOverlay wraps a document or mapping node.
+func newOverlay(node *yaml.Node) (*Overlay, error) {
+	if node.Kind == 0 {
+		// An empty document decodes to a zero node
+		return &Overlay{}, nil
+	}
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return &Overlay{}, nil
+		}
+		node = node.Content[0]
+	}
+	if isNull(node) {
+		return &Overlay{}, nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: config document must be a mapping", node.Line)
+	}
+	return &Overlay{root: node}, nil
+}
 
-	// Merge logging config
-	if override.Logging.Level != slog.LevelDebug {
-		result.Logging.Level = override.Logging.Level
+// Apply returns a new configuration with the overlay's fields applied on top of base.
+// Base is not modified. Fields the configuration does not have, values that do not decode and bindings
+// that do not merge are reported together as a *ValidationError. The configuration is returned along with
+// it, keeping the base value of every field at fault, so the caller can validate the rest and report every
+// problem at once.
+func (o *Overlay) Apply(base *Config) (*Config, error) {
+	var diagnostics []Diagnostic
+	if o.root != nil {
+		diagnostics = checkFields(o.file, o.root, reflect.TypeOf(Config{}), "")
+		diagnostics = append(diagnostics, checkModeDirectives(o.file, o.root)...)
 	}
 
-	// Merge UI config
-	if override.UI.TrayIcon.Size != 0 {
-		result.UI.TrayIcon.Size = override.UI.TrayIcon.Size
+	var merged yaml.Node
+	if err := merged.Encode(base); err != nil {
+		return nil, fmt.Errorf("failed to encode base config: %w", err)
 	}
-	if override.UI.TrayIcon.CornerRadius != 0 {
-		result.UI.TrayIcon.CornerRadius = override.UI.TrayIcon.CornerRadius
+	if o.root != nil {
+		mergeNodes(&merged, o.root, "")
 	}
-	if override.UI.TrayIcon.Padding != 0 {
-		result.UI.TrayIcon.Padding = override.UI.TrayIcon.Padding
+
+	var result Config
+	if err := merged.Decode(&result); err != nil {
+		// Start over from a copy of base and decode what does decode
+		var copied yaml.Node
+		if err := copied.Encode(base); err != nil {
+			return nil, fmt.Errorf("failed to encode base config: %w", err)
+		}
+		result = Config{}
+		if err := copied.Decode(&result); err != nil {
+			return nil, fmt.Errorf("failed to copy base config: %w", err)
+		}
+		reported := make(map[string]bool)
+		for _, d := range diagnostics {
+			reported[d.Path] = true
+		}
+		for _, err := range decodeLenient(&merged, reflect.ValueOf(&result).Elem(), "", reported) {
+			diagnostics = append(diagnostics, diagnose("", yamlErrors(o.file, err))...)
+		}
 	}
-	if override.UI.TrayIcon.BgOpacity != 0 {
-		result.UI.TrayIcon.BgOpacity = override.UI.TrayIcon.BgOpacity
+
+	// Bindings are merged by directive rather than replaced as a whole.
+	if shortcuts := mappingValue(o.root, "shortcuts"); shortcuts != nil && !isNull(shortcuts) {
+		bindings, errs := mergeBindings(base.Shortcuts.Bindings, shortcuts)
+		for _, err := range errs {
+			diagnostics = append(diagnostics, diagnose("", yamlErrors(o.file, err))...)
+		}
+		result.Shortcuts.Bindings = bindings
 	}
-	if override.UI.TrayIcon.BgColor != (color.RGBA{}) {
-		result.UI.TrayIcon.BgColor = override.UI.TrayIcon.BgColor
+
+	return &result, invalid(diagnostics)
+}
+
+// unmarshalerType is the type of values that decode themselves, which decodeLenient decodes as a whole.
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+
+// decodeLenient decodes node into out one field, map entry and list item at a time, so a value that does not
+// decode leaves its field as it was instead of failing the whole document. Map entries and list items with
+// such a value are left out. It returns an error for every value that did not decode, except for those at
+// the paths in reported, which checkFields has already reported.
+func decodeLenient(node *yaml.Node, out reflect.Value, path string, reported map[string]bool) []error {
+	decoded := reflect.New(out.Type())
+	err := node.Decode(decoded.Interface())
+	if err == nil {
+		out.Set(decoded.Elem())
+		return nil
 	}
-	if override.UI.TrayIcon.TextColor != (color.RGBA{}) {
-		result.UI.TrayIcon.TextColor = override.UI.TrayIcon.TextColor
+
+	t := out.Type()
+	var errs []error
+	switch {
+	case reflect.PointerTo(t).Implements(unmarshalerType):
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if field, ok := fields[key.Value]; ok {
+				errs = append(errs, decodeLenient(value, out.FieldByIndex(field.Index), joinPath(path, key.Value), reported)...)
+			}
+		}
+		return errs
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		if out.IsNil() {
+			out.Set(reflect.MakeMap(t))
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			k := reflect.New(t.Key())
+			if err := key.Decode(k.Interface()); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			elem := reflect.New(t.Elem()).Elem()
+			if elemErrs := decodeLenient(value, elem, joinPath(path, key.Value), reported); len(elemErrs) > 0 {
+				errs = append(errs, elemErrs...)
+				continue
+			}
+			out.SetMapIndex(k.Elem(), elem)
+		}
+		return errs
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		items := reflect.MakeSlice(t, 0, len(node.Content))
+		for i, item := range node.Content {
+			elem := reflect.New(t.Elem()).Elem()
+			if elemErrs := decodeLenient(item, elem, fmt.Sprintf("%s[%d]", path, i), reported); len(elemErrs) > 0 {
+				errs = append(errs, elemErrs...)
+				continue
+			}
+			items = reflect.Append(items, elem)
+		}
+		out.Set(items)
+		return errs
+	}
+	if reported[path] {
+		return nil
 	}
-	if override.UI.TrayIcon.ShadowColor != (color.RGBA{}) {
-		result.UI.TrayIcon.ShadowColor = override.UI.TrayIcon.ShadowColor
+	return []error{err}
+}
+
+// checkModeDirectives reports the merge directives of mode bindings. The bindings of a mode replace those
+// the mode had in earlier layers as a whole, so there is nothing for a directive to merge with.
+func checkModeDirectives(file string, root *yaml.Node) []Diagnostic {
+	modes := mappingValue(mappingValue(root, "shortcuts"), "modes")
+	if modes == nil || modes.Kind != yaml.MappingNode {
+		return nil
 	}
-	if override.UI.TrayIcon.ShadowOpacity != 0 {
-		result.UI.TrayIcon.ShadowOpacity = override.UI.TrayIcon.ShadowOpacity
+	var diagnostics []Diagnostic
+	for i := 0; i+1 < len(modes.Content); i += 2 {
+		path := joinPath("shortcuts.modes", modes.Content[i].Value) + ".bindings"
+		list := mappingValue(modes.Content[i+1], "bindings")
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		for j, item := range list.Content {
+			for k := 0; item.Kind == yaml.MappingNode && k+1 < len(item.Content); k += 2 {
+				if key := item.Content[k]; key.Value == "merge" {
+					diagnostics = append(diagnostics, Diagnostic{
+						Position: Position{File: file, Line: key.Line, Column: key.Column},
+						Path:     fmt.Sprintf("%s[%d].merge", path, j),
+						Message:  "merge directives only apply to shortcuts.bindings; the bindings of a mode replace those of earlier layers",
+					})
+				}
+			}
+		}
 	}
+	return diagnostics
+}
 
-	// Merge virtual desktops config
-	if override.VirtualDesktops.MinimumCount != 0 {
-		result.VirtualDesktops.MinimumCount = override.VirtualDesktops.MinimumCount
+// paths lists the dotted paths of the leaf fields the overlay sets, excluding binding directives.
+// Each binding of a mode counts as a field of its own, like those of shortcuts.bindings.
+func (o *Overlay) paths() []string {
+	var paths []string
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldPath := joinPath(path, key.Value)
+			switch {
+			case isNull(value) || isDirectiveField(fieldPath):
+			case value.Kind == yaml.MappingNode:
+				walk(value, fieldPath)
+			case value.Kind == yaml.SequenceNode && isModeBindings(fieldPath):
+				for j := range value.Content {
+					paths = append(paths, fmt.Sprintf("%s[%d]", fieldPath, j))
+				}
+			default:
+				paths = append(paths, fieldPath)
+			}
+		}
+	}
+	if o.root != nil {
+		walk(o.root, "")
 	}
+	return paths
+}
 
-	// Merge shortcuts
-	if len(override.Shortcuts.Bindings) > 0 {
-		result.Shortcuts.Bindings = override.Shortcuts.Bindings
+// bindings decodes the override bindings of the overlay, including their merge directives, and returns
+// them with the node of each. Bindings that do not decode are left out; Apply reports them.
+func (o *Overlay) bindings() ([]KeyBinding, []*yaml.Node) {
+	list := mappingValue(mappingValue(o.root, "shortcuts"), "bindings")
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return nil, nil
+	}
+	var bindings []KeyBinding
+	var nodes []*yaml.Node
+	for _, item := range list.Content {
+		var binding KeyBinding
+		if err := item.Decode(&binding); err == nil {
+			bindings = append(bindings, binding)
+			nodes = append(nodes, item)
+		}
 	}
+	return bindings, nodes
+}
+
+// mergeNodes copies every field present in override into base, recursing into mappings.
+// Null values count as absent so an empty section such as `ui:` does not wipe the defaults.
+func mergeNodes(base, override *yaml.Node, path string) {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		fieldPath := joinPath(path, key.Value)
+		if isNull(value) || isDirectiveField(fieldPath) {
+			continue
+		}
 
-	return &result
+		existing := mappingValue(base, key.Value)
+		switch {
+		case existing == nil:
+			base.Content = append(base.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeNodes(existing, value, fieldPath)
+		default:
+			*existing = *value
+		}
+	}
+}
+
+// isDirectiveField reports whether a path is merged by mergeBindings instead of mergeNodes.
+func isDirectiveField(path string) bool {
+	return path == "shortcuts.bindings" || path == "shortcuts.merge"
+}
+
+// isModeBindings reports whether a path is the binding list of a mode, such as "shortcuts.modes.resize.bindings".
+func isModeBindings(path string) bool {
+	name, ok := strings.CutPrefix(path, "shortcuts.modes.")
+	if !ok {
+		return false
+	}
+	name, ok = strings.CutSuffix(name, ".bindings")
+	return ok && name != "" && !strings.Contains(name, ".")
+}
+
+// mergeBindings applies the bindings of a shortcuts override node on top of the base bindings.
+// An override binding that does not decode or merge is reported and skipped; the others still apply.
+func mergeBindings(base []KeyBinding, shortcuts *yaml.Node) ([]KeyBinding, []error) {
+	var errs []error
+	var result []KeyBinding
+	mode := ""
+	if node := mappingValue(shortcuts, "merge"); node != nil {
+		mode = node.Value
+	}
+	switch mode {
+	case "", MergeAppend:
+		result = slices.Clone(base)
+	case MergeReplace:
+		// Start from scratch: the override list is the complete list of bindings.
+	default:
+		errs = append(errs, fmt.Errorf("shortcuts.merge: unknown merge mode %q (want %s or %s)", mode, MergeAppend, MergeReplace))
+		result = slices.Clone(base)
+	}
+
+	list := mappingValue(shortcuts, "bindings")
+	if list == nil || isNull(list) {
+		return result, errs
+	}
+	if list.Kind != yaml.SequenceNode {
+		return result, append(errs, fmt.Errorf("line %d: shortcuts.bindings must be a list", list.Line))
+	}
+
+	for _, item := range list.Content {
+		var binding KeyBinding
+		if err := item.Decode(&binding); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		directive := binding.Merge
+		binding.Merge = ""
+		idx := slices.IndexFunc(result, func(existing KeyBinding) bool {
+			return existing.matches(binding)
+		})
+
+		switch directive {
+		case "", MergeAppend:
+			if idx >= 0 {
+				result[idx] = binding
+			} else {
+				result = append(result, binding)
+			}
+		case MergeReplace:
+			if idx < 0 {
+				errs = append(errs, fmt.Errorf("line %d: no binding %s to replace", item.Line, binding.identity()))
+				continue
+			}
+			patched, err := patchBinding(result[idx], item)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result[idx] = patched
+		case MergeRemove:
+			if idx < 0 {
+				errs = append(errs, fmt.Errorf("line %d: no binding %s to remove", item.Line, binding.identity()))
+				continue
+			}
+			result = slices.Delete(result, idx, idx+1)
+		default:
+			errs = append(errs, fmt.Errorf("line %d: unknown merge directive %q (want %s, %s or %s)",
+				item.Line, directive, MergeAppend, MergeReplace, MergeRemove))
+		}
+	}
+
+	return result, errs
+}
+
+// patchBinding applies the fields written in an override binding node to an existing binding.
+func patchBinding(existing KeyBinding, override *yaml.Node) (KeyBinding, error) {
+	var merged yaml.Node
+	if err := merged.Encode(existing); err != nil {
+		return KeyBinding{}, err
+	}
+	mergeNodes(&merged, override, "")
+
+	var patched KeyBinding
+	if err := merged.Decode(&patched); err != nil {
+		return KeyBinding{}, err
+	}
+	patched.Merge = ""
+	return patched, nil
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil if absent.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isNull reports whether a node is an explicit or implicit YAML null.
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// joinPath builds a dotted field path such as "ui.tray_icon.size".
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
 }
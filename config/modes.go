@@ -0,0 +1,16 @@
+package config
+
+import (
+	"time"
+)
+
+// DefaultModeName is the name of the bindings active outside of any mode; it cannot be used for a mode.
+const DefaultModeName = "default"
+
+// ModeConfig holds the bindings of a named mode.
+// While a mode is active only its bindings apply, and they may be bare keys such as "H" since
+// they cannot clash with typing in the focused application for long.
+type ModeConfig struct {
+	Timeout  time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"` // Leave the mode after this long without a key press; zero stays until ExitMode
+	Bindings []KeyBinding  `yaml:"bindings" json:"bindings"`
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShortcutsModesValidation tests the validation of binding modes
+func TestShortcutsModesValidation(t *testing.T) {
+	enterResize := KeyBinding{Keys: KeyList{"Alt", "R"}, Action: "EnterMode", Params: PositionalParams("resize")}
+	resize := ModeConfig{Bindings: []KeyBinding{
+		{Keys: KeyList{"H"}, Action: "SwitchDesktop", Params: PositionalParams("1")},
+		{Keys: KeyList{"Esc"}, Action: "ExitMode"},
+	}}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{
+			name: "valid mode",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings, enterResize)
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": resize}
+			},
+		},
+		{
+			name: "entering an unknown mode",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Bindings = append(cfg.Shortcuts.Bindings, enterResize)
+			},
+			wantErr: `unknown mode "resize"`,
+		},
+		{
+			name: "reserved mode name",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"Default": resize}
+			},
+			wantErr: "invalid mode name",
+		},
+		{
+			name: "negative timeout",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": {Timeout: -time.Second}}
+			},
+			wantErr: "timeout cannot be negative",
+		},
+		{
+			name: "invalid binding in a mode",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": {Bindings: []KeyBinding{
+					{Keys: KeyList{"NotAKey"}, Action: "ExitMode"},
+				}}}
+			},
+			wantErr: `invalid binding in mode "resize"`,
+		},
+		{
+			name: "id used twice across modes",
+			modify: func(cfg *Config) {
+				cfg.Shortcuts.Modes = map[string]ModeConfig{"resize": {Bindings: []KeyBinding{
+					{ID: "create-desktop", Keys: KeyList{"N"}, Action: "CreateDesktop"},
+				}}}
+			},
+			wantErr: `binding id "create-desktop" is used more than once`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"wincuts/action"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Params are the parameters of a binding's action. In config files they are written either as a list
+// in the order the action declares them, e.g. ["3"], or by name, e.g. {desktop: 3, follow: false};
+// a single value may also be written on its own.
+type Params []action.Arg
+
+// PositionalParams returns parameters given as a list.
+func PositionalParams(values ...string) Params {
+	params := make(Params, len(values))
+	for i, value := range values {
+		params[i] = action.Arg{Value: value}
+	}
+	return params
+}
+
+// Named reports whether the parameters are given by name.
+func (p Params) Named() bool {
+	return slices.ContainsFunc(p, func(arg action.Arg) bool { return arg.Name != "" })
+}
+
+// Values returns the values in the order they are written.
+func (p Params) Values() []string {
+	values := make([]string, len(p))
+	for i, arg := range p {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// path returns the field path of the value an error of action.Definition.Parse is about, relative to
+// the binding, e.g. "params.desktop" or "params[0]", or "params" if it is about no value in particular.
+func (p Params) path(err error) string {
+	var param *action.ParamError
+	if !errors.As(err, &param) || param.Index < 0 || param.Index >= len(p) {
+		return "params"
+	}
+	if name := p[param.Index].Name; name != "" {
+		return "params." + name
+	}
+	return fmt.Sprintf("params[%d]", param.Index)
+}
+
+// String formats the parameters for listings, e.g. "3" or "desktop=3 follow=false".
+func (p Params) String() string {
+	parts := make([]string, len(p))
+	for i, arg := range p {
+		parts[i] = arg.Value
+		if arg.Name != "" {
+			parts[i] = arg.Name + "=" + arg.Value
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Params.
+func (p *Params) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Tag == "!!null" {
+			*p = nil
+			return nil
+		}
+		*p = PositionalParams(value.Value)
+	case yaml.SequenceNode:
+		params := make(Params, 0, len(value.Content))
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: parameter must be a single value", item.Line)
+			}
+			params = append(params, action.Arg{Value: item.Value})
+		}
+		*p = params
+	case yaml.MappingNode:
+		params := make(Params, 0, len(value.Content)/2)
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key, item := value.Content[i], value.Content[i+1]
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: parameter %s must be a single value", item.Line, key.Value)
+			}
+			params = append(params, action.Arg{Name: key.Value, Value: item.Value})
+		}
+		*p = params
+	default:
+		return fmt.Errorf("line %d: params must be a list or a mapping of names to values", value.Line)
+	}
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler for Params, keeping the form they were written in.
+func (p Params) MarshalYAML() (any, error) {
+	if !p.Named() {
+		return p.Values(), nil
+	}
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, arg := range p {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: arg.Name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: arg.Value})
+	}
+	return node, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Params, accepting strings, numbers and booleans as values.
+func (p *Params) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	var params Params
+	switch token {
+	case nil:
+		*p = nil
+		return nil
+	case json.Delim('['):
+		params = Params{}
+		for dec.More() {
+			value, err := jsonParamValue(dec, "")
+			if err != nil {
+				return err
+			}
+			params = append(params, action.Arg{Value: value})
+		}
+	case json.Delim('{'):
+		params = Params{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			name := key.(string)
+			value, err := jsonParamValue(dec, name)
+			if err != nil {
+				return err
+			}
+			params = append(params, action.Arg{Name: name, Value: value})
+		}
+	default:
+		*p = PositionalParams(fmt.Sprint(token))
+		return nil
+	}
+	*p = params
+	return nil
+}
+
+// jsonParamValue reads a single parameter value, the one named name if it is not empty.
+func jsonParamValue(dec *json.Decoder, name string) (string, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	switch token.(type) {
+	case string, json.Number, bool:
+		return fmt.Sprint(token), nil
+	}
+	if name != "" {
+		return "", fmt.Errorf("parameter %s must be a single value", name)
+	}
+	return "", fmt.Errorf("parameter must be a single value")
+}
+
+// MarshalJSON implements json.Marshaler for Params, keeping the form they were written in.
+func (p Params) MarshalJSON() ([]byte, error) {
+	if !p.Named() {
+		return json.Marshal(p.Values())
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, arg := range p {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(arg.Name)
+		value, _ := json.Marshal(arg.Value)
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
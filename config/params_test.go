@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestParamsUnmarshal tests the forms params can be written in and that encoding keeps the form
+func TestParamsUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		json     string
+		expected Params
+	}{
+		{
+			name:     "list",
+			yaml:     `params: ["3", 4]`,
+			json:     `{"params": ["3", 4]}`,
+			expected: PositionalParams("3", "4"),
+		},
+		{
+			name:     "single value",
+			yaml:     `params: 3`,
+			json:     `{"params": 3}`,
+			expected: PositionalParams("3"),
+		},
+		{
+			name:     "empty list",
+			yaml:     `params: []`,
+			json:     `{"params": []}`,
+			expected: Params{},
+		},
+		{
+			name:     "named in order",
+			yaml:     `params: {follow: false, desktop: 3}`,
+			json:     `{"params": {"follow": false, "desktop": 3}}`,
+			expected: Params{{Name: "follow", Value: "false"}, {Name: "desktop", Value: "3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fromYAML KeyBinding
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &fromYAML))
+			assert.Equal(t, tt.expected, fromYAML.Params)
+
+			var fromJSON KeyBinding
+			require.NoError(t, json.Unmarshal([]byte(tt.json), &fromJSON))
+			assert.Equal(t, tt.expected, fromJSON.Params)
+
+			encoded, err := yaml.Marshal(fromYAML)
+			require.NoError(t, err)
+			var roundTrip KeyBinding
+			require.NoError(t, yaml.Unmarshal(encoded, &roundTrip))
+			assert.Equal(t, tt.expected, roundTrip.Params)
+
+			encoded, err = json.Marshal(fromJSON)
+			require.NoError(t, err)
+			roundTrip = KeyBinding{}
+			require.NoError(t, json.Unmarshal(encoded, &roundTrip))
+			assert.Equal(t, tt.expected, roundTrip.Params)
+		})
+	}
+
+	var binding KeyBinding
+	assert.ErrorContains(t, yaml.Unmarshal([]byte("params: {desktop: [1, 2]}"), &binding), "parameter desktop must be a single value")
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"wincuts/keyboard/types"
+)
+
+// RemapConfig sends the keys To in place of the keys From. A single key remapped to a single key stays
+// remapped while held, so it can act as a modifier; otherwise typing From sends To as a whole.
+type RemapConfig struct {
+	From KeyList `yaml:"from" json:"from"` // Keys typed, e.g. "CapsLock" or "Alt+H"
+	To   KeyList `yaml:"to" json:"to"`     // Keys sent instead, e.g. "LCtrl" or "Left"
+}
+
+// GetKeys returns the virtual keys of both sides, resolving custom modifiers in modifiers and skipping invalid names.
+func (r *RemapConfig) GetKeys(modifiers *types.Modifiers) (from, to types.KeyBinding) {
+	for _, name := range r.From {
+		if vk, ok := modifiers.LookupKey(name); ok {
+			from = append(from, vk)
+		}
+	}
+	for _, name := range r.To {
+		if vk, ok := modifiers.LookupKey(name); ok {
+			to = append(to, vk)
+		}
+	}
+	return from, to
+}
+
+// validateRemaps checks every remap against the modifiers defined by validateLayers.
+func (s *ShortcutsConfig) validateRemaps(modifiers *types.Modifiers) []error {
+	layerKeys := make(map[types.VirtualKey]bool)
+	for _, layer := range s.Layers {
+		key, _ := layer.GetKeys()
+		layerKeys[key] = true
+	}
+	var errs []error
+	var seen []types.KeyBinding
+	for i, remap := range s.Remap {
+		if err := remap.validate(modifiers, layerKeys, seen); err != nil {
+			errs = append(errs, &FieldError{Path: fmt.Sprintf("remap[%d]", i), Err: err})
+			continue
+		}
+		from, _ := remap.GetKeys(modifiers)
+		seen = append(seen, from)
+	}
+	return errs
+}
+
+// validate checks a remap given the custom modifiers, the keys used by layers and the keys remapped before it.
+func (r *RemapConfig) validate(modifiers *types.Modifiers, layerKeys map[types.VirtualKey]bool, seen []types.KeyBinding) error {
+	if len(r.From) == 0 || len(r.To) == 0 {
+		return fmt.Errorf("remap %s to %s: both sides need keys", r.From, r.To)
+	}
+	if r.From.IsSequence() || r.To.IsSequence() {
+		return fmt.Errorf("remap %s to %s: key sequences cannot be remapped", r.From, r.To)
+	}
+	from, to := r.GetKeys(modifiers)
+	if len(from) != len(r.From) {
+		return &FieldError{Path: "from", Err: fmt.Errorf("remap from %s: invalid key", r.From)}
+	}
+	if len(to) != len(r.To) || slices.ContainsFunc(to, types.VirtualKey.IsCustomModifier) {
+		return &FieldError{Path: "to", Err: fmt.Errorf("remap to %s: invalid key", r.To)}
+	}
+	if len(from) == 1 && len(to) == 1 {
+		if from[0].IsCustomModifier() {
+			return &FieldError{Path: "from", Err: fmt.Errorf("remap from %s: custom modifiers only exist in combinations", r.From)}
+		}
+		if layerKeys[from[0]] {
+			return &FieldError{Path: "from", Err: fmt.Errorf("remap from %s: the key is used by a layer", r.From)}
+		}
+	} else if !slices.ContainsFunc(from, func(vk types.VirtualKey) bool { return !vk.IsModifier() }) {
+		return &FieldError{Path: "from", Err: fmt.Errorf("remap from %s: a combination needs a key besides modifiers", r.From)}
+	}
+	if slices.ContainsFunc(seen, func(other types.KeyBinding) bool { return from.SubsetOf(other) && other.SubsetOf(from) }) {
+		return &FieldError{Path: "from", Err: fmt.Errorf("keys %s are remapped twice", r.From)}
+	}
+	return nil
+}
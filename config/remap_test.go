@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestShortcutsRemapValidation tests the validation of keys sent in place of others
+func TestShortcutsRemapValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		remap   []RemapConfig
+		wantErr string
+	}{
+		{
+			name:  "swapped keys",
+			remap: []RemapConfig{{From: KeyList{"CapsLock"}, To: KeyList{"LCtrl"}}, {From: KeyList{"LCtrl"}, To: KeyList{"CapsLock"}}},
+		},
+		{
+			name:  "combinations",
+			remap: []RemapConfig{{From: KeyList{"Alt", "H"}, To: KeyList{"Left"}}, {From: KeyList{"Hyper", "C"}, To: KeyList{"Ctrl", "C"}}},
+		},
+		{
+			name:    "unknown key",
+			remap:   []RemapConfig{{From: KeyList{"Caps"}, To: KeyList{"LCtrl"}}},
+			wantErr: "remap from Caps: invalid key",
+		},
+		{
+			name:    "empty target",
+			remap:   []RemapConfig{{From: KeyList{"CapsLock"}}},
+			wantErr: "both sides need keys",
+		},
+		{
+			name:    "sequence",
+			remap:   []RemapConfig{{From: KeyList{"Alt+W", "3"}, To: KeyList{"Left"}}},
+			wantErr: "key sequences cannot be remapped",
+		},
+		{
+			name:    "modifiers only",
+			remap:   []RemapConfig{{From: KeyList{"Alt", "Shift"}, To: KeyList{"LCtrl"}}},
+			wantErr: "a combination needs a key besides modifiers",
+		},
+		{
+			name:    "layer key",
+			remap:   []RemapConfig{{From: KeyList{"apps"}, To: KeyList{"LCtrl"}}},
+			wantErr: "the key is used by a layer",
+		},
+		{
+			name:    "custom modifier sent",
+			remap:   []RemapConfig{{From: KeyList{"Alt", "H"}, To: KeyList{"Hyper", "H"}}},
+			wantErr: "remap to Hyper+H: invalid key",
+		},
+		{
+			name:    "remapped twice",
+			remap:   []RemapConfig{{From: KeyList{"Alt", "H"}, To: KeyList{"Left"}}, {From: KeyList{"H", "menu"}, To: KeyList{"Home"}}},
+			wantErr: "remapped twice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Shortcuts.Layers = map[string]LayerConfig{"Hyper": {Key: "Apps"}}
+			cfg.Shortcuts.Remap = tt.remap
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
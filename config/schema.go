@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/json"
+	"image/color"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SchemaURI identifies the JSON Schema draft the generated schema conforms to.
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document describing the config file format.
+type Schema map[string]any
+
+// GenerateSchema derives a JSON Schema for the config file from the Config type.
+// Field names follow the yaml tags, so the schema stays in sync with what the loader accepts.
+func GenerateSchema(actions ActionProvider) Schema {
+	schema := schemaFor(reflect.TypeOf(Config{}))
+	schema["$schema"] = SchemaURI
+	schema["title"] = "WinCuts configuration"
+
+	// Constrain ranges and enumerated string fields that reflection alone cannot describe.
+	size := schema.property("ui").property("tray_icon").property("size")
+	size["minimum"], size["maximum"] = MinTrayIconSize, MaxTrayIconSize
+	schema.property("virtual_desktops").property("minimum_count")["minimum"] = 0
+	shortcuts := schema.property("shortcuts")
+	shortcuts.property("merge")["enum"] = []string{MergeAppend, MergeReplace}
+	shortcuts.property("execution").property("mode")["enum"] = []string{ExecutionSerial, ExecutionConcurrent}
+	binding, _ := shortcuts.property("bindings")["items"].(Schema)
+	binding.property("merge")["enum"] = []string{MergeAppend, MergeReplace, MergeRemove}
+	constrainBinding(binding, actions)
+	mode, _ := shortcuts.property("modes")["additionalProperties"].(Schema)
+	modeBinding, _ := mode.property("bindings")["items"].(Schema)
+	delete(modeBinding["properties"].(map[string]any), "merge")
+	constrainBinding(modeBinding, actions)
+
+	return schema
+}
+
+// constrainBinding describes the action names and the key syntax of a binding schema.
+func constrainBinding(binding Schema, actions ActionProvider) {
+	binding.property("action")["enum"] = actionNames(actions)
+	binding.property("keys")["description"] = "A key combination, or a sequence of combinations pressed one after another such as [\"Alt+W\", \"3\"]"
+}
+
+// WriteSchema writes the schema as indented JSON.
+func WriteSchema(w io.Writer, schema Schema) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(schema)
+}
+
+// property returns the schema of a named object property, or an empty schema if absent.
+func (s Schema) property(name string) Schema {
+	props, _ := s["properties"].(map[string]any)
+	if prop, ok := props[name].(Schema); ok {
+		return prop
+	}
+	return Schema{}
+}
+
+var (
+	levelType      = reflect.TypeOf(slog.Level(0))
+	colorType      = reflect.TypeOf(color.RGBA{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	stringListType = reflect.TypeOf(StringList{})
+	keyListType    = reflect.TypeOf(KeyList{})
+	paramsType     = reflect.TypeOf(Params{})
+	triggerType    = reflect.TypeOf(Trigger(""))
+	repeatType     = reflect.TypeOf(Repeat(""))
+)
+
+// settingDurationPattern matches the parenthesized duration of a trigger or repeat setting, where a bare number is in milliseconds.
+const settingDurationPattern = `(\s*\(\s*[0-9.]+(ns|us|µs|ms|s|m|h)?\s*\))`
+
+// schemaFor builds the schema for a Go type, following yaml tags for struct fields.
+func schemaFor(t reflect.Type) Schema {
+	switch t {
+	case levelType:
+		return Schema{"type": "string", "enum": []string{"DEBUG", "INFO", "WARN", "ERROR"}}
+	case durationType:
+		return Schema{"type": "string", "pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`, "description": "Duration such as \"500ms\" or \"1.5s\""}
+	case stringListType:
+		return Schema{"oneOf": []any{Schema{"type": "string"}, Schema{"type": "array", "items": Schema{"type": "string"}}}}
+	case keyListType:
+		return Schema{"oneOf": []any{
+			Schema{"type": "array", "items": Schema{"type": "string"}},
+			Schema{"type": "string", "description": "Keys joined by \"+\", e.g. \"Alt+Shift+1\""},
+		}}
+	case paramsType:
+		value := Schema{"type": []string{"string", "number", "boolean"}}
+		return Schema{"oneOf": []any{
+			Schema{"type": "array", "items": value, "description": "Parameters in the order the action declares them"},
+			Schema{"type": "object", "additionalProperties": value, "description": "Parameters by name, e.g. {\"desktop\": 3, \"follow\": false}"},
+			value,
+		}}
+	case triggerType:
+		return Schema{
+			"type":        "string",
+			"pattern":     `^\s*(up|down|(hold|double)` + settingDurationPattern + `?)\s*$`,
+			"description": "When the binding fires: up (default), down, hold or double, the latter two with an optional duration such as \"hold(800ms)\"",
+		}
+	case repeatType:
+		return Schema{
+			"type":        "string",
+			"pattern":     `^\s*(ignore|fire|throttle` + settingDurationPattern + `)\s*$`,
+			"description": "What auto-repeat of held keys does: ignore (default), fire, or throttle with an interval such as \"throttle(150ms)\"",
+		}
+	case colorType:
+		channel := Schema{"type": "integer", "minimum": 0, "maximum": 255}
+		return Schema{
+			"type":                 "object",
+			"properties":           map[string]any{"r": channel, "g": channel, "b": channel, "a": channel},
+			"additionalProperties": false,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Uint8:
+		return Schema{"type": "integer", "minimum": 0, "maximum": 255}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice:
+		return Schema{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			props[name] = schemaFor(field.Type)
+		}
+		return Schema{"type": "object", "properties": props, "additionalProperties": false}
+	default:
+		return Schema{}
+	}
+}
+
+// actionNames returns the sorted names of all known actions.
+func actionNames(actions ActionProvider) []string {
+	var names []string
+	for name := range actions.GetActions() {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
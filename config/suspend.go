@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	"wincuts/keyboard/types"
+)
+
+// SuspendConfig turns every shortcut, layer and remap off while suspended, letting all keys through untouched.
+type SuspendConfig struct {
+	Toggle     KeyList    `yaml:"toggle,omitempty" json:"toggle,omitempty"`           // Keys that suspend and resume, active in every mode and while suspended, e.g. "Ctrl+Alt+Pause"
+	FullScreen bool       `yaml:"full_screen,omitempty" json:"full_screen,omitempty"` // Suspend while a full-screen window is in the foreground
+	Process    StringList `yaml:"process,omitempty" json:"process,omitempty"`         // Suspend while one of these executables is in the foreground
+}
+
+// Validate implements ConfigValidator for SuspendConfig.
+func (c *SuspendConfig) Validate() error {
+	if len(c.Toggle) == 0 {
+		return nil
+	}
+	if c.Toggle.IsSequence() {
+		return fmt.Errorf("toggle %s cannot be a key sequence", c.Toggle)
+	}
+	keys := c.GetToggleKeys()
+	if len(keys) != len(c.Toggle) || slices.ContainsFunc(keys, types.VirtualKey.IsCustomModifier) {
+		return fmt.Errorf("toggle %s: invalid key", c.Toggle)
+	}
+	if !slices.ContainsFunc(keys, func(vk types.VirtualKey) bool { return !vk.IsModifier() }) {
+		return fmt.Errorf("toggle %s needs a key besides modifiers", c.Toggle)
+	}
+	return nil
+}
+
+// GetToggleKeys returns the virtual keys of the toggle, skipping invalid names.
+func (c *SuspendConfig) GetToggleKeys() types.KeyBinding {
+	var keys types.KeyBinding
+	for _, name := range c.Toggle {
+		if vk, ok := types.LookupKey(name); ok {
+			keys = append(keys, vk)
+		}
+	}
+	return keys
+}
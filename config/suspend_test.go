@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestSuspendConfigValidation tests the validation of the suspend toggle keys
+func TestSuspendConfigValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "no toggle", yaml: `full_screen: true`},
+		{name: "toggle", yaml: `toggle: "Ctrl+Alt+Pause"`},
+		{name: "unknown key", yaml: `toggle: "Ctrl+Pause!"`, wantErr: "invalid key"},
+		{name: "modifiers only", yaml: `toggle: "Ctrl+Alt"`, wantErr: "needs a key besides modifiers"},
+		{name: "sequence", yaml: `toggle: ["Ctrl+Alt+P", "S"]`, wantErr: "cannot be a key sequence"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg SuspendConfig
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &cfg))
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
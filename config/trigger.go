@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trigger decides when a binding fires: "up" when its keys are released (the default), "down" as soon as
+// they are pressed, "hold" once they are held for a while and "double" on a second tap shortly after the first.
+// Hold and double take an optional duration such as "hold(800ms)" or "double(250ms)".
+type Trigger string
+
+// Trigger kinds, as returned by Trigger.Parse.
+const (
+	TriggerUp     Trigger = "up"
+	TriggerDown   Trigger = "down"
+	TriggerHold   Trigger = "hold"
+	TriggerDouble Trigger = "double"
+)
+
+// Parse returns the kind of trigger and its duration, filling in DefaultHoldDuration or DefaultDoubleTapInterval.
+// The empty Trigger is TriggerUp.
+func (t Trigger) Parse() (Trigger, time.Duration, error) {
+	name, duration, hasDuration, err := splitSetting(string(t))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid trigger %q: %w", t, err)
+	}
+	kind := Trigger(name)
+	switch kind {
+	case "", TriggerUp, TriggerDown:
+		if hasDuration {
+			return "", 0, fmt.Errorf("trigger %q takes no duration", name)
+		}
+		if kind == "" {
+			kind = TriggerUp
+		}
+		return kind, 0, nil
+	case TriggerHold:
+		if !hasDuration {
+			duration = DefaultHoldDuration
+		}
+		return kind, duration, nil
+	case TriggerDouble:
+		if !hasDuration {
+			duration = DefaultDoubleTapInterval
+		}
+		return kind, duration, nil
+	}
+	return "", 0, fmt.Errorf("unknown trigger %q, expected up, down, hold or double", t)
+}
+
+// Repeat decides what happens while Windows auto-repeats the keys of a held binding: "ignore" fires once per
+// press (the default), "fire" fires again on every repeat and "throttle(150ms)" at most once per interval.
+// A bare number such as "throttle(150)" is in milliseconds.
+type Repeat string
+
+// Repeat kinds, as returned by Repeat.Parse.
+const (
+	RepeatIgnore   Repeat = "ignore"
+	RepeatFire     Repeat = "fire"
+	RepeatThrottle Repeat = "throttle"
+)
+
+// Parse returns the kind of repeat and the throttle interval. The empty Repeat is RepeatIgnore.
+func (r Repeat) Parse() (Repeat, time.Duration, error) {
+	name, interval, hasInterval, err := splitSetting(string(r))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid repeat %q: %w", r, err)
+	}
+	kind := Repeat(name)
+	switch kind {
+	case "", RepeatIgnore, RepeatFire:
+		if hasInterval {
+			return "", 0, fmt.Errorf("repeat %q takes no interval", name)
+		}
+		if kind == "" {
+			kind = RepeatIgnore
+		}
+		return kind, 0, nil
+	case RepeatThrottle:
+		if !hasInterval {
+			return "", 0, fmt.Errorf("repeat %q needs an interval, e.g. throttle(150ms)", name)
+		}
+		return kind, interval, nil
+	}
+	return "", 0, fmt.Errorf("unknown repeat %q, expected ignore, fire or throttle", r)
+}
+
+// splitSetting splits a setting such as "hold(800ms)" into its lower-case name and the positive duration in
+// parentheses, where a bare number is in milliseconds. hasDuration is false without parentheses.
+func splitSetting(setting string) (name string, duration time.Duration, hasDuration bool, err error) {
+	name, arg, hasDuration := strings.Cut(strings.TrimSpace(setting), "(")
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !hasDuration {
+		return name, 0, false, nil
+	}
+	arg, closed := strings.CutSuffix(strings.TrimSpace(arg), ")")
+	if !closed {
+		return "", 0, false, fmt.Errorf("missing closing parenthesis")
+	}
+	arg = strings.TrimSpace(arg)
+	if ms, err := strconv.Atoi(arg); err == nil {
+		duration = time.Duration(ms) * time.Millisecond
+	} else if duration, err = time.ParseDuration(arg); err != nil {
+		return "", 0, false, err
+	}
+	if duration <= 0 {
+		return "", 0, false, fmt.Errorf("duration must be positive")
+	}
+	return name, duration, true, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTriggerParse tests parsing of binding triggers and their default durations
+func TestTriggerParse(t *testing.T) {
+	tests := []struct {
+		trigger  Trigger
+		kind     Trigger
+		duration time.Duration
+		wantErr  bool
+	}{
+		{trigger: "", kind: TriggerUp},
+		{trigger: "up", kind: TriggerUp},
+		{trigger: "Down", kind: TriggerDown},
+		{trigger: "hold", kind: TriggerHold, duration: DefaultHoldDuration},
+		{trigger: "hold(1.5s)", kind: TriggerHold, duration: 1500 * time.Millisecond},
+		{trigger: "double ( 250ms )", kind: TriggerDouble, duration: 250 * time.Millisecond},
+		{trigger: "double", kind: TriggerDouble, duration: DefaultDoubleTapInterval},
+		{trigger: "down(1s)", wantErr: true},
+		{trigger: "hold(1s", wantErr: true},
+		{trigger: "hold(0s)", wantErr: true},
+		{trigger: "hold(soon)", wantErr: true},
+		{trigger: "press", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.trigger), func(t *testing.T) {
+			kind, duration, err := tt.trigger.Parse()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.duration, duration)
+		})
+	}
+}
+
+// TestRepeatParse tests parsing of binding repeat policies
+func TestRepeatParse(t *testing.T) {
+	tests := []struct {
+		repeat   Repeat
+		kind     Repeat
+		interval time.Duration
+		wantErr  bool
+	}{
+		{repeat: "", kind: RepeatIgnore},
+		{repeat: "ignore", kind: RepeatIgnore},
+		{repeat: "FIRE", kind: RepeatFire},
+		{repeat: "throttle(150)", kind: RepeatThrottle, interval: 150 * time.Millisecond},
+		{repeat: "throttle(0.2s)", kind: RepeatThrottle, interval: 200 * time.Millisecond},
+		{repeat: "throttle", wantErr: true},
+		{repeat: "throttle(-5)", wantErr: true},
+		{repeat: "fire(100ms)", wantErr: true},
+		{repeat: "always", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.repeat), func(t *testing.T) {
+			kind, interval, err := tt.repeat.Parse()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.interval, interval)
+		})
+	}
+}
@@ -3,10 +3,18 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image/color"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"wincuts/action"
 	"wincuts/keyboard/types"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all application configuration.
@@ -24,14 +32,20 @@ type LogConfig struct {
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler for LogConfig.
-func (l *LogConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+func (l *LogConfig) UnmarshalYAML(value *yaml.Node) error {
 	var raw struct {
 		Level string
 	}
-	if err := unmarshal(&raw); err != nil {
+	if err := value.Decode(&raw); err != nil {
 		return err
 	}
-	return l.parseLevel(raw.Level)
+	if err := l.parseLevel(raw.Level); err != nil {
+		if level := mappingValue(value, "level"); level != nil {
+			return fmt.Errorf("line %d, column %d: %w", level.Line, level.Column, err)
+		}
+		return err
+	}
+	return nil
 }
 
 // UnmarshalJSON implements json.Unmarshaler for LogConfig.
@@ -45,19 +59,23 @@ func (l *LogConfig) UnmarshalJSON(data []byte) error {
 	return l.parseLevel(raw.Level)
 }
 
-// parseLevel converts a string level to slog.Level.
+// parseLevel converts a string level to slog.Level, ignoring case. An empty level keeps the current one.
 func (l *LogConfig) parseLevel(level string) error {
-	switch level {
-	case "DEBUG":
-		l.Level = slog.LevelDebug
-	case "INFO":
-		l.Level = slog.LevelInfo
-	case "WARN":
-		l.Level = slog.LevelWarn
-	case "ERROR":
-		l.Level = slog.LevelError
-	default:
-		l.Level = slog.LevelDebug // Default to debug if invalid
+	if level == "" {
+		return nil
+	}
+	parsed, err := parseLogLevel(strings.ToUpper(level))
+	if err != nil {
+		return fmt.Errorf("%w, want DEBUG, INFO, WARN or ERROR", err)
+	}
+	l.Level = parsed
+	return nil
+}
+
+// Validate implements ConfigValidator for LogConfig.
+func (l *LogConfig) Validate() error {
+	if _, err := parseLogLevel(l.Level.String()); err != nil {
+		return &FieldError{Path: "level", Err: err}
 	}
 	return nil
 }
@@ -79,6 +97,28 @@ type TrayIconConfig struct {
 	ShadowOpacity uint8      `yaml:"shadow_opacity" json:"shadow_opacity"`
 }
 
+// Limits of TrayIconConfig.Size in pixels: the digits need 16, and Windows icons are at most 256.
+const (
+	MinTrayIconSize = 16
+	MaxTrayIconSize = 256
+)
+
+// Validate implements ConfigValidator for TrayIconConfig. Opacities and colors cannot be out of range once
+// decoded; checkFields reports values too large for them in the file.
+func (t *TrayIconConfig) Validate() error {
+	var errs []error
+	if t.Size < MinTrayIconSize || t.Size > MaxTrayIconSize {
+		errs = append(errs, &FieldError{Path: "size", Err: fmt.Errorf("size must be between %d and %d", MinTrayIconSize, MaxTrayIconSize)})
+	}
+	if t.CornerRadius < 0 || t.CornerRadius > t.Size/2 {
+		errs = append(errs, &FieldError{Path: "corner_radius", Err: fmt.Errorf("corner_radius must be between 0 and half the size")})
+	}
+	if t.Padding < 0 || 2*t.Padding >= t.Size {
+		errs = append(errs, &FieldError{Path: "padding", Err: fmt.Errorf("padding must be at least 0 and leave room inside the icon")})
+	}
+	return errors.Join(errs...)
+}
+
 // VirtualDesktopsConfig holds configuration for virtual desktops.
 type VirtualDesktopsConfig struct {
 	MinimumCount int `yaml:"minimum_count" json:"minimum_count"` // Minimum number of virtual desktops to ensure
@@ -87,72 +127,142 @@ type VirtualDesktopsConfig struct {
 // Validate implements ConfigValidator for VirtualDesktopsConfig.
 func (v *VirtualDesktopsConfig) Validate() error {
 	if v.MinimumCount < 0 {
-		return fmt.Errorf("minimum_count cannot be negative")
+		return &FieldError{Path: "minimum_count", Err: fmt.Errorf("minimum_count cannot be negative")}
 	}
 	return nil
 }
 
 // ShortcutsConfig holds keyboard shortcut configurations.
 type ShortcutsConfig struct {
-	Bindings []KeyBinding `yaml:"bindings" json:"bindings"`
+	Merge           string        `yaml:"merge,omitempty" json:"merge,omitempty"`   // How override bindings combine with the base list: "append" (default) or "replace"
+	SequenceTimeout time.Duration `yaml:"sequence_timeout" json:"sequence_timeout"` // How long a key sequence waits for its next step (e.g. "1s")
+	Bindings        []KeyBinding  `yaml:"bindings" json:"bindings"`
+	// Named binding modes entered with the EnterMode action, e.g. a "resize" mode using bare H/J/K/L
+	Modes map[string]ModeConfig `yaml:"modes,omitempty" json:"modes,omitempty"`
+	// Keys acting as custom modifiers by name, e.g. CapsLock as "Hyper" for bindings like "Hyper+H"
+	Layers map[string]LayerConfig `yaml:"layers,omitempty" json:"layers,omitempty"`
+	// Keys sent to the focused application in place of others, e.g. CapsLock as LCtrl or Alt+H as Left.
+	// An override's list replaces the base list.
+	Remap []RemapConfig `yaml:"remap,omitempty" json:"remap,omitempty"`
+	// Turning all shortcuts, layers and remaps off without quitting, e.g. while gaming
+	Suspend SuspendConfig `yaml:"suspend,omitempty" json:"suspend,omitempty"`
+	// How the actions of fired shortcuts are run
+	Execution ExecutionConfig `yaml:"execution,omitempty" json:"execution,omitempty"`
 }
 
-// KeyBinding represents a single keyboard shortcut and its associated action
-type KeyBinding struct {
-	Keys   []string `yaml:"keys" json:"keys"`     // List of keys that make up the binding (e.g., ["LAlt", "LShift", "1"])
-	Action string   `yaml:"action" json:"action"` // Name of the action to perform (e.g., "SwitchDesktop", "MoveWindowToDesktop")
-	Params []string `yaml:"params" json:"params"` // Parameters for the action (e.g., ["1"] for desktop number)
+// Validate implements ConfigValidator for ShortcutsConfig.
+// Bindings are validated separately; this checks the settings, the layers and that every EnterMode targets
+// a defined mode. Remaps are resolved against the modifiers of the layers, see Modifiers.
+// Every problem is reported, joined with errors.Join.
+func (s *ShortcutsConfig) Validate() error {
+	var errs []error
+	if s.SequenceTimeout <= 0 {
+		errs = append(errs, &FieldError{Path: "sequence_timeout", Err: fmt.Errorf("sequence_timeout must be positive")})
+	}
+	modifiers := types.NewModifiers()
+	errs = append(errs, s.validateLayers(modifiers)...)
+	errs = append(errs, s.validateRemaps(modifiers)...)
+	if err := s.Suspend.Validate(); err != nil {
+		errs = append(errs, &FieldError{Path: "suspend", Err: fmt.Errorf("suspend: %w", err)})
+	}
+	if err := s.Execution.Validate(); err != nil {
+		errs = append(errs, &FieldError{Path: "execution", Err: fmt.Errorf("execution: %w", err)})
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Modes)) {
+		path := "modes." + name
+		if name == "" || strings.EqualFold(name, DefaultModeName) {
+			errs = append(errs, &FieldError{Path: path, Err: fmt.Errorf("invalid mode name %q", name)})
+		}
+		if s.Modes[name].Timeout < 0 {
+			errs = append(errs, &FieldError{Path: path + ".timeout", Err: fmt.Errorf("mode %q: timeout cannot be negative", name)})
+		}
+	}
+	ids := make(map[string]bool)
+	for _, binding := range s.allBindings() {
+		if binding.ID == "" {
+			continue
+		}
+		if ids[binding.ID] {
+			errs = append(errs, &FieldError{Path: binding.path + ".id", Err: fmt.Errorf("binding id %q is used more than once", binding.ID)})
+		}
+		ids[binding.ID] = true
+	}
+	enterMode, _ := action.Default.Lookup("EnterMode")
+	for _, binding := range s.allBindings() {
+		if binding.Action != enterMode.Name {
+			continue
+		}
+		args, err := enterMode.Parse(binding.Params)
+		if err != nil {
+			continue // Reported by the binding's own validation
+		}
+		mode := args.String("mode")
+		if _, ok := s.Modes[mode]; !ok {
+			errs = append(errs, &FieldError{Path: binding.path + ".params", Err: fmt.Errorf("binding %s enters unknown mode %q", binding.identity(), mode)})
+		}
+	}
+	return errors.Join(errs...)
 }
 
-// GetVirtualKeys converts a slice of key names to VirtualKeys
-func (k *KeyBinding) GetVirtualKeys() []types.VirtualKey {
-	provider := &DefaultKeyProvider{}
-	validKeys := provider.GetValidKeys()
+// placedBinding is a binding with the mode it belongs to and its field path below the shortcuts,
+// e.g. "bindings[2]" or "modes.resize.bindings[0]".
+type placedBinding struct {
+	KeyBinding
+	mode string
+	path string
+}
 
-	var keys []types.VirtualKey
-	for _, keyName := range k.Keys {
-		if vk, ok := validKeys[keyName]; ok {
-			keys = append(keys, vk)
+// allBindings returns the default bindings followed by the bindings of every mode, by mode name.
+func (s *ShortcutsConfig) allBindings() []placedBinding {
+	var bindings []placedBinding
+	for i, binding := range s.Bindings {
+		bindings = append(bindings, placedBinding{binding, DefaultModeName, fmt.Sprintf("bindings[%d]", i)})
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Modes)) {
+		for i, binding := range s.Modes[name].Bindings {
+			bindings = append(bindings, placedBinding{binding, name, fmt.Sprintf("modes.%s.bindings[%d]", name, i)})
 		}
 	}
-	return keys
+	return bindings
 }
 
-// Validate checks if a key binding is valid
-func (k *KeyBinding) Validate() error {
-	actionProvider := &DefaultActionProvider{}
-	keyProvider := &DefaultKeyProvider{}
+// StringList is a list of strings that may be written as a single string in config files.
+type StringList []string
 
-	// Check if action exists
-	actions := actionProvider.GetActions()
-	action, exists := actions[k.Action]
-	if !exists {
-		return fmt.Errorf("unknown action: %s", k.Action)
+// UnmarshalYAML implements yaml.Unmarshaler for StringList.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = StringList{value.Value}
+		return nil
 	}
-
-	// Check if all keys are valid
-	validKeys := keyProvider.GetValidKeys()
-	for _, key := range k.Keys {
-		if _, ok := validKeys[key]; !ok {
-			return fmt.Errorf("invalid key: %s", key)
-		}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
 	}
+	*l = list
+	return nil
+}
 
-	// Validate parameters using the action's validator
-	if action.Validator != nil {
-		if err := action.Validator(k.Params); err != nil {
-			return fmt.Errorf("invalid parameters for %s: %w", k.Action, err)
-		}
+// UnmarshalJSON implements json.Unmarshaler for StringList.
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = StringList{single}
+		return nil
 	}
-
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
 	return nil
 }
 
 // Action represents a function that can be bound to keys.
 type Action struct {
-	Name        string               // Name of the action
-	Description string               // Description of what the action does
-	Category    string               // Category for grouping
-	ParamTypes  []string             // Types of parameters expected
-	Validator   func([]string) error // Optional validator for parameters
+	Name        string             // Name of the action
+	Description string             // Description of what the action does
+	Category    string             // Category for grouping
+	ParamTypes  []string           // Types of parameters expected
+	Validator   func(Params) error // Optional validator for parameters
 }
@@ -1,20 +1,45 @@
 package config
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
-// validateConfig checks if the loaded configuration is valid
+// Validate implements ConfigValidator for Config.
+func (c *Config) Validate() error {
+	return validateConfig(c)
+}
+
+// validateConfig checks if the loaded configuration is valid. Every problem found is reported
+// together as a *ValidationError, with the path of the field at fault.
 func validateConfig(cfg *Config) error {
-	// Validate virtual desktops configuration
-	if err := cfg.VirtualDesktops.Validate(); err != nil {
-		return fmt.Errorf("invalid virtual desktops config: %w", err)
+	var diagnostics []Diagnostic
+	check := func(path string, err error) {
+		diagnostics = append(diagnostics, diagnose(path, err)...)
 	}
 
-	// Validate shortcuts
-	for _, binding := range cfg.Shortcuts.Bindings {
-		if err := binding.Validate(); err != nil {
-			return fmt.Errorf("invalid binding: %w", err)
+	check("logging", cfg.Logging.Validate())
+	check("ui.tray_icon", cfg.UI.TrayIcon.Validate())
+	check("virtual_desktops", cfg.VirtualDesktops.Validate())
+
+	check("shortcuts", cfg.Shortcuts.Validate())
+	// Bindings may use the modifiers of this config's layers, whatever config is running
+	modifiers := cfg.Shortcuts.Modifiers()
+	for _, binding := range cfg.Shortcuts.allBindings() {
+		err := binding.validate(modifiers)
+		path := "shortcuts." + binding.path
+		var field *FieldError
+		if errors.As(err, &field) {
+			path = fieldPath(path, field.Path)
+		}
+		switch {
+		case err == nil:
+		case binding.mode == DefaultModeName:
+			check(path, fmt.Errorf("invalid binding %s: %w", binding.identity(), err))
+		default:
+			check(path, fmt.Errorf("invalid binding in mode %q %s: %w", binding.mode, binding.identity(), err))
 		}
 	}
 
-	return nil
+	return invalid(diagnostics)
 }
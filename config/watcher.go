@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"wincuts/clock"
+)
+
+// DefaultWatchInterval is how often a Watcher polls its source for changes.
+const DefaultWatchInterval = time.Second
+
+// Source is a ConfigLoader whose backing data can change while the application runs.
+// Watchers compare revisions to decide when a reload is needed, so loading only happens on real changes.
+type Source interface {
+	ConfigLoader
+	// Revision returns an opaque token that changes whenever the underlying data changes.
+	Revision() (string, error)
+}
+
+// Watcher keeps the last good configuration of a Source and notifies subscribers when it changes.
+// An edit that fails to parse or validate is logged and ignored, so a typo never tears down working bindings.
+type Watcher struct {
+	source   Source
+	clock    clock.Clock
+	interval time.Duration
+
+	mu       sync.Mutex
+	current  *Config
+	revision string
+	handlers []func(*Config)
+}
+
+// NewWatcher creates a Watcher polling source at the given interval.
+func NewWatcher(source Source, clk clock.Clock, interval time.Duration) *Watcher {
+	return &Watcher{
+		source:   source,
+		clock:    clk,
+		interval: interval,
+	}
+}
+
+// Load performs the initial load and records it as the last good configuration.
+func (w *Watcher) Load() (*Config, error) {
+	revision, err := w.source.Revision()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config revision: %w", err)
+	}
+	cfg, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = cfg
+	w.revision = revision
+	return cfg, nil
+}
+
+// Current returns the last good configuration, or nil before the first successful load.
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// OnChange registers a handler that is called with every newly applied configuration.
+func (w *Watcher) OnChange(handler func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Check polls the source once and applies the new configuration if its revision changed.
+// It reports whether handlers were notified. A failed reload returns the error and keeps the last good config.
+func (w *Watcher) Check() (bool, error) {
+	revision, err := w.source.Revision()
+	if err != nil {
+		return false, fmt.Errorf("failed to read config revision: %w", err)
+	}
+
+	w.mu.Lock()
+	unchanged := revision == w.revision
+	// Remember the revision even if loading fails so a broken file is reported once, not on every poll.
+	w.revision = revision
+	w.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cfg, err := w.load()
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	w.current = cfg
+	handlers := append([]func(*Config){}, w.handlers...)
+	w.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(cfg)
+	}
+	return true, nil
+}
+
+// Run polls the source until the context is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.clock.After(w.interval):
+			changed, err := w.Check()
+			if err != nil {
+				slog.Error("config reload failed, keeping last good configuration", "error", err)
+				continue
+			}
+			if changed {
+				slog.Info("configuration reloaded")
+			}
+		}
+	}
+}
+
+// load loads and validates the configuration from the source. The file loaders validate as they load, locating
+// each problem in its file, so only the configurations of other sources are validated here. Conflicts between
+// its bindings are left to the caller to log, once logging is set up for the configuration.
+func (w *Watcher) load() (*Config, error) {
+	cfg, err := w.source.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	switch w.source.(type) {
+	case *LayeredConfigLoader, *FileConfigLoader:
+	default:
+		if err := validateConfig(cfg); err != nil {
+			return nil, fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+	return cfg, nil
+}
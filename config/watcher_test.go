@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/clock"
+)
+
+// memorySource is an in-memory Source whose configuration can be swapped by tests.
+type memorySource struct {
+	mu       sync.Mutex
+	cfg      *Config
+	err      error
+	revision int
+}
+
+func (m *memorySource) set(cfg *Config, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	m.err = err
+	m.revision++
+}
+
+func (m *memorySource) Load() (*Config, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg, m.err
+}
+
+func (m *memorySource) Revision() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return fmt.Sprint(m.revision), nil
+}
+
+// configWithMinimum returns a valid configuration that can be told apart by its minimum desktop count.
+func configWithMinimum(count int) *Config {
+	cfg := DefaultConfig()
+	cfg.VirtualDesktops.MinimumCount = count
+	cfg.Shortcuts.Bindings = []KeyBinding{{Keys: []string{"LAlt", "1"}, Action: "CreateDesktop"}}
+	return cfg
+}
+
+// TestWatcherAppliesChanges verifies that a changed source is reloaded and handlers receive the new config.
+func TestWatcherAppliesChanges(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	source := &memorySource{}
+	source.set(configWithMinimum(3), nil)
+	watcher := NewWatcher(source, clock.NewFake(time.Time{}), time.Second)
+
+	cfg, err := watcher.Load()
+	require.NoError(err)
+	assert.Equal(3, cfg.VirtualDesktops.MinimumCount)
+
+	var applied []*Config
+	watcher.OnChange(func(cfg *Config) { applied = append(applied, cfg) })
+
+	changed, err := watcher.Check()
+	require.NoError(err)
+	assert.False(changed, "Unchanged source should not notify handlers")
+
+	source.set(configWithMinimum(5), nil)
+	changed, err = watcher.Check()
+	require.NoError(err)
+	assert.True(changed, "Changed source should notify handlers")
+	require.Len(applied, 1)
+	assert.Equal(5, applied[0].VirtualDesktops.MinimumCount)
+	assert.Equal(5, watcher.Current().VirtualDesktops.MinimumCount)
+}
+
+// TestWatcherKeepsLastGoodConfig verifies that load and validation failures keep the previous configuration.
+func TestWatcherKeepsLastGoodConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		err  error
+	}{
+		{
+			name: "load error",
+			err:  errors.New("yaml: line 3: did not find expected key"),
+		},
+		{
+			name: "validation error",
+			cfg:  configWithMinimum(-1),
+		},
+		{
+			name: "invalid binding",
+			cfg: &Config{Shortcuts: ShortcutsConfig{SequenceTimeout: time.Second, Bindings: []KeyBinding{
+				{Keys: []string{"LAlt", "NotAKey"}, Action: "SwitchDesktop", Params: PositionalParams("1")},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &memorySource{}
+			source.set(configWithMinimum(4), nil)
+			watcher := NewWatcher(source, clock.NewFake(time.Time{}), time.Second)
+			_, err := watcher.Load()
+			require.NoError(t, err)
+
+			var notified bool
+			watcher.OnChange(func(*Config) { notified = true })
+
+			source.set(tt.cfg, tt.err)
+			changed, err := watcher.Check()
+			assert.Error(t, err, "Broken config should be reported")
+			assert.False(t, changed)
+			assert.False(t, notified, "Handlers should not see a broken config")
+			assert.Equal(t, 4, watcher.Current().VirtualDesktops.MinimumCount, "Last good config should be kept")
+
+			// The broken revision is only reported once.
+			_, err = watcher.Check()
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestWatcherRunPollsOnClock verifies that Run polls on every tick of the clock and stops with its context.
+func TestWatcherRunPollsOnClock(t *testing.T) {
+	require := require.New(t)
+
+	source := &memorySource{}
+	source.set(configWithMinimum(2), nil)
+	fake := clock.NewFake(time.Time{})
+	watcher := NewWatcher(source, fake, time.Second)
+	_, err := watcher.Load()
+	require.NoError(err)
+
+	applied := make(chan *Config, 1)
+	watcher.OnChange(func(cfg *Config) { applied <- cfg })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx)
+		close(done)
+	}()
+
+	source.set(configWithMinimum(7), nil)
+	require.Eventually(func() bool { return fake.Pending() == 1 }, time.Second, time.Millisecond)
+	fake.Advance(time.Second)
+
+	select {
+	case cfg := <-applied:
+		require.Equal(7, cfg.VirtualDesktops.MinimumCount)
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for reloaded config")
+	}
+
+	cancel()
+	<-done
+}
@@ -7,161 +7,226 @@
 package keyboard
 
 import (
-	"context"
-	"fmt"
 	"log/slog"
-	"maps"
 	"slices"
 	"sync"
 
+	"wincuts/clock"
 	"wincuts/keyboard/shortcut"
 	wtypes "wincuts/keyboard/types"
-
-	"github.com/moutend/go-hook/pkg/keyboard"
-	"github.com/moutend/go-hook/pkg/types"
 )
 
 // Hook manages keyboard event capturing and distribution to subscribers.
 // It maintains thread-safe state tracking of currently pressed keys.
 type Hook struct {
-	lowLevelChan      chan types.KeyboardEvent
-	keyState          map[wtypes.VirtualKey]bool
-	ctx               context.Context
-	cancel            context.CancelFunc
-	wg                sync.WaitGroup
-	stateMutex        sync.RWMutex                   // Protects keyState map
-	shortcutChan      chan *shortcut.KeyBindingAction // Channel for matched shortcuts
-	shortcutService   *shortcut.Service
+	source          InputSource
+	query           KeyStateQuery               // Confirms held keys with the system; nil if the source cannot
+	keyState        *keyState                   // Held keys, telling first presses from auto-repeats
+	stateMutex      sync.Mutex                  // Protects the suppressor, the layers, the remaps and the suspension
+	suppressor      *suppressor                 // Decides which events to swallow
+	layers          *layers                     // Keys acting as custom modifiers
+	modifiers       *wtypes.Modifiers           // Names the custom modifiers of the layers in logs
+	remaps          *remapper                   // Keys sent in place of others
+	suspension      suspension                  // Whether shortcuts are suspended and what toggles them
+	injector        KeyInjector                 // Sends remapped keys and the tap key of layers; may be nil
+	shortcutChan    chan shortcut.ActionContext // Channel for matched shortcuts
+	shortcutService *shortcut.Service
 }
 
-// KeyEvent represents a processed keyboard event with additional context.
+// NewHook creates a new keyboard hook that reads events from source.
+// The injector is used to keep Windows from activating menus after a blocked shortcut, to send
+// remapped keys and to send the tap key of layers; it may be nil.
+func NewHook(shortcutService *shortcut.Service, source InputSource, injector KeyInjector) (*Hook, error) {
+	return NewHookWithClock(shortcutService, source, injector, clock.New())
+}
 
+// NewHookWithClock creates a keyboard hook that times layer taps and held keys on clk.
+func NewHookWithClock(shortcutService *shortcut.Service, source InputSource, injector KeyInjector, clk clock.Clock) (*Hook, error) {
+	query, _ := source.(KeyStateQuery)
+	return &Hook{
+		source:          source,
+		query:           query,
+		keyState:        newKeyState(clk),
+		suppressor:      newSuppressor(injector),
+		layers:          newLayers(clk),
+		remaps:          newRemapper(),
+		injector:        injector,
+		shortcutChan:    shortcutService.GetShortcutChan(), // Buffered channel
+		shortcutService: shortcutService,
+	}, nil
+}
 
-const (
-	// DefaultEventBuffer is the size of the channel buffer for keyboard events
-	DefaultEventBuffer = 100
-)
+// getCurrentKeyState safely returns a snapshot of all currently pressed keys.
+func (h *Hook) getCurrentKeyState() []wtypes.VirtualKey {
+	return h.keyState.pressed()
+}
 
-// NewHook creates a new keyboard hook with default configuration.
-func NewHook(shortcutService *shortcut.Service) (*Hook, error) {
-	lowLevelChan := make(chan types.KeyboardEvent, DefaultEventBuffer)
-	ctx, cancel := context.WithCancel(context.Background())
+// GetShortcutChan returns the channel for matched shortcuts
+func (h *Hook) GetShortcutChan() <-chan shortcut.ActionContext {
+	return h.shortcutChan
+}
 
+// SetLayers replaces the keys that act as custom modifiers, defined in the table modifiers, which names them in logs.
+// Layer keys held at the time keep working until released.
+func (h *Hook) SetLayers(modifiers *wtypes.Modifiers, layers ...Layer) {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	h.layers.set(layers)
+	h.modifiers = modifiers
+}
 
+// SetRemaps replaces the keys sent in place of others. Remapped keys held at the time keep their old remap until released.
+func (h *Hook) SetRemaps(remaps ...Remap) {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	h.remaps.set(remaps)
+}
 
-	return &Hook{
-		lowLevelChan:      lowLevelChan,
-		keyState:          make(map[wtypes.VirtualKey]bool),
-		ctx:               ctx,
-		cancel:            cancel,
-		shortcutChan:      shortcutService.GetShortcutChan(), // Buffered channel
-		shortcutService:   shortcutService,
-	}, nil
+// Start begins capturing keyboard events from the input source.
+func (h *Hook) Start() error {
+	return h.source.Install(h.handle)
 }
 
+// handle processes a single event synchronously: it tracks key state, dispatches matched shortcuts
+// and decides whether the event reaches the focused application.
+func (h *Hook) handle(raw RawEvent) Decision {
+	// Our own synthetic input, such as the menu mask key, is neither tracked nor matched.
+	if raw.ExtraInfo == InjectedTag {
+		return Pass
+	}
+
+	h.recoverKeys()
+	if decision, ok := h.gate(raw); ok {
+		return decision
+	}
 
-// updateKeyState safely updates the state of a key and returns a snapshot of all pressed keys.
-func (h *Hook) updateKeyState(key wtypes.VirtualKey, isDown bool) []wtypes.VirtualKey {
+	vCode := raw.VKCode
+	isKeyDown := raw.KeyDown
+
+	// A layer key is matched as its custom modifier and never reaches the application,
+	// while a remapped key is matched as its target, which is sent in its place
 	h.stateMutex.Lock()
-	defer h.stateMutex.Unlock()
+	modifier, isLayer, tap := h.layers.handle(vCode, isKeyDown)
+	var target wtypes.VirtualKey
+	isRemapped := false
+	if !isLayer {
+		target, isRemapped = h.remaps.key(vCode, isKeyDown)
+	}
+	h.stateMutex.Unlock()
+	switch {
+	case isLayer:
+		vCode = modifier
+	case isRemapped:
+		vCode = target
+	}
 
-	if isDown {
-		h.keyState[key] = true
-	} else {
-		delete(h.keyState, key)
+	// Update the state, keeping the keys held before this event for matching
+	currentState, repeat := h.keyState.update(vCode, isKeyDown)
+	event := shortcut.KeyEvent{
+		PressedKeys: currentState,
+		KeyCode:     vCode,
+		KeyDown:     isKeyDown,
+		Repeat:      repeat,
+	}
+	switch {
+	case repeat:
+		// Auto-repeats arrive many times a second and are not logged
+	case isKeyDown:
+		slog.Debug("key press", "key", vCode.KeybindName(), "state", currentState)
+	default:
+		slog.Debug("key release", "key", vCode.KeybindName(), "state", currentState)
 	}
 
-	// Create a snapshot of currently pressed keys
-	return slices.Collect(maps.Keys(h.keyState))
-}
+	// A remapped combination replaces the keys entirely, taking precedence over shortcuts
+	h.stateMutex.Lock()
+	combo, isCombo := h.remaps.combo(event)
+	modifiers := h.modifiers
+	h.stateMutex.Unlock()
+	if isCombo {
+		if isKeyDown {
+			slog.Debug("remapped keys", "from", modifiers.FormatBinding(combo.From), "to", modifiers.FormatBinding(combo.To))
+			held := slices.DeleteFunc(slices.Clone(currentState), func(vk wtypes.VirtualKey) bool { return vk == vCode })
+			h.sendCombo(combo.To, held)
+		}
+		return Block
+	}
 
-// getCurrentKeyState safely returns a snapshot of all currently pressed keys.
-func (h *Hook) getCurrentKeyState() []wtypes.VirtualKey {
-	h.stateMutex.RLock()
-	defer h.stateMutex.RUnlock()
-	return slices.Collect(maps.Keys(h.keyState))
+	h.stateMutex.Lock()
+	decision := h.suppressor.decide(event, h.shortcutService.Press)
+	h.stateMutex.Unlock()
+	// Only presses that reach the system can later be confirmed with it; a remapped key's target is sent in its place
+	if isKeyDown {
+		h.keyState.setVisible(vCode, decision == Pass && !isLayer)
+	}
+	switch {
+	case isLayer:
+		decision = Block
+	case isRemapped && decision == Pass:
+		h.send(RawEvent{VKCode: injectable(vCode), KeyDown: isKeyDown})
+		decision = Block
+	}
+	if tap != 0 {
+		slog.Debug("layer tapped", "key", tap.KeybindName())
+		h.send(RawEvent{VKCode: tap, KeyDown: true}, RawEvent{VKCode: tap, KeyDown: false})
+	}
+	if decision == Block {
+		slog.Debug("blocked key", "key", vCode.KeybindName(), "down", isKeyDown)
+	}
+
+	// Check if this is a registered shortcut
+	keyBinding, found := h.shortcutService.Match(event)
+	if !found {
+		return decision
+	}
+
+	h.shortcutService.Dispatch(keyBinding)
+	return decision
 }
 
-// GetShortcutChan returns the channel for matched shortcuts
-func (h *Hook) GetShortcutChan() <-chan *shortcut.KeyBindingAction {
-	return h.shortcutChan
+// recoverKeys forgets held keys whose release was lost, so they no longer keep shortcuts from matching.
+func (h *Hook) recoverKeys() {
+	corrections := h.keyState.reconcile(h.query)
+	if len(corrections) == 0 {
+		return
+	}
+	h.stateMutex.Lock()
+	for _, c := range corrections {
+		h.suppressor.forget(c.key)
+	}
+	h.stateMutex.Unlock()
+	for _, c := range corrections {
+		slog.Info("released stuck key", "key", c.key.KeybindName(), "reason", c.reason)
+	}
 }
 
-// Start begins capturing keyboard events and distributing them to subscribers.
-func (h *Hook) Start() error {
-	if err := keyboard.Install(nil, h.lowLevelChan); err != nil {
-		return fmt.Errorf("failed to install keyboard hook: %w", err)
-	}
-
-	h.wg.Add(1)
-	go func() {
-		defer h.wg.Done()
-		for {
-			select {
-			case <-h.ctx.Done():
-				return
-			case k := <-h.lowLevelChan:
-				if k.Message == 0x0312 {
-					fmt.Println("Key combo")
-					continue
-				}
-
-				vCode := wtypes.VirtualKey(k.VKCode)
-				isKeyDown := k.Message == types.WM_KEYDOWN || k.Message == types.WM_SYSKEYDOWN
-
-				// Get current state before updating
-				currentState := h.getCurrentKeyState()
-
-				// Create event with state before the update
-				event := shortcut.KeyEvent{
-					PressedKeys: currentState,
-					KeyCode:     vCode,
-					KeyDown:     isKeyDown,
-				}
-				if isKeyDown {
-					slog.Debug("key press", "key", vCode.KeybindName(), "state", currentState)
-				} else {
-					slog.Debug("key release", "key", vCode.KeybindName(), "state", currentState)
-				}
-
-				// Update state after creating the event
-				h.updateKeyState(vCode, isKeyDown)
-
-				// Check if this is a registered shortcut
-				keyBinding, found := h.shortcutService.Match(event)
-				if !found {
-					continue
-				}
-
-				// Send the matched shortcut through the channel
-				select {
-				case h.shortcutChan <- keyBinding:
-					slog.Debug("sent shortcut", "binding", keyBinding.Binding.PrettyString())
-				default:
-					// Drop the event if the channel is full
-				}
-
-			}
-		}
-	}()
+// ResetKeys forgets every held key, e.g. when the session is locked and key releases go to the secure desktop.
+func (h *Hook) ResetKeys(reason string) {
+	for _, key := range h.keyState.reset() {
+		slog.Info("released stuck key", "key", key.KeybindName(), "reason", reason)
+	}
+	h.stateMutex.Lock()
+	h.resetTranslation()
+	h.stateMutex.Unlock()
+}
 
-	return nil
+// resetTranslation forgets the swallowed keys and the held layers and remaps. The caller holds stateMutex.
+func (h *Hook) resetTranslation() {
+	h.suppressor.reset()
+	h.layers.reset()
+	h.remaps.reset()
 }
 
 // Stop gracefully shuts down the hook and cleans up resources.
 func (h *Hook) Stop() error {
-	h.cancel()  // Signal the goroutine to stop
-	h.wg.Wait() // Wait for goroutine to finish
-
-	if err := keyboard.Uninstall(); err != nil {
-		return fmt.Errorf("keyboard: failed to uninstall hook function: %w", err)
+	if err := h.source.Uninstall(); err != nil {
+		return err
 	}
 
-	close(h.lowLevelChan)
 	// Clear the key state
+	h.keyState.reset()
 	h.stateMutex.Lock()
-	h.keyState = make(map[wtypes.VirtualKey]bool)
+	h.resetTranslation()
 	h.stateMutex.Unlock()
 
 	return nil
-}
\ No newline at end of file
+}
@@ -1,21 +1,26 @@
 package keyboard
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
-	"github.com/moutend/go-hook/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"wincuts/clock"
 	"wincuts/keyboard/shortcut"
 	wtypes "wincuts/keyboard/types"
 )
 
 type HookTestSuite struct {
 	suite.Suite
-	hook *Hook
+	hook     *Hook
+	source   *ScriptedSource
+	service  *shortcut.Service
+	executed chan wtypes.KeyBinding
 }
 
 func TestHookSuite(t *testing.T) {
@@ -24,9 +29,22 @@ func TestHookSuite(t *testing.T) {
 
 func (s *HookTestSuite) SetupTest() {
 	var err error
-	svc := shortcut.NewService(make(chan *shortcut.KeyBindingAction, 1), shortcut.NewMatcher())
-	s.hook, err = NewHook(svc)
+	s.executed = make(chan wtypes.KeyBinding, 10)
+	s.source = NewScriptedSource()
+	s.service = shortcut.NewService(make(chan shortcut.ActionContext, 10), shortcut.NewMatcher())
+	for _, keys := range [][]wtypes.VirtualKey{
+		{wtypes.VK_LMENU, wtypes.VK_1},
+		{wtypes.VK_MENU, wtypes.VK_LSHIFT, wtypes.VK_1},
+	} {
+		binding := wtypes.NewKeybinding(keys...)
+		s.service.RegisterKeyBindingActions(shortcut.NewBindingAction(keys, func(context.Context, shortcut.ActionContext) error {
+			s.executed <- binding
+			return nil
+		}, true))
+	}
+	s.hook, err = NewHook(s.service, s.source, s.source)
 	require.NoError(s.T(), err, "NewHook should not return an error")
+	require.NoError(s.T(), s.hook.Start(), "Start should not return an error")
 }
 
 func (s *HookTestSuite) TearDownTest() {
@@ -35,169 +53,453 @@ func (s *HookTestSuite) TearDownTest() {
 	}
 }
 
+// useFakeClock replaces the hook and the service with ones timed by a fake clock and without bindings.
+func (s *HookTestSuite) useFakeClock() *clock.Fake {
+	fake := clock.NewFake(time.Time{})
+	s.service = shortcut.NewService(make(chan shortcut.ActionContext, 10), shortcut.NewMatcherWithClock(fake))
+	s.hook.Stop()
+	hook, err := NewHookWithClock(s.service, s.source, s.source, fake)
+	s.Require().NoError(err)
+	s.hook = hook
+	s.Require().NoError(s.hook.Start())
+	return fake
+}
+
 // TestNewHook verifies that a new Hook can be created with the expected initial state
 func (s *HookTestSuite) TestNewHook() {
 	assert := assert.New(s.T())
 	require := require.New(s.T())
 
 	require.NotNil(s.hook, "NewHook should return a non-nil Hook")
-	assert.NotNil(s.hook.lowLevelChan, "lowLevelChan should be initialized")
 	assert.NotNil(s.hook.keyState, "keyState should be initialized")
-	assert.NotNil(s.hook.ctx, "context should be initialized")
-	assert.NotNil(s.hook.cancel, "cancel function should be initialized")
+	assert.NotNil(s.hook.suppressor, "suppressor should be initialized")
+	assert.True(s.source.Installed(), "Start should install the handler")
 }
 
-// TestSubscriptionManagement verifies that subscribers can be added and removed correctly
-// TestKeyEventProcessing verifies that keyboard events are correctly processed and broadcast
-func (s *HookTestSuite) TestKeyEventProcessing() {
+// TestHookLifecycle verifies that stopping the hook uninstalls it and clears the key state
+func (s *HookTestSuite) TestHookLifecycle() {
 	require := require.New(s.T())
+
+	s.source.Press(wtypes.VK_A)
+	require.Len(s.hook.getCurrentKeyState(), 1)
+
+	require.NoError(s.hook.Stop())
+	require.False(s.source.Installed(), "Stop should uninstall the handler")
+	require.Empty(s.hook.getCurrentKeyState(), "Stop should clear the key state")
+
+	// Events after stopping no longer reach the hook
+	s.source.Press(wtypes.VK_B)
+	require.Empty(s.hook.getCurrentKeyState(), "Stopped hook should not track keys")
+
+	require.NoError(s.hook.Start(), "Hook should restart")
+	s.hook.Stop()
+	s.hook = nil
+}
+
+// TestKeyStateTracking verifies that the hook correctly tracks the state of pressed keys
+func (s *HookTestSuite) TestKeyStateTracking() {
 	assert := assert.New(s.T())
 
-	// Start the hook
-	err := s.hook.Start()
-	require.NoError(err, "Start should not return an error")
+	s.source.Press(wtypes.VK_LCONTROL)
+	s.source.Press(wtypes.VK_A)
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LCONTROL, wtypes.VK_A}, s.hook.getCurrentKeyState())
 
-	// Subscribe to receive events
+	s.source.Release(wtypes.VK_A)
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LCONTROL}, s.hook.getCurrentKeyState())
 
-	// Create a helper function to create keyboard events
-	createKeyEvent := func(message types.Message, key wtypes.VirtualKey) types.KeyboardEvent {
-		return types.KeyboardEvent{
-			Message: message,
-			KBDLLHOOKSTRUCT: types.KBDLLHOOKSTRUCT{
-				VKCode: types.VKCode(key),
-			},
-		}
-	}
+	s.source.Release(wtypes.VK_LCONTROL)
+	assert.Empty(s.hook.getCurrentKeyState())
+}
 
-	// Simulate a key press event
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYDOWN, wtypes.VK_A)
+// TestShortcutBlocking verifies that a matched shortcut is dispatched and its key never reaches the application
+func (s *HookTestSuite) TestShortcutBlocking() {
+	assert := assert.New(s.T())
+
+	assert.Equal(Pass, s.source.Press(wtypes.VK_LMENU), "Modifier press should pass")
+	assert.Equal(Block, s.source.Press(wtypes.VK_1), "Press completing the shortcut should be blocked")
+	assert.Equal(Block, s.source.Press(wtypes.VK_1), "Auto-repeat should be blocked")
+	assert.Equal(Block, s.source.Release(wtypes.VK_1), "Release of the blocked key should be blocked")
 
-	// Wait for and verify the event
 	select {
-	case event := <-s.hook.GetShortcutChan():
-		assert.True(event.Binding.Contains(wtypes.VK_A), "KeyDown should be true for WM_KEYDOWN")
-	case <-time.After(time.Second):
-		s.T().Fatal("Timeout waiting for key event")
+	case fired := <-s.hook.GetShortcutChan():
+		assert.True(fired.Binding.Binding.Match([]wtypes.VirtualKey{wtypes.VK_LMENU, wtypes.VK_1}))
+	default:
+		s.T().Fatal("Expected the shortcut to be dispatched")
 	}
 
-	// Simulate key release
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYUP, wtypes.VK_A)
+	assert.Equal(Pass, s.source.Release(wtypes.VK_LMENU), "Modifier release should pass")
+	assert.Equal([]RawEvent{
+		{VKCode: wtypes.VK_LMENU, KeyDown: true},
+		{VKCode: MenuMaskKey, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: MenuMaskKey, KeyDown: false, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LMENU, KeyDown: false},
+	}, s.source.Delivered(), "The application should only see Alt, masked so its release does not open the menu bar")
+}
 
-	// Verify key release event
-	select {
-	case event := <-s.hook.GetShortcutChan():
-		assert.False(event.Binding.Contains(wtypes.VK_A), "KeyDown should be false for WM_KEYUP")
-	case <-time.After(time.Second):
-		s.T().Fatal("Timeout waiting for key event")
+// TestUnboundKeysPass verifies that keys without a blocking shortcut reach the application
+func (s *HookTestSuite) TestUnboundKeysPass() {
+	assert := assert.New(s.T())
+
+	s.source.Chord(wtypes.VK_LMENU, wtypes.VK_2)
+	s.source.Chord(wtypes.VK_1)
+	s.source.Chord(wtypes.VK_RMENU, wtypes.VK_1) // Alt+1 is bound to the left Alt only
+	for _, event := range s.source.Events() {
+		assert.Equal(Pass, event.Decision, "%s should pass", event.VKCode.KeybindName())
 	}
+	assert.Len(s.source.Events(), 10, "No events should be injected")
 }
 
-// TestHookLifecycle verifies that the hook's internal state is managed correctly during startup and shutdown
-func (s *HookTestSuite) TestHookLifecycle() {
+// TestInjectedEventsIgnored verifies that our own synthetic input is neither tracked nor blocked
+func (s *HookTestSuite) TestInjectedEventsIgnored() {
+	assert := assert.New(s.T())
+
+	s.source.Press(wtypes.VK_LMENU)
+	assert.NoError(s.source.SendKey(wtypes.VK_1, true))
+	assert.Equal(Pass, s.source.Events()[1].Decision)
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LMENU}, s.hook.getCurrentKeyState())
+}
+
+// TestEndToEndDispatch verifies that the service executes the most specific binding for the keys typed
+func (s *HookTestSuite) TestEndToEndDispatch() {
 	require := require.New(s.T())
+	s.service.Start()
+	defer s.service.Stop()
+
+	s.source.Chord(wtypes.VK_RMENU, wtypes.VK_LSHIFT, wtypes.VK_1)
+	s.source.Chord(wtypes.VK_LMENU, wtypes.VK_1)
 
-	// Start the hook
-	err := s.hook.Start()
-	require.NoError(err, "Start should not return an error")
-
-	// Create a helper function to create keyboard events
-	createKeyEvent := func(message types.Message, key wtypes.VirtualKey) types.KeyboardEvent {
-		return types.KeyboardEvent{
-			Message: message,
-			KBDLLHOOKSTRUCT: types.KBDLLHOOKSTRUCT{
-				VKCode: types.VKCode(key),
-			},
+	// Actions run concurrently, so they may complete in any order
+	var executed []wtypes.KeyBinding
+	for range 2 {
+		select {
+		case got := <-s.executed:
+			executed = append(executed, got)
+		case <-time.After(time.Second):
+			s.T().Fatal("Timeout waiting for the actions to execute")
 		}
 	}
+	require.ElementsMatch([]wtypes.KeyBinding{
+		{wtypes.VK_MENU, wtypes.VK_LSHIFT, wtypes.VK_1},
+		{wtypes.VK_LMENU, wtypes.VK_1},
+	}, executed)
+	for _, event := range s.source.Delivered() {
+		require.NotEqual(wtypes.VK_1, event.VKCode, "The shortcut key should never reach the application")
+	}
+}
 
-	// Verify that events are processed while the hook is running
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYDOWN, wtypes.VK_A)
+// TestSequenceDispatch verifies that every step of a key sequence is kept from the application
+// and that the sequence is dispatched once its last step is pressed
+func (s *HookTestSuite) TestSequenceDispatch() {
+	assert := assert.New(s.T())
+	steps := []wtypes.KeyBinding{{wtypes.VK_MENU, wtypes.VK_W}, {wtypes.VK_3}}
+	s.service.RegisterKeyBindingActions(shortcut.NewSequenceBindingAction(steps, func(context.Context, shortcut.ActionContext) error { return nil }, true))
 
+	s.source.Press(wtypes.VK_LMENU)
+	assert.Equal(Block, s.source.Press(wtypes.VK_W), "First step should be blocked")
+	assert.Equal(Block, s.source.Release(wtypes.VK_W))
+	assert.Equal(Pass, s.source.Release(wtypes.VK_LMENU))
+	assert.Empty(s.hook.GetShortcutChan(), "Nothing should be dispatched before the sequence completes")
+
+	assert.Equal(Block, s.source.Press(wtypes.VK_3), "Last step should be blocked")
+	assert.Equal(Block, s.source.Release(wtypes.VK_3))
 	select {
-	case event := <-s.hook.GetShortcutChan():
-		require.True(event.Binding.Contains(wtypes.VK_A), "Should receive events while hook is running")
-	case <-time.After(time.Second):
-		s.T().Fatal("Timeout waiting for event")
+	case fired := <-s.hook.GetShortcutChan():
+		assert.Equal(steps, fired.Binding.Steps())
+	default:
+		s.T().Fatal("Expected the sequence to be dispatched")
 	}
+	assert.Empty(s.hook.GetShortcutChan(), "The release of the last step should not dispatch again")
 
-	// Stop the hook and verify that the context is canceled
-	s.hook.cancel()  // Cancel the context directly since we can't uninstall the hook in tests
-	s.hook.wg.Wait() // Wait for goroutine to finish
+	for _, event := range s.source.Delivered() {
+		assert.NotContains([]wtypes.VirtualKey{wtypes.VK_W, wtypes.VK_3}, event.VKCode, "Sequence keys should never reach the application")
+	}
+}
 
-	// Verify that sending more events doesn't cause issues
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYDOWN, wtypes.VK_B)
+// TestSequenceCancelledByEscape verifies that Escape abandons a pending sequence without reaching the application
+func (s *HookTestSuite) TestSequenceCancelledByEscape() {
+	assert := assert.New(s.T())
+	steps := []wtypes.KeyBinding{{wtypes.VK_MENU, wtypes.VK_W}, {wtypes.VK_3}}
+	s.service.RegisterKeyBindingActions(shortcut.NewSequenceBindingAction(steps, func(context.Context, shortcut.ActionContext) error { return nil }, true))
+
+	s.source.Chord(wtypes.VK_LMENU, wtypes.VK_W)
+	assert.Equal(Block, s.source.Press(wtypes.VK_ESCAPE), "Escape should cancel the sequence")
+	assert.Equal(Block, s.source.Release(wtypes.VK_ESCAPE))
+	assert.Equal(Pass, s.source.Press(wtypes.VK_3), "Keys after cancelling should pass")
+	assert.Equal(Pass, s.source.Press(wtypes.VK_ESCAPE), "Escape without a pending sequence should pass")
+	assert.Empty(s.hook.GetShortcutChan())
+}
 
-	// The channel should eventually be closed
+// TestHoldTrigger verifies that auto-repeats while holding the keys neither restart nor cancel a hold
+func (s *HookTestSuite) TestHoldTrigger() {
+	assert := assert.New(s.T())
+	fake := s.useFakeClock()
+	binding := shortcut.NewBindingAction([]wtypes.VirtualKey{wtypes.VK_MENU, wtypes.VK_1}, func(context.Context, shortcut.ActionContext) error { return nil }, true)
+	binding.Trigger = shortcut.Trigger{Kind: shortcut.TriggerHold, Duration: 500 * time.Millisecond}
+	s.service.RegisterKeyBindingActions(binding)
+
+	s.source.Press(wtypes.VK_LMENU)
+	assert.Equal(Block, s.source.Press(wtypes.VK_1))
+	fake.Advance(300 * time.Millisecond)
+	assert.Equal(Block, s.source.Press(wtypes.VK_1), "Auto-repeat should be blocked")
+	fake.Advance(200 * time.Millisecond)
 	select {
-	case _, ok := <-s.hook.GetShortcutChan():
-		require.False(ok, "Channel should be closed after stopping")
-	case <-time.After(time.Second):
-		// Channel might not be closed immediately, which is also acceptable
+	case fired := <-s.hook.GetShortcutChan():
+		assert.Equal(shortcut.TriggerHold, fired.Binding.Trigger.Kind)
+	default:
+		s.T().Fatal("Expected the hold to fire")
 	}
+
+	assert.Equal(Block, s.source.Release(wtypes.VK_1))
+	assert.Empty(s.hook.GetShortcutChan(), "The release should not fire again")
 }
 
-// TestKeyStateTracking verifies that the hook correctly tracks the state of pressed keys
-func (s *HookTestSuite) TestKeyStateTracking() {
-	require := require.New(s.T())
+// TestRepeatFire verifies that a binding allowed to repeat fires on every auto-repeat and not again on release
+func (s *HookTestSuite) TestRepeatFire() {
 	assert := assert.New(s.T())
+	binding := shortcut.NewBindingAction([]wtypes.VirtualKey{wtypes.VK_MENU, wtypes.VK_RIGHT}, func(context.Context, shortcut.ActionContext) error { return nil }, true)
+	binding.Repeat = shortcut.Repeat{Kind: shortcut.RepeatFire}
+	s.service.RegisterKeyBindingActions(binding)
+
+	s.source.Press(wtypes.VK_LMENU)
+	assert.Equal(Block, s.source.Press(wtypes.VK_RIGHT))
+	assert.Empty(s.hook.GetShortcutChan(), "A binding fired on release should wait for a repeat or the release")
+	for range 3 {
+		assert.Equal(Block, s.source.Press(wtypes.VK_RIGHT), "Auto-repeats should be blocked")
+	}
+	assert.Equal(Block, s.source.Release(wtypes.VK_RIGHT))
+	assert.Len(s.hook.GetShortcutChan(), 3, "Each auto-repeat should fire once and the release not at all")
+}
 
-	err := s.hook.Start()
-	require.NoError(err, "Start should not return an error")
+// TestRepeatIgnoredByDefault verifies that holding a shortcut fires it once
+func (s *HookTestSuite) TestRepeatIgnoredByDefault() {
+	s.source.Press(wtypes.VK_LMENU)
+	for range 5 {
+		s.source.Press(wtypes.VK_1)
+	}
+	s.source.Release(wtypes.VK_1)
+	s.Len(s.hook.GetShortcutChan(), 1)
+}
 
-	// Create a helper function to create keyboard events
-	createKeyEvent := func(message types.Message, key wtypes.VirtualKey) types.KeyboardEvent {
-		return types.KeyboardEvent{
-			Message: message,
-			KBDLLHOOKSTRUCT: types.KBDLLHOOKSTRUCT{
-				VKCode: types.VKCode(key),
-			},
-		}
+// TestLayerTapAndHold verifies that a layer key acts as its modifier while held and sends its tap key when tapped alone
+func (s *HookTestSuite) TestLayerTapAndHold() {
+	assert := assert.New(s.T())
+	fake := s.useFakeClock()
+	modifiers := wtypes.NewModifiers()
+	hyper, err := modifiers.Define("Hyper")
+	s.Require().NoError(err)
+	s.hook.SetLayers(modifiers, Layer{Key: wtypes.VK_CAPITAL, Modifier: hyper, Tap: wtypes.VK_ESCAPE, TapTimeout: 200 * time.Millisecond})
+	s.service.RegisterKeyBindingActions(shortcut.NewBindingAction([]wtypes.VirtualKey{hyper, wtypes.VK_H}, func(context.Context, shortcut.ActionContext) error { return nil }, true))
+	esc := []RawEvent{
+		{VKCode: wtypes.VK_ESCAPE, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_ESCAPE, KeyDown: false, ExtraInfo: InjectedTag},
 	}
 
-	// Simulate pressing multiple keys
-	keys := []wtypes.VirtualKey{wtypes.VK_LCONTROL, wtypes.VK_A}
+	assert.Equal(Block, s.source.Press(wtypes.VK_CAPITAL), "The layer key should never reach the application")
+	assert.Equal([]wtypes.VirtualKey{hyper}, s.hook.getCurrentKeyState(), "The layer key should be held as its modifier")
+	assert.Equal(Block, s.source.Release(wtypes.VK_CAPITAL))
+	assert.Equal(esc, s.source.Delivered(), "A tap should send Escape")
+	s.source.Reset()
+
+	assert.Equal([]Decision{Block, Block, Block, Block}, s.source.Chord(wtypes.VK_CAPITAL, wtypes.VK_H))
+	assert.Len(s.hook.GetShortcutChan(), 1, "Hyper+H should be dispatched")
+	assert.Empty(s.source.Delivered(), "A layer used as a modifier should not send its tap key")
+
+	s.source.Chord(wtypes.VK_CAPITAL, wtypes.VK_J)
+	assert.Equal([]RawEvent{
+		{VKCode: wtypes.VK_J, KeyDown: true},
+		{VKCode: wtypes.VK_J, KeyDown: false},
+	}, s.source.Delivered(), "Keys without a layer binding should pass on their own")
+	s.source.Reset()
+
+	s.source.Press(wtypes.VK_CAPITAL)
+	s.source.Press(wtypes.VK_CAPITAL)
+	fake.Advance(300 * time.Millisecond)
+	s.source.Release(wtypes.VK_CAPITAL)
+	assert.Empty(s.source.Delivered(), "A press longer than the tap timeout is no tap")
+	assert.Empty(s.hook.getCurrentKeyState())
+}
 
-	// Press first key
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYDOWN, keys[0])
+// TestRemapKeySwap verifies that swapped keys reach the application and match bindings as each other
+func (s *HookTestSuite) TestRemapKeySwap() {
+	assert := assert.New(s.T())
+	s.hook.SetRemaps(
+		Remap{From: wtypes.KeyBinding{wtypes.VK_CAPITAL}, To: wtypes.KeyBinding{wtypes.VK_LCONTROL}},
+		Remap{From: wtypes.KeyBinding{wtypes.VK_LCONTROL}, To: wtypes.KeyBinding{wtypes.VK_CAPITAL}},
+	)
+	s.service.RegisterKeyBindingActions(shortcut.NewBindingAction([]wtypes.VirtualKey{wtypes.VK_CONTROL, wtypes.VK_1}, func(context.Context, shortcut.ActionContext) error { return nil }, true))
+
+	assert.Equal(Block, s.source.Press(wtypes.VK_CAPITAL), "The typed key should be replaced")
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LCONTROL}, s.hook.getCurrentKeyState(), "The target should be held in its place")
+	s.source.Press(wtypes.VK_1)
+	s.source.Release(wtypes.VK_1)
+	s.source.Release(wtypes.VK_CAPITAL)
+	assert.Len(s.hook.GetShortcutChan(), 1, "CapsLock+1 should match Ctrl+1")
+	assert.Equal([]RawEvent{
+		{VKCode: wtypes.VK_LCONTROL, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LCONTROL, KeyDown: false, ExtraInfo: InjectedTag},
+	}, s.source.Delivered(), "The application should see Ctrl, and the injected keys should not be remapped again")
+	assert.Empty(s.hook.getCurrentKeyState())
+	s.source.Reset()
+
+	s.source.Chord(wtypes.VK_LCONTROL)
+	assert.Equal([]RawEvent{
+		{VKCode: wtypes.VK_CAPITAL, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_CAPITAL, KeyDown: false, ExtraInfo: InjectedTag},
+	}, s.source.Delivered())
+
+	s.source.Press(wtypes.VK_CAPITAL)
+	s.hook.SetRemaps()
+	s.source.Release(wtypes.VK_CAPITAL)
+	assert.Empty(s.hook.getCurrentKeyState(), "A key held while the remaps change should be released as its old target")
+}
 
-	// Wait for first key event
-	select {
-	case event := <-s.hook.GetShortcutChan():
-		assert.True(event.Binding.Contains(keys[0]), "KeyDown should be true for first key")
-	case <-time.After(time.Second):
-		s.T().Fatal("Timeout waiting for first key event")
+// TestRemapCombo verifies that a remapped combination sends its target in place of itself and of any binding
+func (s *HookTestSuite) TestRemapCombo() {
+	assert := assert.New(s.T())
+	s.hook.SetRemaps(Remap{From: wtypes.KeyBinding{wtypes.VK_MENU, wtypes.VK_H}, To: wtypes.KeyBinding{wtypes.VK_LEFT}})
+	s.service.RegisterKeyBindingActions(shortcut.NewBindingAction([]wtypes.VirtualKey{wtypes.VK_MENU, wtypes.VK_H}, func(context.Context, shortcut.ActionContext) error { return nil }, true))
+	left := []RawEvent{
+		{VKCode: MenuMaskKey, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: MenuMaskKey, KeyDown: false, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LMENU, KeyDown: false, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LEFT, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LEFT, KeyDown: false, ExtraInfo: InjectedTag},
+		{VKCode: wtypes.VK_LMENU, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: MenuMaskKey, KeyDown: true, ExtraInfo: InjectedTag},
+		{VKCode: MenuMaskKey, KeyDown: false, ExtraInfo: InjectedTag},
 	}
 
-	// Press second key
-	s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYDOWN, keys[1])
+	s.source.Press(wtypes.VK_LMENU)
+	s.source.Reset()
+	assert.Equal(Block, s.source.Press(wtypes.VK_H))
+	assert.Equal(left, s.source.Delivered(), "Alt should be lifted around a plain Left")
+	s.source.Reset()
 
-	// Wait for second key event
-	select {
-	case event := <-s.hook.GetShortcutChan():
-		assert.True(event.Binding.Contains(keys[1]), "KeyDown should be true for second key")
-		assert.Contains(event.Binding, keys[0], "First key should be in pressed state")
-	case <-time.After(time.Second):
-		s.T().Fatal("Timeout waiting for second key event")
-	}
+	assert.Equal(Block, s.source.Press(wtypes.VK_H), "Auto-repeats should be remapped too")
+	assert.Equal(left, s.source.Delivered())
+	s.source.Reset()
 
-	// Release keys in reverse order
-	for i := len(keys) - 1; i >= 0; i-- {
-		s.hook.lowLevelChan <- createKeyEvent(types.WM_KEYUP, keys[i])
+	assert.Equal(Block, s.source.Release(wtypes.VK_H))
+	assert.Equal(Pass, s.source.Release(wtypes.VK_LMENU))
+	assert.Empty(s.hook.GetShortcutChan(), "The remap should take precedence over the Alt+H binding")
+	assert.Empty(s.hook.getCurrentKeyState())
+}
 
-		select {
-		case event := <-s.hook.GetShortcutChan():
-			assert.False(event.Binding.Contains(keys[i]), "KeyDown should be false for key release")
-			if i == len(keys)-1 {
-				// When releasing the second key (A), both keys should still be in pressed state
-				assert.Contains(event.Binding, keys[0], "First key should still be pressed")
-				assert.Contains(event.Binding, keys[1], "Second key should still be in state before release")
-			} else {
-				// When releasing the first key (LCONTROL), only it should be in pressed state
-				assert.Contains(event.Binding, keys[0], "First key should be in state before release")
-				assert.NotContains(event.Binding, keys[1], "Second key should be released")
-			}
-		case <-time.After(time.Second):
-			s.T().Fatal("Timeout waiting for key release event")
-		}
-	}
+// TestStuckKeyRecovery verifies that a key whose release was lost stops keeping shortcuts from matching
+func (s *HookTestSuite) TestStuckKeyRecovery() {
+	assert := assert.New(s.T())
+	fake := s.useFakeClock()
+	s.service.RegisterKeyBindingActions(shortcut.NewBindingAction([]wtypes.VirtualKey{wtypes.VK_MENU, wtypes.VK_1}, func(context.Context, shortcut.ActionContext) error { return nil }, true))
+
+	s.source.Press(wtypes.VK_LWIN)
+	s.source.Lose(wtypes.VK_LWIN) // Win+L: the release goes to the lock screen
+	s.source.Press(wtypes.VK_LMENU)
+	fake.Advance(staleAfter)
+	assert.Equal(Block, s.source.Press(wtypes.VK_1), "Alt+1 should match once Win is known to be released")
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LMENU, wtypes.VK_1}, s.hook.getCurrentKeyState(), "Alt is still held according to the system")
+	s.source.Release(wtypes.VK_LMENU)
+	s.Require().Len(s.hook.GetShortcutChan(), 1)
+	<-s.hook.GetShortcutChan()
+
+	// The press of 1 was blocked, so the system cannot confirm it; it is dropped after the timeout
+	fake.Advance(unverifiedTimeout)
+	s.source.Press(wtypes.VK_LMENU)
+	assert.Equal(Block, s.source.Press(wtypes.VK_1), "The press of 1 should be new rather than a repeat of the lost one")
+	assert.Equal(Block, s.source.Release(wtypes.VK_1))
+	s.Len(s.hook.GetShortcutChan(), 1)
+}
+
+// TestResetKeys verifies that resetting forgets held keys, as done when the session is locked
+func (s *HookTestSuite) TestResetKeys() {
+	s.source.Press(wtypes.VK_LWIN)
+	s.source.Press(wtypes.VK_LMENU)
+	s.hook.ResetKeys("session locked")
+	s.Empty(s.hook.getCurrentKeyState())
+}
+
+// TestSuspendToggle verifies that the toggle keys suspend every shortcut and resume them, even while suspended
+func (s *HookTestSuite) TestSuspendToggle() {
+	assert := assert.New(s.T())
+	var changes []bool
+	s.hook.OnSuspendChange(func(suspended bool) { changes = append(changes, suspended) })
+	s.hook.SetSuspendToggle(wtypes.KeyBinding{wtypes.VK_CONTROL, wtypes.VK_MENU, wtypes.VK_PAUSE})
+
+	s.source.Press(wtypes.VK_LCONTROL)
+	s.source.Press(wtypes.VK_LMENU)
+	assert.Equal(Block, s.source.Press(wtypes.VK_PAUSE), "The toggle should not reach the application")
+	assert.Equal(Block, s.source.Press(wtypes.VK_PAUSE), "Nor should its auto-repeats")
+	assert.Equal(Block, s.source.Release(wtypes.VK_PAUSE))
+	s.source.Release(wtypes.VK_LMENU)
+	s.source.Release(wtypes.VK_LCONTROL)
+	assert.True(s.hook.Suspended())
+
+	s.source.Reset()
+	assert.Equal([]Decision{Pass, Pass, Pass, Pass}, s.source.Chord(wtypes.VK_LMENU, wtypes.VK_1), "Shortcuts should pass while suspended")
+	assert.Len(s.source.Events(), 4, "Nothing should be injected while suspended")
+	assert.Empty(s.hook.GetShortcutChan())
+
+	s.source.Chord(wtypes.VK_RCONTROL, wtypes.VK_RMENU, wtypes.VK_PAUSE)
+	assert.False(s.hook.Suspended())
+	assert.Equal([]bool{true, false}, changes)
+	s.source.Chord(wtypes.VK_LMENU, wtypes.VK_1)
+	assert.Len(s.hook.GetShortcutChan(), 1, "Shortcuts should work again once resumed")
+}
+
+// TestSuspendKeepsHeldKeys verifies that keys still held when the hook is suspended or resumed keep counting
+func (s *HookTestSuite) TestSuspendKeepsHeldKeys() {
+	assert := assert.New(s.T())
+	s.hook.SetSuspendToggle(wtypes.KeyBinding{wtypes.VK_CONTROL, wtypes.VK_MENU, wtypes.VK_PAUSE})
+	s.hook.ToggleSuspend()
+
+	s.source.Press(wtypes.VK_LCONTROL)
+	s.source.Press(wtypes.VK_LMENU)
+	s.source.Press(wtypes.VK_PAUSE)
+	assert.False(s.hook.Suspended())
+	s.source.Release(wtypes.VK_PAUSE)
+	s.source.Release(wtypes.VK_LCONTROL)
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LMENU}, s.hook.getCurrentKeyState(), "Alt is still down after resuming")
+
+	s.source.Chord(wtypes.VK_1)
+	s.source.Release(wtypes.VK_LMENU)
+	assert.Len(s.hook.GetShortcutChan(), 1, "Alt held across the toggle should complete Alt+1")
+}
+
+// fixedWindow is a WindowProvider reporting a settable foreground window.
+type fixedWindow struct {
+	window shortcut.Window
+	err    error
+}
+
+func (f *fixedWindow) ForegroundWindow() (shortcut.Window, error) {
+	return f.window, f.err
+}
+
+// TestAutoSuspend verifies that the suspend rule follows the foreground window and that the toggle overrides it
+func (s *HookTestSuite) TestAutoSuspend() {
+	assert := assert.New(s.T())
+	provider := &fixedWindow{window: shortcut.Window{Process: "Game.EXE"}}
+	s.hook.SetSuspendRule(SuspendRule{FullScreen: true, Processes: []string{"game.exe"}})
+
+	s.hook.checkAutoSuspend(provider)
+	assert.True(s.hook.Suspended(), "A listed process should suspend the hook")
+	provider.err = errors.New("window closed")
+	provider.window = shortcut.Window{}
+	s.hook.checkAutoSuspend(provider)
+	assert.True(s.hook.Suspended(), "A failed lookup should keep the state")
+
+	provider.err = nil
+	s.hook.checkAutoSuspend(provider)
+	assert.False(s.hook.Suspended())
+	provider.window = shortcut.Window{Process: "player.exe", FullScreen: true}
+	s.hook.checkAutoSuspend(provider)
+	assert.True(s.hook.Suspended(), "A full-screen window should suspend the hook")
+
+	s.hook.ToggleSuspend()
+	s.hook.checkAutoSuspend(provider)
+	assert.False(s.hook.Suspended(), "The toggle should resume until the rule stops applying")
+	provider.window.FullScreen = false
+	s.hook.checkAutoSuspend(provider)
+	provider.window.FullScreen = true
+	s.hook.checkAutoSuspend(provider)
+	assert.True(s.hook.Suspended())
 }
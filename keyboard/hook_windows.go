@@ -0,0 +1,102 @@
+//go:build windows
+
+package keyboard
+
+import (
+	"fmt"
+	"unsafe"
+
+	wtypes "wincuts/keyboard/types"
+
+	"github.com/lxn/win"
+	"github.com/moutend/go-hook/pkg/keyboard"
+	"github.com/moutend/go-hook/pkg/types"
+	"github.com/moutend/go-hook/pkg/win32"
+	"golang.org/x/sys/windows"
+)
+
+var procGetAsyncKeyState = windows.NewLazySystemDLL("user32.dll").NewProc("GetAsyncKeyState")
+
+// LowLevelHook is the InputSource backed by the Windows WH_KEYBOARD_LL hook.
+type LowLevelHook struct {
+	events chan types.KeyboardEvent // Required by go-hook, unused because events are handled in the hook procedure
+}
+
+// NewLowLevelHook creates an InputSource for the Windows low-level keyboard hook.
+func NewLowLevelHook() *LowLevelHook {
+	return &LowLevelHook{events: make(chan types.KeyboardEvent)}
+}
+
+// Install implements InputSource. The handler runs on the hook thread and its decision is returned
+// to Windows directly, which is the only point where a key can still be swallowed.
+func (l *LowLevelHook) Install(handler EventHandler) error {
+	proc := func(chan<- types.KeyboardEvent) types.HOOKPROC {
+		return func(code int32, wParam, lParam uintptr) uintptr {
+			if code >= 0 && lParam != 0 {
+				info := *(**types.KBDLLHOOKSTRUCT)(unsafe.Pointer(&lParam))
+				message := types.Message(wParam)
+				event := RawEvent{
+					VKCode:    wtypes.VirtualKey(info.VKCode),
+					KeyDown:   message == types.WM_KEYDOWN || message == types.WM_SYSKEYDOWN,
+					ExtraInfo: info.DWExtraInfo,
+				}
+				if handler(event) == Block {
+					return 1
+				}
+			}
+			return win32.CallNextHookEx(0, code, wParam, lParam)
+		}
+	}
+	if err := keyboard.Install(proc, l.events); err != nil {
+		return fmt.Errorf("failed to install keyboard hook: %w", err)
+	}
+	return nil
+}
+
+// Uninstall implements InputSource.
+func (l *LowLevelHook) Uninstall() error {
+	return keyboard.Uninstall()
+}
+
+// IsKeyDown implements KeyStateQuery with GetAsyncKeyState.
+func (l *LowLevelHook) IsKeyDown(vk wtypes.VirtualKey) bool {
+	state, _, _ := procGetAsyncKeyState.Call(uintptr(vk))
+	return state&0x8000 != 0
+}
+
+// SendInputInjector is the KeyInjector backed by the Windows SendInput API.
+type SendInputInjector struct{}
+
+// SendKey implements KeyInjector.
+func (SendInputInjector) SendKey(vk wtypes.VirtualKey, down bool) error {
+	input := win.KEYBD_INPUT{
+		Type: win.INPUT_KEYBOARD,
+		Ki: win.KEYBDINPUT{
+			WVk:         uint16(vk),
+			DwExtraInfo: uintptr(InjectedTag),
+		},
+	}
+	if isExtendedKey(vk) {
+		input.Ki.DwFlags |= win.KEYEVENTF_EXTENDEDKEY
+	}
+	if !down {
+		input.Ki.DwFlags |= win.KEYEVENTF_KEYUP
+	}
+	if sent := win.SendInput(1, unsafe.Pointer(&input), int32(unsafe.Sizeof(input))); sent != 1 {
+		return fmt.Errorf("failed to inject key %s", vk.KeybindName())
+	}
+	return nil
+}
+
+// isExtendedKey reports whether vk is on the extended part of the keyboard. Applications would otherwise
+// take remapped arrows and navigation keys for their number pad counterparts.
+func isExtendedKey(vk wtypes.VirtualKey) bool {
+	switch vk {
+	case wtypes.VK_LEFT, wtypes.VK_RIGHT, wtypes.VK_UP, wtypes.VK_DOWN,
+		wtypes.VK_INSERT, wtypes.VK_DELETE, wtypes.VK_HOME, wtypes.VK_END, wtypes.VK_PRIOR, wtypes.VK_NEXT,
+		wtypes.VK_RCONTROL, wtypes.VK_RMENU, wtypes.VK_LWIN, wtypes.VK_RWIN, wtypes.VK_APPS,
+		wtypes.VK_DIVIDE, wtypes.VK_NUMLOCK:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package keyboard
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"wincuts/clock"
+	wtypes "wincuts/keyboard/types"
+)
+
+const (
+	// staleAfter is how long a key may be held without any event for it before the system is asked whether it is still down.
+	staleAfter = time.Second
+	// unverifiedTimeout is how long a key the system cannot confirm, because its press was blocked or it is a
+	// custom modifier, may be held without any event for it before it is considered released.
+	unverifiedTimeout = time.Minute
+)
+
+// heldKey is a key the hook saw pressed and not yet released.
+type heldKey struct {
+	at      time.Time // Last press or auto-repeat
+	visible bool      // The last press reached the system, so KeyStateQuery knows about the key
+}
+
+// correction is a held key the hook forgot because its release was lost.
+type correction struct {
+	key    wtypes.VirtualKey
+	reason string
+}
+
+// keyState tracks the keys currently held. Windows keeps sending key-down events while a key stays
+// pressed, so a press of a key that is already held is reported as an auto-repeat.
+// A release can get lost, e.g. to the secure desktop after Win+L, so held keys are reconciled with the system.
+type keyState struct {
+	mu    sync.RWMutex
+	clock clock.Clock
+	held  map[wtypes.VirtualKey]*heldKey
+}
+
+// newKeyState creates a keyState with no keys held, timing held keys on clk.
+func newKeyState(clk clock.Clock) *keyState {
+	return &keyState{clock: clk, held: make(map[wtypes.VirtualKey]*heldKey)}
+}
+
+// update records a press or release of key. It returns the keys held before the event and
+// whether the event is an auto-repeat, i.e. a press of a key that is already held.
+func (k *keyState) update(key wtypes.VirtualKey, down bool) (before []wtypes.VirtualKey, repeat bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	before = slices.Collect(maps.Keys(k.held))
+	held, repeat := k.held[key]
+	repeat = down && repeat
+	switch {
+	case repeat:
+		held.at = k.clock.Now()
+	case down:
+		k.held[key] = &heldKey{at: k.clock.Now()}
+	default:
+		delete(k.held, key)
+	}
+	return before, repeat
+}
+
+// setVisible records whether the last press of a held key reached the system.
+func (k *keyState) setVisible(key wtypes.VirtualKey, visible bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if held, ok := k.held[key]; ok {
+		held.visible = visible
+	}
+}
+
+// reconcile forgets the keys that have been held without events for a while and are no longer down:
+// the system is asked about the keys it knows, and the others are dropped after unverifiedTimeout.
+// query may be nil, in which case every key is treated as unverifiable.
+func (k *keyState) reconcile(query KeyStateQuery) []correction {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.clock.Now()
+	var corrections []correction
+	for key, held := range k.held {
+		idle := now.Sub(held.at)
+		switch {
+		case idle < staleAfter:
+		case held.visible && query != nil:
+			if !query.IsKeyDown(key) {
+				corrections = append(corrections, correction{key: key, reason: "released according to the system"})
+			}
+		case idle >= unverifiedTimeout:
+			corrections = append(corrections, correction{key: key, reason: "held too long without events"})
+		}
+	}
+	for _, c := range corrections {
+		delete(k.held, c.key)
+	}
+	return corrections
+}
+
+// pressed returns a snapshot of the keys currently held.
+func (k *keyState) pressed() []wtypes.VirtualKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return slices.Collect(maps.Keys(k.held))
+}
+
+// resync keeps the held keys the system reports as down and forgets the others, returning those forgotten.
+// Custom modifiers and keys whose press was blocked are unknown to the system, so they are forgotten, and
+// so is every key if query is nil.
+func (k *keyState) resync(query KeyStateQuery) []wtypes.VirtualKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var forgotten []wtypes.VirtualKey
+	for key, held := range k.held {
+		if query != nil && !key.IsCustomModifier() && query.IsKeyDown(key) {
+			held.visible = true
+			continue
+		}
+		forgotten = append(forgotten, key)
+		delete(k.held, key)
+	}
+	return forgotten
+}
+
+// reset forgets every held key and returns them.
+func (k *keyState) reset() []wtypes.VirtualKey {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := slices.Collect(maps.Keys(k.held))
+	clear(k.held)
+	return keys
+}
@@ -0,0 +1,92 @@
+package keyboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"wincuts/clock"
+	wtypes "wincuts/keyboard/types"
+)
+
+// TestKeyStateRepeat verifies that presses of a held key are reported as auto-repeats.
+func TestKeyStateRepeat(t *testing.T) {
+	assert := assert.New(t)
+	state := newKeyState(clock.New())
+
+	before, repeat := state.update(wtypes.VK_LMENU, true)
+	assert.Empty(before)
+	assert.False(repeat, "The first press is not a repeat")
+
+	before, repeat = state.update(wtypes.VK_RIGHT, true)
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LMENU}, before)
+	assert.False(repeat)
+
+	before, repeat = state.update(wtypes.VK_RIGHT, true)
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LMENU, wtypes.VK_RIGHT}, before, "A repeat sees its own key held")
+	assert.True(repeat)
+
+	_, repeat = state.update(wtypes.VK_RIGHT, false)
+	assert.False(repeat, "Releases are never repeats")
+	_, repeat = state.update(wtypes.VK_RIGHT, true)
+	assert.False(repeat, "A press after the release is a new press")
+
+	state.reset()
+	assert.Empty(state.pressed())
+	_, repeat = state.update(wtypes.VK_RIGHT, true)
+	assert.False(repeat, "Reset forgets held keys")
+}
+
+// fakeQuery is a KeyStateQuery reporting the keys set to true as pressed.
+type fakeQuery map[wtypes.VirtualKey]bool
+
+func (q fakeQuery) IsKeyDown(vk wtypes.VirtualKey) bool {
+	return q[vk]
+}
+
+// TestKeyStateReconcile verifies that keys held without events are checked with the system, or dropped after a timeout if it cannot tell.
+func TestKeyStateReconcile(t *testing.T) {
+	assert := assert.New(t)
+	fake := clock.NewFake(time.Time{})
+	state := newKeyState(fake)
+	query := fakeQuery{wtypes.VK_LSHIFT: true}
+
+	for _, key := range []wtypes.VirtualKey{wtypes.VK_LWIN, wtypes.VK_LSHIFT, wtypes.VK_1} {
+		state.update(key, true)
+	}
+	state.setVisible(wtypes.VK_LWIN, true)
+	state.setVisible(wtypes.VK_LSHIFT, true)
+	assert.Empty(state.reconcile(query), "Recently pressed keys should not be checked")
+
+	fake.Advance(staleAfter)
+	assert.Equal([]correction{{key: wtypes.VK_LWIN, reason: "released according to the system"}}, state.reconcile(query))
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_LSHIFT, wtypes.VK_1}, state.pressed(), "Keys the system holds or cannot see should stay")
+
+	fake.Advance(unverifiedTimeout - staleAfter - time.Millisecond)
+	state.update(wtypes.VK_1, true)
+	fake.Advance(time.Millisecond)
+	assert.Empty(state.reconcile(query), "An auto-repeat should restart the timeout")
+	fake.Advance(unverifiedTimeout)
+	assert.Equal([]correction{{key: wtypes.VK_1, reason: "held too long without events"}}, state.reconcile(query))
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LSHIFT}, state.pressed())
+	assert.Equal([]correction{{key: wtypes.VK_LSHIFT, reason: "held too long without events"}}, state.reconcile(nil),
+		"Without a query the timeout applies to every key")
+}
+
+// TestKeyStateResync verifies that only the keys the system reports as down stay held.
+func TestKeyStateResync(t *testing.T) {
+	assert := assert.New(t)
+	state := newKeyState(clock.New())
+	hyper := wtypes.VK_WIN + 1
+	for _, key := range []wtypes.VirtualKey{wtypes.VK_LMENU, wtypes.VK_1, hyper} {
+		state.update(key, true)
+	}
+
+	forgotten := state.resync(fakeQuery{wtypes.VK_LMENU: true, hyper: true})
+	assert.ElementsMatch([]wtypes.VirtualKey{wtypes.VK_1, hyper}, forgotten, "Custom modifiers are unknown to the system")
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LMENU}, state.pressed())
+
+	assert.Equal([]wtypes.VirtualKey{wtypes.VK_LMENU}, state.resync(nil), "Without a query every key is forgotten")
+	assert.Empty(state.pressed())
+}
@@ -0,0 +1,82 @@
+package keyboard
+
+import (
+	"time"
+
+	"wincuts/clock"
+	wtypes "wincuts/keyboard/types"
+)
+
+// Layer turns a physical key into a custom modifier while it is held, such as CapsLock acting as Hyper,
+// so bindings like Hyper+H can be matched. Tapped on its own the key can send another key instead, e.g. Esc.
+type Layer struct {
+	Key        wtypes.VirtualKey // Physical key held for the layer, e.g. VK_CAPITAL
+	Modifier   wtypes.VirtualKey // Custom modifier reported while Key is held, see types.Modifiers.Define
+	Tap        wtypes.VirtualKey // Sent when Key is pressed and released without another key in between; zero sends nothing
+	TapTimeout time.Duration     // A press held longer than this is never a tap; zero means no limit
+}
+
+// heldLayer is a layer whose key is down.
+type heldLayer struct {
+	Layer
+	pressedAt time.Time
+	used      bool // Another key was pressed while the layer key was down, so its release is no tap
+}
+
+// layers translates layer keys into their modifiers and tells taps from holds.
+// A held layer keeps the definition it was pressed with, so a config reload cannot strand its modifier.
+type layers struct {
+	clock clock.Clock
+	defs  map[wtypes.VirtualKey]Layer
+	held  map[wtypes.VirtualKey]*heldLayer
+}
+
+// newLayers creates layers without any definitions, timing taps on clk.
+func newLayers(clk clock.Clock) *layers {
+	return &layers{
+		clock: clk,
+		defs:  make(map[wtypes.VirtualKey]Layer),
+		held:  make(map[wtypes.VirtualKey]*heldLayer),
+	}
+}
+
+// set replaces the layer definitions.
+func (l *layers) set(defs []Layer) {
+	l.defs = make(map[wtypes.VirtualKey]Layer, len(defs))
+	for _, def := range defs {
+		l.defs[def.Key] = def
+	}
+}
+
+// handle inspects a key event. For a layer key it returns the layer's modifier, which replaces the key in the
+// event, and on release the key to send if the layer was tapped. Any other key pressed marks held layers as used.
+func (l *layers) handle(key wtypes.VirtualKey, down bool) (modifier wtypes.VirtualKey, isLayer bool, tap wtypes.VirtualKey) {
+	if held, ok := l.held[key]; ok {
+		if down {
+			return held.Modifier, true, 0 // Auto-repeat
+		}
+		delete(l.held, key)
+		if !held.used && (held.TapTimeout <= 0 || l.clock.Now().Sub(held.pressedAt) <= held.TapTimeout) {
+			tap = held.Tap
+		}
+		return held.Modifier, true, tap
+	}
+	if !down {
+		return 0, false, 0
+	}
+
+	for _, held := range l.held {
+		held.used = true
+	}
+	def, ok := l.defs[key]
+	if !ok {
+		return 0, false, 0
+	}
+	l.held[key] = &heldLayer{Layer: def, pressedAt: l.clock.Now()}
+	return def.Modifier, true, 0
+}
+
+// reset forgets the held layers.
+func (l *layers) reset() {
+	clear(l.held)
+}
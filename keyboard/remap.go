@@ -0,0 +1,169 @@
+package keyboard
+
+import (
+	"log/slog"
+	"slices"
+
+	"wincuts/keyboard/shortcut"
+	wtypes "wincuts/keyboard/types"
+)
+
+// Remap sends other keys to the focused application in place of the keys typed.
+// A single key mapped to a single key is remapped for as long as it is held, so it can act as a modifier,
+// e.g. CapsLock as LCtrl. Otherwise typing From sends To as a whole, e.g. Alt+H sends Left.
+type Remap struct {
+	From wtypes.KeyBinding // Keys typed; a combination needs a key besides modifiers
+	To   wtypes.KeyBinding // Keys sent instead
+}
+
+// isKey reports whether the remap replaces one key by another while it is held.
+func (r Remap) isKey() bool {
+	return len(r.From) == 1 && len(r.To) == 1
+}
+
+// remapper holds the remaps of a hook and the combinations being held.
+type remapper struct {
+	keys    map[wtypes.VirtualKey]wtypes.VirtualKey // Key remaps by typed key
+	combos  []Remap                                 // Combination remaps in precedence order
+	pressed map[wtypes.VirtualKey]wtypes.VirtualKey // Targets of the remapped keys being held
+	held    map[wtypes.VirtualKey]Remap             // Combination remaps by the held key that triggered them
+}
+
+// newRemapper creates a remapper without remaps.
+func newRemapper() *remapper {
+	return &remapper{
+		keys:    make(map[wtypes.VirtualKey]wtypes.VirtualKey),
+		pressed: make(map[wtypes.VirtualKey]wtypes.VirtualKey),
+		held:    make(map[wtypes.VirtualKey]Remap),
+	}
+}
+
+// set replaces the remaps. Keys and combinations being held finish with their old remap.
+func (r *remapper) set(remaps []Remap) {
+	r.keys = make(map[wtypes.VirtualKey]wtypes.VirtualKey)
+	r.combos = nil
+	for _, remap := range remaps {
+		if remap.isKey() {
+			r.keys[remap.From[0]] = remap.To[0]
+		} else {
+			r.combos = append(r.combos, remap)
+		}
+	}
+	// The most specific combination wins, like bindings
+	slices.SortStableFunc(r.combos, func(a, b Remap) int {
+		return b.From.Specificity() - a.From.Specificity()
+	})
+}
+
+// key returns the key that replaces a press or release of key, if it is remapped.
+func (r *remapper) key(key wtypes.VirtualKey, down bool) (wtypes.VirtualKey, bool) {
+	if target, ok := r.pressed[key]; ok {
+		if !down {
+			delete(r.pressed, key)
+		}
+		return target, true
+	}
+	if !down {
+		return 0, false
+	}
+	target, ok := r.keys[key]
+	if ok {
+		r.pressed[key] = target
+	}
+	return target, ok
+}
+
+// combo returns the combination remap for event. A new press of a non-modifier key completing a remapped
+// combination starts it, and its auto-repeats and release belong to it until the release.
+func (r *remapper) combo(event shortcut.KeyEvent) (Remap, bool) {
+	if remap, ok := r.held[event.KeyCode]; ok {
+		if !event.KeyDown {
+			delete(r.held, event.KeyCode)
+		}
+		return remap, true
+	}
+	if !event.KeyDown || event.Repeat || event.KeyCode.IsModifier() {
+		return Remap{}, false
+	}
+	keys := append(slices.Clone(event.PressedKeys), event.KeyCode)
+	for _, remap := range r.combos {
+		if remap.From.Match(keys) {
+			r.held[event.KeyCode] = remap
+			return remap, true
+		}
+	}
+	return Remap{}, false
+}
+
+// reset forgets the keys and combinations being held.
+func (r *remapper) reset() {
+	clear(r.pressed)
+	clear(r.held)
+}
+
+// sendCombo taps the keys of to while the keys in held are down. Held modifiers that to does not use are released
+// around the tap and pressed again afterwards, so Alt+H can send a plain Left; custom modifiers exist only
+// for matching and are never sent.
+func (h *Hook) sendCombo(to wtypes.KeyBinding, held []wtypes.VirtualKey) {
+	var lifted []wtypes.VirtualKey
+	for _, vk := range held {
+		if !vk.IsModifier() {
+			continue
+		}
+		if !vk.IsCustomModifier() && !slices.ContainsFunc(to, vk.Satisfies) {
+			lifted = append(lifted, vk)
+		}
+	}
+	var pressed []wtypes.VirtualKey
+	for _, vk := range to {
+		if !slices.ContainsFunc(held, func(h wtypes.VirtualKey) bool { return h.Satisfies(vk) }) {
+			pressed = append(pressed, injectable(vk))
+		}
+	}
+	masked := slices.ContainsFunc(lifted, isMenuKey)
+
+	var events []RawEvent
+	for _, vk := range lifted {
+		events = append(events, RawEvent{VKCode: vk, KeyDown: false})
+	}
+	for _, vk := range pressed {
+		events = append(events, RawEvent{VKCode: vk, KeyDown: true})
+	}
+	for _, vk := range slices.Backward(pressed) {
+		events = append(events, RawEvent{VKCode: vk, KeyDown: false})
+	}
+	for _, vk := range lifted {
+		events = append(events, RawEvent{VKCode: vk, KeyDown: true})
+	}
+
+	// Releasing Alt or Win on its own would open the menu bar or the Start menu, and so would
+	// releasing them later after pressing them again; the mask key keeps both from counting.
+	if masked {
+		h.suppressor.maskMenu()
+	}
+	h.send(events...)
+	if masked {
+		h.suppressor.maskMenu()
+	}
+}
+
+// send injects events in order, stopping at the first failure.
+func (h *Hook) send(events ...RawEvent) {
+	if h.injector == nil {
+		return
+	}
+	for _, event := range events {
+		if err := h.injector.SendKey(event.VKCode, event.KeyDown); err != nil {
+			slog.Error("failed to send key", "key", event.VKCode.KeybindName(), "down", event.KeyDown, "error", err)
+			return
+		}
+	}
+}
+
+// injectable returns a key that can be sent for vk: the generic Windows key has no virtual key code of its own.
+func injectable(vk wtypes.VirtualKey) wtypes.VirtualKey {
+	if vk == wtypes.VK_WIN {
+		return wtypes.VK_LWIN
+	}
+	return vk
+}
@@ -0,0 +1,170 @@
+package keyboard
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	wtypes "wincuts/keyboard/types"
+)
+
+// ScriptedEvent is an event delivered by a ScriptedSource together with the handler's decision.
+type ScriptedEvent struct {
+	RawEvent
+	Decision Decision
+}
+
+// ScriptedSource is an in-memory InputSource driven by test code instead of a keyboard.
+// It also implements KeyInjector: injected events are fed back through the handler after the
+// current event has been decided, the way Windows delivers SendInput events to low-level hooks.
+// As a KeyStateQuery it reports the keys whose last passed event was a press, like the system would.
+type ScriptedSource struct {
+	mu         sync.Mutex
+	handler    EventHandler
+	delivering bool
+	queue      []RawEvent
+	events     []ScriptedEvent
+	down       map[wtypes.VirtualKey]bool // Keys the system considers pressed
+}
+
+// NewScriptedSource creates an empty ScriptedSource.
+func NewScriptedSource() *ScriptedSource {
+	return &ScriptedSource{down: make(map[wtypes.VirtualKey]bool)}
+}
+
+// Install implements InputSource.
+func (s *ScriptedSource) Install(handler EventHandler) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handler != nil {
+		return errors.New("keyboard: scripted source is already installed")
+	}
+	s.handler = handler
+	return nil
+}
+
+// Uninstall implements InputSource.
+func (s *ScriptedSource) Uninstall() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.handler == nil {
+		return errors.New("keyboard: scripted source is not installed")
+	}
+	s.handler = nil
+	return nil
+}
+
+// Installed reports whether a handler is installed.
+func (s *ScriptedSource) Installed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handler != nil
+}
+
+// SendKey implements KeyInjector by delivering a tagged event.
+func (s *ScriptedSource) SendKey(vk wtypes.VirtualKey, down bool) error {
+	s.Send(RawEvent{VKCode: vk, KeyDown: down, ExtraInfo: InjectedTag})
+	return nil
+}
+
+// Send delivers an event and returns the handler's decision. Without an installed handler
+// the event passes, as it would with no hook in place. Events sent while another event is
+// being decided are queued and delivered afterwards; their decision is reported as Pass.
+func (s *ScriptedSource) Send(event RawEvent) Decision {
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	if s.delivering {
+		s.mu.Unlock()
+		return Pass
+	}
+	s.delivering = true
+
+	first := true
+	var decision Decision
+	for len(s.queue) > 0 {
+		next := s.queue[0]
+		s.queue = s.queue[1:]
+		handler := s.handler
+		s.mu.Unlock()
+
+		d := Pass
+		if handler != nil {
+			d = handler(next)
+		}
+
+		s.mu.Lock()
+		s.events = append(s.events, ScriptedEvent{RawEvent: next, Decision: d})
+		if d == Pass {
+			s.down[next.VKCode] = next.KeyDown
+		}
+		if first {
+			decision, first = d, false
+		}
+	}
+	s.delivering = false
+	s.mu.Unlock()
+	return decision
+}
+
+// Press delivers a key press.
+func (s *ScriptedSource) Press(vk wtypes.VirtualKey) Decision {
+	return s.Send(RawEvent{VKCode: vk, KeyDown: true})
+}
+
+// Release delivers a key release.
+func (s *ScriptedSource) Release(vk wtypes.VirtualKey) Decision {
+	return s.Send(RawEvent{VKCode: vk, KeyDown: false})
+}
+
+// Lose releases vk without delivering the event, as when the release goes to the secure desktop after Win+L.
+func (s *ScriptedSource) Lose(vk wtypes.VirtualKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down[vk] = false
+}
+
+// IsKeyDown implements KeyStateQuery.
+func (s *ScriptedSource) IsKeyDown(vk wtypes.VirtualKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.down[vk]
+}
+
+// Chord presses the keys in order and releases them in reverse order, returning all decisions.
+func (s *ScriptedSource) Chord(vks ...wtypes.VirtualKey) []Decision {
+	var decisions []Decision
+	for _, vk := range vks {
+		decisions = append(decisions, s.Press(vk))
+	}
+	for _, vk := range slices.Backward(vks) {
+		decisions = append(decisions, s.Release(vk))
+	}
+	return decisions
+}
+
+// Events returns every delivered event in order, including injected ones.
+func (s *ScriptedSource) Events() []ScriptedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events)
+}
+
+// Delivered returns the events that passed the handler, i.e. what the focused application would see.
+func (s *ScriptedSource) Delivered() []RawEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var delivered []RawEvent
+	for _, event := range s.events {
+		if event.Decision == Pass {
+			delivered = append(delivered, event.RawEvent)
+		}
+	}
+	return delivered
+}
+
+// Reset forgets the recorded events.
+func (s *ScriptedSource) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = nil
+}
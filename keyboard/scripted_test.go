@@ -0,0 +1,63 @@
+package keyboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	wtypes "wincuts/keyboard/types"
+)
+
+// TestScriptedSourceInstall verifies the install lifecycle and that events pass without a handler
+func TestScriptedSourceInstall(t *testing.T) {
+	source := NewScriptedSource()
+	assert.Equal(t, Pass, source.Press(wtypes.VK_A), "Events should pass without a handler")
+
+	require.NoError(t, source.Install(func(RawEvent) Decision { return Block }))
+	assert.Error(t, source.Install(func(RawEvent) Decision { return Pass }), "Second install should fail")
+	assert.Equal(t, Block, source.Press(wtypes.VK_A))
+
+	require.NoError(t, source.Uninstall())
+	assert.Error(t, source.Uninstall(), "Second uninstall should fail")
+	assert.Equal(t, Pass, source.Release(wtypes.VK_A))
+}
+
+// TestScriptedSourceInjection verifies that injected events are delivered after the current event is decided
+func TestScriptedSourceInjection(t *testing.T) {
+	source := NewScriptedSource()
+	var handled []RawEvent
+	require.NoError(t, source.Install(func(event RawEvent) Decision {
+		handled = append(handled, event)
+		if event.VKCode == wtypes.VK_A && event.ExtraInfo != InjectedTag {
+			require.NoError(t, source.SendKey(wtypes.VK_B, true))
+			assert.Len(t, handled, 1, "Injected event should not be delivered re-entrantly")
+			return Block
+		}
+		return Pass
+	}))
+
+	assert.Equal(t, Block, source.Press(wtypes.VK_A), "Decision of the sent event should be returned")
+	assert.Equal(t, []ScriptedEvent{
+		{RawEvent: RawEvent{VKCode: wtypes.VK_A, KeyDown: true}, Decision: Block},
+		{RawEvent: RawEvent{VKCode: wtypes.VK_B, KeyDown: true, ExtraInfo: InjectedTag}, Decision: Pass},
+	}, source.Events())
+	assert.Equal(t, []RawEvent{{VKCode: wtypes.VK_B, KeyDown: true, ExtraInfo: InjectedTag}}, source.Delivered())
+
+	source.Reset()
+	assert.Empty(t, source.Events())
+}
+
+// TestScriptedSourceChord verifies that a chord presses in order and releases in reverse order
+func TestScriptedSourceChord(t *testing.T) {
+	source := NewScriptedSource()
+	decisions := source.Chord(wtypes.VK_LCONTROL, wtypes.VK_C)
+
+	assert.Equal(t, []Decision{Pass, Pass, Pass, Pass}, decisions)
+	assert.Equal(t, []RawEvent{
+		{VKCode: wtypes.VK_LCONTROL, KeyDown: true},
+		{VKCode: wtypes.VK_C, KeyDown: true},
+		{VKCode: wtypes.VK_C, KeyDown: false},
+		{VKCode: wtypes.VK_LCONTROL, KeyDown: false},
+	}, source.Delivered())
+}
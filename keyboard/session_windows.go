@@ -0,0 +1,93 @@
+//go:build windows
+
+package keyboard
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/lxn/win"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	wtsapi32                             = windows.NewLazySystemDLL("wtsapi32.dll")
+	procWTSRegisterSessionNotification   = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSUnRegisterSessionNotification = wtsapi32.NewProc("WTSUnRegisterSessionNotification")
+)
+
+const (
+	wmWTSSessionChange   = 0x02B1 // WM_WTSSESSION_CHANGE
+	notifyForThisSession = 0      // NOTIFY_FOR_THIS_SESSION
+	wtsSessionLock       = 0x7    // WTS_SESSION_LOCK
+	wtsSessionUnlock     = 0x8    // WTS_SESSION_UNLOCK
+)
+
+// WatchSession calls onChange whenever the session is locked or unlocked, until stop is called.
+// Key releases made while the lock screen is up never reach the hook, so callers reset the key state.
+// The notifications arrive on a message-only window with its own thread; call WatchSession once per process.
+func WatchSession(onChange func(locked bool)) (stop func(), err error) {
+	proc := func(hwnd win.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+		switch msg {
+		case wmWTSSessionChange:
+			switch wParam {
+			case wtsSessionLock:
+				onChange(true)
+			case wtsSessionUnlock:
+				onChange(false)
+			}
+			return 0
+		case win.WM_DESTROY:
+			procWTSUnRegisterSessionNotification.Call(uintptr(hwnd))
+			win.PostQuitMessage(0)
+			return 0
+		}
+		return win.DefWindowProc(hwnd, msg, wParam, lParam)
+	}
+
+	started := make(chan win.HWND)
+	failed := make(chan error)
+	go func() {
+		// Window messages are delivered to the thread that created the window
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		className := syscall.StringToUTF16Ptr("WinCutsSessionWatcher")
+		wc := win.WNDCLASSEX{
+			CbSize:        uint32(unsafe.Sizeof(win.WNDCLASSEX{})),
+			LpfnWndProc:   syscall.NewCallback(proc),
+			HInstance:     win.GetModuleHandle(nil),
+			LpszClassName: className,
+		}
+		if atom := win.RegisterClassEx(&wc); atom == 0 {
+			failed <- fmt.Errorf("failed to register session watcher window class")
+			return
+		}
+		hwnd := win.CreateWindowEx(0, className, nil, 0, 0, 0, 0, 0, win.HWND_MESSAGE, 0, win.GetModuleHandle(nil), nil)
+		if hwnd == 0 {
+			failed <- fmt.Errorf("failed to create session watcher window")
+			return
+		}
+		if ok, _, err := procWTSRegisterSessionNotification.Call(uintptr(hwnd), notifyForThisSession); ok == 0 {
+			win.DestroyWindow(hwnd)
+			failed <- fmt.Errorf("failed to register for session notifications: %w", err)
+			return
+		}
+		started <- hwnd
+
+		var msg win.MSG
+		for win.GetMessage(&msg, 0, 0, 0) > 0 {
+			win.TranslateMessage(&msg)
+			win.DispatchMessage(&msg)
+		}
+	}()
+
+	select {
+	case hwnd := <-started:
+		return func() { win.PostMessage(hwnd, win.WM_CLOSE, 0, 0) }, nil
+	case err := <-failed:
+		return nil, err
+	}
+}
@@ -1,40 +1,119 @@
 package shortcut
 
 import (
+	"context"
+	"log/slog"
+	"slices"
+
 	"wincuts/keyboard/types"
 )
 
-type KeyBindingFunc func() error
+// KeyBindingFunc is the action of a binding. It should give up once ctx is done, which happens when the
+// action runs longer than the executor's timeout; ac describes the firing of the binding.
+type KeyBindingFunc func(ctx context.Context, ac ActionContext) error
 
 type KeyBindingAction struct {
 	Binding types.KeyBinding 
+	Prefix  []types.KeyBinding // Steps pressed before Binding when the binding is a key sequence
+	Window  *WindowCondition   // Restricts the binding to some foreground windows; nil applies everywhere
 	Action 	KeyBindingFunc
-	onKeyDown bool
+	Trigger Trigger // When the binding fires; the zero value fires on release
+	Repeat  Repeat  // What auto-repeat of the held keys does; the zero value fires once per press
 	ShouldBlock bool
+	Modifiers   *types.Modifiers // Names the custom modifiers of the keys in logs; nil if there are none
+	// Metadata of the configured binding, shown in logs
+	ID          string
+	Description string
+	Tags        []string
 }
 
-func (kba *KeyBindingAction) Execute() error {
-	return kba.Action()
+func (kba *KeyBindingAction) Execute(ctx context.Context, ac ActionContext) error {
+	return kba.Action(ctx, ac)
 }
 
+// Match reports whether the release in event fires the binding. Bindings triggered on press, hold or
+// double tap never match here; the Service fires them from key presses.
 func (kba *KeyBindingAction) Match(event KeyEvent) bool {
-	if event.KeyDown != kba.onKeyDown{
+	if event.KeyDown || kba.Trigger.Kind != TriggerUp {
 		return false
 	}
+	if kba.IsSequence() {
+		return false // Sequences are completed step by step through Matcher.Press
+	}
 	
 	return kba.Binding.Match(event.PressedKeys)
 } 
 
+// IsSequence reports whether the binding is triggered by several steps pressed one after another.
+func (kba *KeyBindingAction) IsSequence() bool {
+	return len(kba.Prefix) > 0
+}
+
+// Steps returns every step of the binding, ending with Binding.
+func (kba *KeyBindingAction) Steps() []types.KeyBinding {
+	return append(slices.Clone(kba.Prefix), kba.Binding)
+}
+
+// Specificity sums the specificity of every step, see types.KeyBinding.Specificity.
+func (kba *KeyBindingAction) Specificity() int {
+	specificity := kba.Binding.Specificity()
+	for _, step := range kba.Prefix {
+		specificity += step.Specificity()
+	}
+	return specificity
+}
+
+// outranks reports whether kba takes precedence over other when both match: bindings scoped to
+// the foreground window win over global ones, then side-specific keys win over generic ones.
+func (kba *KeyBindingAction) outranks(other *KeyBindingAction) bool {
+	if scoped, otherScoped := kba.scoped(), other.scoped(); scoped != otherScoped {
+		return scoped
+	}
+	return kba.Specificity() > other.Specificity()
+}
+
+// scoped reports whether the binding only applies to windows matching a When rule.
+func (kba *KeyBindingAction) scoped() bool {
+	return kba.Window != nil && kba.Window.When != nil
+}
+
+// LogValue implements slog.LogValuer, logging the keys of the binding along with its metadata.
+func (kba *KeyBindingAction) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("keys", FormatSteps(kba.Steps(), kba.Modifiers))}
+	if kba.ID != "" {
+		attrs = append(attrs, slog.String("id", kba.ID))
+	}
+	if kba.Description != "" {
+		attrs = append(attrs, slog.String("description", kba.Description))
+	}
+	if len(kba.Tags) > 0 {
+		attrs = append(attrs, slog.Any("tags", kba.Tags))
+	}
+	return slog.GroupValue(attrs...)
+}
+
 func NewBindingActionFromBinding(binding types.KeyBinding, action KeyBindingFunc) (KeyBindingAction) {
 	return KeyBindingAction{
 		Binding: binding,
 		Action:      action,
-		onKeyDown: false,
 		ShouldBlock: false,
 	}
 
 }
 
 func NewBindingAction(keys []types.VirtualKey, action KeyBindingFunc, shouldBlock bool) (KeyBindingAction) {
-	return NewBindingActionFromBinding(types.NewKeybinding(keys...),action)
+	binding := NewBindingActionFromBinding(types.NewKeybinding(keys...),action)
+	binding.ShouldBlock = shouldBlock
+	return binding
+}
+
+// NewSequenceBindingAction creates a binding triggered by pressing the steps one after another,
+// for example Alt+W followed by 3. With a single step it is an ordinary binding.
+func NewSequenceBindingAction(steps []types.KeyBinding, action KeyBindingFunc, shouldBlock bool) KeyBindingAction {
+	last := len(steps) - 1
+	binding := NewBindingAction(steps[last], action, shouldBlock)
+	if last > 0 {
+		binding.Prefix = slices.Clone(steps[:last])
+	}
+	return binding
 }
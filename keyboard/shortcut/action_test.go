@@ -1,6 +1,7 @@
 package shortcut
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,7 +15,7 @@ func TestKeyBindingActionExecute(t *testing.T) {
 	assert := assert.New(t)
 
 	var executed bool
-	action := func() error {
+	action := func(context.Context, ActionContext) error {
 		executed = true
 		return nil
 	}
@@ -22,7 +23,7 @@ func TestKeyBindingActionExecute(t *testing.T) {
 	keys := []types.VirtualKey{types.VK_LMENU, types.VK_1}
 	bindingAction := NewBindingAction(keys, action, false)
 
-	err := bindingAction.Execute()
+	err := bindingAction.Execute(context.Background(), ActionContext{})
 	assert.NoError(err, "Execute should not return an error")
 	assert.True(executed, "Expected action function to have been executed")
 }
@@ -32,8 +33,8 @@ func TestKeyBindingActionMatch(t *testing.T) {
 	assert := assert.New(t)
 	require := require.New(t)
 
-	// Create a binding action; by default, NewBindingAction sets onKeyDown to false.
-	action := func() error { return nil }
+	// Create a binding action; by default, NewBindingAction fires on release.
+	action := func(context.Context, ActionContext) error { return nil }
 	keys := []types.VirtualKey{types.VK_LMENU, types.VK_1}
 	bindingAction := NewBindingAction(keys, action, false)
 
@@ -60,3 +61,17 @@ func TestKeyBindingActionMatch(t *testing.T) {
 	assert.False(bindingAction.Match(nonMatchingEvent), "Expected Match to return false when KeyDown state differs")
 	assert.False(bindingAction.Match(nonMatchingKeysEvent), "Expected Match to return false for non-matching keys")
 }
+
+// TestKeyBindingActionLogValue verifies that bindings are logged with their keys and whatever metadata they carry.
+func TestKeyBindingActionLogValue(t *testing.T) {
+	bindingAction := NewSequenceBindingAction([]types.KeyBinding{
+		types.NewKeybinding(types.VK_LMENU, types.VK_W),
+		types.NewKeybinding(types.VK_3),
+	}, nil, false)
+	assert.Equal(t, "[keys=LMENU + W, 3]", bindingAction.LogValue().String())
+
+	bindingAction.ID = "move-3"
+	bindingAction.Description = "Move to desktop 3"
+	bindingAction.Tags = []string{"desktop"}
+	assert.Equal(t, "[keys=LMENU + W, 3 id=move-3 description=Move to desktop 3 tags=[desktop]]", bindingAction.LogValue().String())
+}
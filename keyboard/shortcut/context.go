@@ -0,0 +1,78 @@
+package shortcut
+
+import (
+	"log/slog"
+	"time"
+)
+
+// ActionContext describes the firing of a binding to its action. The time, the mode and the handle of the
+// foreground window are captured when the binding fires; the rest of the window and the desktop are looked up
+// before the action is queued, off the keyboard hook, so an action waiting in the executor's queue still sees
+// the window the keys were pressed in.
+type ActionContext struct {
+	Binding *KeyBindingAction // Binding that fired
+	Time    time.Time         // When the binding fired
+	Window  Window            // Foreground window when the binding fired; unknown parts are empty
+	Desktop int               // Virtual desktop shown when the action was queued, counting from 1; zero if unknown
+	Mode    string            // Binding mode active when the binding fired, DefaultMode outside of modes
+}
+
+// DesktopProvider reports the virtual desktop being shown.
+type DesktopProvider interface {
+	CurrentDesktop() (int, error) // Counting from 1
+}
+
+// WindowDescriber is implemented by window providers that can read the foreground window handle cheaply
+// enough for the keyboard hook, and describe the window behind a handle later.
+type WindowDescriber interface {
+	ForegroundHandle() uintptr
+	DescribeWindow(handle uintptr) (Window, error)
+}
+
+// SetDesktopProvider sets the source of the current desktop reported to actions.
+func (s *Service) SetDesktopProvider(provider DesktopProvider) *Service {
+	s.desktopsMu.Lock()
+	defer s.desktopsMu.Unlock()
+	s.desktops = provider
+	return s
+}
+
+// actionContext captures what is cheap to read when binding fires, on the thread of the keyboard hook:
+// the time, the mode and the handle of the foreground window. See describe for the rest.
+func (s *Service) actionContext(binding *KeyBindingAction) ActionContext {
+	ac := ActionContext{Binding: binding, Time: s.matcher.clock.Now(), Mode: s.matcher.Mode()}
+	if describer, ok := s.matcher.windowProvider().(WindowDescriber); ok {
+		ac.Window.Handle = describer.ForegroundHandle()
+	}
+	return ac
+}
+
+// describe fills in the foreground window and the current desktop of a firing captured by actionContext.
+// Both may take a while to look up, so it runs on the service goroutine rather than in the keyboard hook.
+func (s *Service) describe(ac *ActionContext) {
+	var w Window
+	var err error
+	provider := s.matcher.windowProvider()
+	if describer, ok := provider.(WindowDescriber); ok {
+		if ac.Window.Handle != 0 {
+			w, err = describer.DescribeWindow(ac.Window.Handle)
+		}
+	} else {
+		w, err = provider.ForegroundWindow()
+	}
+	if err != nil {
+		slog.Debug("failed to get the foreground window", "error", err)
+	}
+	ac.Window = w
+
+	s.desktopsMu.Lock()
+	desktops := s.desktops
+	s.desktopsMu.Unlock()
+	if desktops != nil {
+		desktop, err := desktops.CurrentDesktop()
+		if err != nil {
+			slog.Debug("failed to get the current desktop", "error", err)
+		}
+		ac.Desktop = desktop
+	}
+}
@@ -0,0 +1,239 @@
+package shortcut
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"wincuts/clock"
+)
+
+// ExecutionMode decides whether actions run one after another or side by side.
+type ExecutionMode int
+
+const (
+	ExecuteSerial     ExecutionMode = iota // One action at a time in the order the shortcuts fired, the default
+	ExecuteConcurrent                      // Every action in its own goroutine as soon as its shortcut fires
+)
+
+func (m ExecutionMode) String() string {
+	if m == ExecuteConcurrent {
+		return "concurrent"
+	}
+	return "serial"
+}
+
+// ExecutionPolicy configures how an Executor runs actions. The zero value runs actions serially without limits.
+type ExecutionPolicy struct {
+	Mode ExecutionMode
+	// Timeout is how long an action may run before the executor cancels it and reports it as failed; zero waits
+	// forever. The executor gives a cancelled action as long again to return, so serial actions do not overlap,
+	// then abandons it: an action that ignores the cancellation keeps running in the background, overlapping
+	// the actions after it and outliving Stop.
+	Timeout time.Duration
+	// Debounce drops firings of a binding closer than this to its previous accepted firing, and firings of a binding
+	// still waiting in the queue; zero runs them all.
+	Debounce time.Duration
+}
+
+// executorQueueSize is how many actions may wait to run; firings beyond it are dropped.
+const executorQueueSize = 64
+
+// PanicError reports an action that panicked. The executor recovers the panic so the process keeps running.
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("action panicked: %v", e.Value)
+}
+
+// actionKey identifies a binding across the copies the matcher hands out, for coalescing and debouncing.
+type actionKey struct {
+	steps   string
+	trigger TriggerKind
+	window  *WindowCondition
+}
+
+// keyOf returns the identity of a binding.
+func keyOf(binding *KeyBindingAction) actionKey {
+	return actionKey{steps: FormatSteps(binding.Steps(), nil), trigger: binding.Trigger.Kind, window: binding.Window}
+}
+
+// Executor runs the actions of fired bindings off the keyboard hook according to an ExecutionPolicy,
+// recovering panics and reporting failures.
+type Executor struct {
+	clock     clock.Clock
+	queue     chan ActionContext
+	done      chan struct{}  // Closed once the queue has been drained after Stop
+	running   sync.WaitGroup // Actions started concurrently, done once they return or are abandoned after a timeout
+	mu        sync.Mutex
+	policy    ExecutionPolicy
+	queued    map[actionKey]int       // How often each binding is waiting in the queue
+	last      map[actionKey]time.Time // When each binding was last accepted, for debouncing
+	stopped   bool
+	onFailure func(binding *KeyBindingAction, err error)
+}
+
+// NewExecutor creates an executor that runs actions serially, timing debounces on clk.
+func NewExecutor(clk clock.Clock) *Executor {
+	e := &Executor{
+		clock:  clk,
+		queue:  make(chan ActionContext, executorQueueSize),
+		done:   make(chan struct{}),
+		queued: make(map[actionKey]int),
+		last:   make(map[actionKey]time.Time),
+	}
+	go e.work()
+	return e
+}
+
+// SetPolicy changes how actions are run from the next one taken from the queue on.
+func (e *Executor) SetPolicy(policy ExecutionPolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+}
+
+// OnFailure registers a function called with every action that returns an error, times out or panics.
+// It is called on the goroutine that ran the action.
+func (e *Executor) OnFailure(fn func(binding *KeyBindingAction, err error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onFailure = fn
+}
+
+// Submit queues the action of the binding that fired in ac and reports whether it was accepted. A firing is dropped
+// when the queue is full or after Stop, and while debouncing when the same binding is still waiting in the queue or
+// fired within the debounce interval.
+func (e *Executor) Submit(ac ActionContext) bool {
+	binding := ac.Binding
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return false
+	}
+	key := keyOf(binding)
+	if e.policy.Debounce > 0 && e.queued[key] > 0 {
+		slog.Debug("coalesced action", "binding", binding)
+		return false
+	}
+	now := e.clock.Now()
+	if last, ok := e.last[key]; ok && e.policy.Debounce > 0 && now.Sub(last) < e.policy.Debounce {
+		slog.Debug("debounced action", "binding", binding)
+		return false
+	}
+	select {
+	case e.queue <- ac:
+		e.queued[key]++
+		e.last[key] = now
+		return true
+	default:
+		slog.Warn("dropped action, too many actions waiting", "binding", binding)
+		return false
+	}
+}
+
+// Stop stops accepting actions and waits until the queued and running ones have finished or timed out.
+func (e *Executor) Stop() {
+	e.mu.Lock()
+	if !e.stopped {
+		e.stopped = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	<-e.done
+	e.running.Wait()
+}
+
+// work takes actions from the queue until it is closed and drained.
+func (e *Executor) work() {
+	defer close(e.done)
+	for ac := range e.queue {
+		e.mu.Lock()
+		key := keyOf(ac.Binding)
+		if e.queued[key]--; e.queued[key] == 0 {
+			delete(e.queued, key)
+		}
+		policy := e.policy
+		e.mu.Unlock()
+
+		if policy.Mode == ExecuteConcurrent {
+			e.running.Add(1)
+			go func() {
+				defer e.running.Done()
+				e.run(ac, policy.Timeout)
+			}()
+		} else {
+			e.run(ac, policy.Timeout)
+		}
+	}
+}
+
+// run executes the action of the binding that fired in ac, cancelling it and reporting it as failed after timeout
+// unless timeout is zero. It returns once the action has returned, or once it has been abandoned for ignoring
+// the cancellation for another timeout.
+func (e *Executor) run(ac ActionContext, timeout time.Duration) {
+	binding := ac.Binding
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	slog.Info("executing action", "binding", binding, "mode", ac.Mode, "desktop", ac.Desktop, "window", ac.Window.Process)
+	result := make(chan error, 1)
+	go func() {
+		defer func() {
+			if value := recover(); value != nil {
+				result <- &PanicError{Value: value, Stack: debug.Stack()}
+			}
+		}()
+		result <- binding.Execute(ctx, ac)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		e.fail(binding, fmt.Errorf("action timed out after %s: %w", timeout, ctx.Err()))
+		// Wait a while for the action to give up, so the next serial action does not overlap it. Only a panic
+		// is worth reporting again; any other error is the cancellation taking effect.
+		grace := time.NewTimer(timeout)
+		defer grace.Stop()
+		select {
+		case err = <-result:
+		case <-grace.C:
+			slog.Warn("abandoned action that ignores cancellation", "binding", binding)
+			return
+		}
+		var panicErr *PanicError
+		if !errors.As(err, &panicErr) {
+			return
+		}
+	}
+	if err != nil {
+		e.fail(binding, err)
+	}
+}
+
+// fail logs and reports a failed action.
+func (e *Executor) fail(binding *KeyBindingAction, err error) {
+	if panicErr, ok := err.(*PanicError); ok {
+		slog.Error("action panicked", "binding", binding, "panic", panicErr.Value, "stack", string(panicErr.Stack))
+	} else {
+		slog.Error("failed to execute action", "binding", binding, "error", err)
+	}
+	e.mu.Lock()
+	onFailure := e.onFailure
+	e.mu.Unlock()
+	if onFailure != nil {
+		onFailure(binding, err)
+	}
+}
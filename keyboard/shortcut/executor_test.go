@@ -0,0 +1,226 @@
+package shortcut
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// recorder collects the names of the actions run, in the order they ran.
+type recorder struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+// action returns a binding of key that records name, waiting for release first if it is not nil.
+func (r *recorder) action(key types.VirtualKey, name string, release <-chan struct{}) *KeyBindingAction {
+	binding := NewBindingAction([]types.VirtualKey{key}, func(context.Context, ActionContext) error {
+		if release != nil {
+			<-release
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ran = append(r.ran, name)
+		return nil
+	}, false)
+	return &binding
+}
+
+// fired returns the context of binding firing.
+func fired(binding *KeyBindingAction) ActionContext {
+	return ActionContext{Binding: binding}
+}
+
+func (r *recorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ran...)
+}
+
+// TestExecutorSerial verifies that actions run one at a time in the order they were submitted,
+// even when an earlier one is slow, and that Stop waits for all of them.
+func TestExecutorSerial(t *testing.T) {
+	executor := NewExecutor(clock.New())
+	var r recorder
+	release := make(chan struct{})
+
+	require.True(t, executor.Submit(fired(r.action(types.VK_1, "slow", release))))
+	require.True(t, executor.Submit(fired(r.action(types.VK_2, "fast", nil))))
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, r.names(), "the second action must wait for the first")
+
+	close(release)
+	executor.Stop()
+	assert.Equal(t, []string{"slow", "fast"}, r.names())
+	assert.False(t, executor.Submit(fired(r.action(types.VK_3, "late", nil))), "no actions are accepted after Stop")
+}
+
+// TestExecutorConcurrent verifies that concurrent actions do not wait for each other.
+func TestExecutorConcurrent(t *testing.T) {
+	executor := NewExecutor(clock.New())
+	executor.SetPolicy(ExecutionPolicy{Mode: ExecuteConcurrent})
+	var r recorder
+	release := make(chan struct{})
+
+	require.True(t, executor.Submit(fired(r.action(types.VK_1, "slow", release))))
+	require.True(t, executor.Submit(fired(r.action(types.VK_2, "fast", nil))))
+	require.Eventually(t, func() bool { return len(r.names()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"fast"}, r.names())
+
+	close(release)
+	executor.Stop()
+	assert.Equal(t, []string{"fast", "slow"}, r.names())
+}
+
+// TestExecutorFailures verifies that errors, timeouts and panics are reported and do not hold up the queue.
+func TestExecutorFailures(t *testing.T) {
+	executor := NewExecutor(clock.New())
+	executor.SetPolicy(ExecutionPolicy{Timeout: 20 * time.Millisecond})
+	var mu sync.Mutex
+	var failures []error
+	executor.OnFailure(func(binding *KeyBindingAction, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err)
+	})
+
+	errBroken := errors.New("broken")
+	failing := NewBindingAction([]types.VirtualKey{types.VK_1}, func(context.Context, ActionContext) error { return errBroken }, false)
+	hanging := NewBindingAction([]types.VirtualKey{types.VK_2}, func(ctx context.Context, _ ActionContext) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, false)
+	panicking := NewBindingAction([]types.VirtualKey{types.VK_3}, func(context.Context, ActionContext) error { panic("boom") }, false)
+	var r recorder
+
+	require.True(t, executor.Submit(fired(&failing)))
+	require.True(t, executor.Submit(fired(&hanging)))
+	require.True(t, executor.Submit(fired(&panicking)))
+	require.True(t, executor.Submit(fired(r.action(types.VK_4, "after", nil))))
+	executor.Stop()
+
+	assert.Equal(t, []string{"after"}, r.names())
+	require.Len(t, failures, 3)
+	assert.ErrorIs(t, failures[0], errBroken)
+	assert.ErrorIs(t, failures[1], context.DeadlineExceeded)
+	var panicErr *PanicError
+	require.ErrorAs(t, failures[2], &panicErr)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+}
+
+// TestExecutorCoalesce verifies that a binding fired again while it is still waiting in the queue runs once
+// when debouncing, and every time otherwise.
+func TestExecutorCoalesce(t *testing.T) {
+	tests := []struct {
+		name     string
+		debounce time.Duration
+		want     []string
+	}{
+		{name: "without debounce", want: []string{"blocker", "waiting", "waiting", "other"}},
+		{name: "with debounce", debounce: time.Millisecond, want: []string{"blocker", "waiting", "other"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := NewExecutor(clock.NewFake(time.Time{}))
+			executor.SetPolicy(ExecutionPolicy{Debounce: tt.debounce})
+			var r recorder
+			release := make(chan struct{})
+
+			require.True(t, executor.Submit(fired(r.action(types.VK_1, "blocker", release))))
+			require.Eventually(t, func() bool {
+				executor.mu.Lock()
+				defer executor.mu.Unlock()
+				return len(executor.queued) == 0
+			}, time.Second, time.Millisecond, "the blocker must be running")
+
+			waiting := r.action(types.VK_2, "waiting", nil)
+			assert.True(t, executor.Submit(fired(waiting)))
+			copied := *waiting
+			assert.Equal(t, tt.debounce == 0, executor.Submit(fired(&copied)), "a copy of a queued binding is coalesced only when debouncing")
+			assert.True(t, executor.Submit(fired(r.action(types.VK_3, "other", nil))))
+
+			close(release)
+			executor.Stop()
+			assert.Equal(t, tt.want, r.names())
+		})
+	}
+}
+
+// TestExecutorTimeoutKeepsOrder verifies that a serial action waits for a timed-out one that gives up soon after
+// being cancelled.
+func TestExecutorTimeoutKeepsOrder(t *testing.T) {
+	executor := NewExecutor(clock.New())
+	executor.SetPolicy(ExecutionPolicy{Timeout: 50 * time.Millisecond})
+	var r recorder
+	slow := NewBindingAction([]types.VirtualKey{types.VK_1}, func(ctx context.Context, _ ActionContext) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond) // Cleaning up after the cancellation
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.ran = append(r.ran, "slow")
+		return ctx.Err()
+	}, false)
+
+	require.True(t, executor.Submit(fired(&slow)))
+	require.True(t, executor.Submit(fired(r.action(types.VK_2, "next", nil))))
+	executor.Stop()
+	assert.Equal(t, []string{"slow", "next"}, r.names())
+}
+
+// TestExecutorAbandonsStuckAction verifies that an action ignoring the cancellation holds up neither the queue
+// nor Stop for longer than twice the timeout.
+func TestExecutorAbandonsStuckAction(t *testing.T) {
+	executor := NewExecutor(clock.New())
+	executor.SetPolicy(ExecutionPolicy{Timeout: 10 * time.Millisecond})
+	var r recorder
+	release := make(chan struct{})
+	defer close(release)
+
+	require.True(t, executor.Submit(fired(r.action(types.VK_1, "stuck", release))))
+	require.True(t, executor.Submit(fired(r.action(types.VK_2, "next", nil))))
+	stopped := make(chan struct{})
+	go func() {
+		executor.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop must not wait for an action that ignores the cancellation")
+	}
+	assert.Equal(t, []string{"next"}, r.names())
+}
+
+// TestExecutorDebounce verifies that a binding fired again within the debounce interval is dropped.
+func TestExecutorDebounce(t *testing.T) {
+	clk := clock.NewFake(time.Time{})
+	executor := NewExecutor(clk)
+	executor.SetPolicy(ExecutionPolicy{Debounce: 200 * time.Millisecond})
+	var r recorder
+	binding := r.action(types.VK_1, "debounced", nil)
+	ran := func(n int) func() bool {
+		return func() bool { return len(r.names()) == n }
+	}
+
+	require.True(t, executor.Submit(fired(binding)))
+	require.Eventually(t, ran(1), time.Second, time.Millisecond)
+
+	clk.Advance(100 * time.Millisecond)
+	assert.False(t, executor.Submit(fired(binding)), "too soon after the last run")
+	assert.True(t, executor.Submit(fired(r.action(types.VK_2, "other", nil))), "other bindings are not affected")
+
+	clk.Advance(100 * time.Millisecond)
+	assert.True(t, executor.Submit(fired(binding)))
+	executor.Stop()
+	assert.Equal(t, []string{"debounced", "other", "debounced"}, r.names())
+}
@@ -0,0 +1,195 @@
+package shortcut
+
+import (
+	"math/bits"
+	"sync"
+
+	"wincuts/keyboard/types"
+)
+
+// keySet is a set of virtual keys stored as a bitset, so it can key a map whatever the order of the keys.
+// It holds the codes below keySetSize: every Windows key, the generic modifiers and the first custom modifiers.
+type keySet [8]uint64
+
+// keySetSize is the first key code a keySet cannot hold.
+const keySetSize = types.VirtualKey(len(keySet{}) * 64)
+
+// newKeySet returns the set of keys, or false if one of them does not fit.
+func newKeySet(keys []types.VirtualKey) (keySet, bool) {
+	var set keySet
+	for _, vk := range keys {
+		if vk >= keySetSize {
+			return keySet{}, false
+		}
+		set[vk/64] |= 1 << (vk % 64)
+	}
+	return set, true
+}
+
+// with returns the set with vk added; vk must fit.
+func (s keySet) with(vk types.VirtualKey) keySet {
+	s[vk/64] |= 1 << (vk % 64)
+	return s
+}
+
+// bindingIndex finds the bindings of a table by the exact keys held, so matching a key event only checks
+// the bindings on those keys however many bindings there are.
+type bindingIndex struct {
+	combos    keyIndex // Combinations by their keys
+	sequences keyIndex // Key sequences by the keys of their first step
+}
+
+// keyIndex holds bindings by a set of their keys, as indices into the table in registration order.
+type keyIndex struct {
+	byKeys    map[keySet][]int
+	unindexed []int // Bindings with keys a keySet cannot hold, checked one by one
+	size      int   // One more than the largest index, the bits a lookup may mark
+}
+
+// newBindingIndex indexes the bindings of a table.
+func newBindingIndex(bindings []KeyBindingAction) *bindingIndex {
+	x := &bindingIndex{
+		combos:    keyIndex{byKeys: make(map[keySet][]int)},
+		sequences: keyIndex{byKeys: make(map[keySet][]int)},
+	}
+	for i := range bindings {
+		binding := &bindings[i]
+		if binding.IsSequence() {
+			x.sequences.add(i, binding.Prefix[0])
+		} else {
+			x.combos.add(i, binding.Binding)
+		}
+	}
+	return x
+}
+
+// combinations returns the indices of the combination bindings that may match exactly keys, in registration order.
+// The result lives in buf, see keyIndex.lookup.
+func (x *bindingIndex) combinations(keys []types.VirtualKey, buf *lookupBuffers) []int {
+	return x.combos.lookup(keys, buf)
+}
+
+// sequenceStarts returns the indices of the key sequences whose first step may match exactly keys, in registration order.
+// The result lives in buf, see keyIndex.lookup.
+func (x *bindingIndex) sequenceStarts(keys []types.VirtualKey, buf *lookupBuffers) []int {
+	return x.sequences.lookup(keys, buf)
+}
+
+// add indexes the binding at index i under keys.
+func (x *keyIndex) add(i int, keys types.KeyBinding) {
+	x.size = max(x.size, i+1)
+	set, ok := newKeySet(keys)
+	if !ok {
+		x.unindexed = append(x.unindexed, i)
+		return
+	}
+	x.byKeys[set] = append(x.byKeys[set], i)
+}
+
+// lookupBuffers is the scratch space of lookups, reused through lookupPool so matching a key event does not allocate.
+type lookupBuffers struct {
+	marks      []uint64 // Bitset of the candidates by index, cleared again while it is read
+	touched    []uint64 // Bitset of the words of marks that are not zero, so reading skips the others
+	candidates []int
+}
+
+var lookupPool = sync.Pool{New: func() any { return new(lookupBuffers) }}
+
+// getLookupBuffers returns scratch space for lookups; return it with putLookupBuffers once the candidates are used.
+func getLookupBuffers() *lookupBuffers {
+	return lookupPool.Get().(*lookupBuffers)
+}
+
+func putLookupBuffers(buf *lookupBuffers) {
+	lookupPool.Put(buf)
+}
+
+// lookup collects the bindings stored under any set of keys matched by pressing keys, together with
+// the unindexed ones, in registration order. The result lives in buf and is valid until buf is reused.
+func (x *keyIndex) lookup(keys []types.VirtualKey, buf *lookupBuffers) []int {
+	if words := (x.size + 63) / 64; len(buf.marks) < words {
+		buf.marks = make([]uint64, words)
+		buf.touched = make([]uint64, (words+63)/64)
+	}
+	// Marking candidates in a bitset drops duplicates, as two held keys with the same generic form can reach
+	// a binding twice, and reading it back yields them in order without sorting
+	mark := func(list []int) {
+		for _, i := range list {
+			w := i / 64
+			buf.marks[w] |= 1 << (i % 64)
+			buf.touched[w/64] |= 1 << (w % 64)
+		}
+	}
+	mark(x.unindexed)
+	eachMatchedSet(keys, func(set keySet) {
+		mark(x.byKeys[set])
+	})
+
+	candidates := buf.candidates[:0]
+	for t, touched := range buf.touched {
+		for ; touched != 0; touched &= touched - 1 {
+			w := t*64 + bits.TrailingZeros64(touched)
+			for word := buf.marks[w]; word != 0; word &= word - 1 {
+				candidates = append(candidates, w*64+bits.TrailingZeros64(word))
+			}
+			buf.marks[w] = 0
+		}
+		buf.touched[t] = 0
+	}
+	buf.candidates = candidates
+	return candidates
+}
+
+// genericKeys holds the generic form of every side-specific modifier a keySet can hold, and zero for other keys,
+// so expanding held keys into matched sets needs no map lookups.
+var genericKeys = func() (generic [keySetSize]types.VirtualKey) {
+	for vk := range keySetSize {
+		generic[vk], _ = vk.Generic()
+	}
+	return generic
+}()
+
+// maxExpandedModifiers is how many held side-specific modifiers eachMatchedSet expands without allocating:
+// one for each side of Shift, Ctrl, Alt and Win.
+const maxExpandedModifiers = 8
+
+// eachMatchedSet calls fn with every set of binding keys that types.KeyBinding.Match accepts for exactly keys.
+// A side-specific modifier is satisfied by itself or its generic form, so each held one is named in a
+// binding as either or both of them: with n of them held there are 3^n sets, some of them repeated.
+// Keys a keySet cannot hold can only be matched by unindexed bindings, so fn is not called for them.
+func eachMatchedSet(keys []types.VirtualKey, fn func(set keySet)) {
+	var base keySet
+	var held [maxExpandedModifiers][2]types.VirtualKey
+	modifiers := held[:0]
+	for _, vk := range keys {
+		if vk >= keySetSize {
+			return
+		}
+		if generic := genericKeys[vk]; generic != 0 {
+			modifiers = append(modifiers, [2]types.VirtualKey{vk, generic})
+		} else {
+			base = base.with(vk)
+		}
+	}
+
+	combinations := 1
+	for range modifiers {
+		combinations *= 3
+	}
+	for c := range combinations {
+		set := base
+		digits := c // Each modifier in turn is named by itself, by its generic form or by both
+		for _, modifier := range modifiers {
+			switch digits % 3 {
+			case 0:
+				set = set.with(modifier[0])
+			case 1:
+				set = set.with(modifier[1])
+			default:
+				set = set.with(modifier[0]).with(modifier[1])
+			}
+			digits /= 3
+		}
+		fn(set)
+	}
+}
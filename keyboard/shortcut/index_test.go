@@ -0,0 +1,280 @@
+package shortcut
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/keyboard/types"
+)
+
+// bindingID identifies a test binding through the error its action returns.
+type bindingID int
+
+func (id bindingID) Error() string { return fmt.Sprintf("binding %d", int(id)) }
+
+// identify returns the id of a binding created by randomBindings, or -1 for none.
+func identify(binding *KeyBindingAction, ok bool) int {
+	if !ok {
+		return -1
+	}
+	return int(binding.Execute(context.Background(), ActionContext{}).(bindingID))
+}
+
+// unindexedKey is a key code too large for a keySet, so bindings naming it are checked one by one.
+const unindexedKey = keySetSize + 1
+
+// indexTestKeys are held in the random key sets: side-specific modifiers, since their generic forms
+// are what the index has to expand, some plain keys and a key the index cannot hold.
+var indexTestKeys = []types.VirtualKey{
+	types.VK_LMENU, types.VK_RMENU, types.VK_LSHIFT, types.VK_RSHIFT, types.VK_LCONTROL, types.VK_LWIN, types.VK_RWIN,
+	types.VK_A, types.VK_B, types.VK_1, unindexedKey,
+}
+
+// indexBindingKeys may appear in random bindings: the held keys and the generic modifiers.
+var indexBindingKeys = append(slices.Clone(indexTestKeys), types.VK_MENU, types.VK_SHIFT, types.VK_CONTROL, types.VK_WIN)
+
+// randomKeys picks up to max distinct keys from pool.
+func randomKeys(r *rand.Rand, pool []types.VirtualKey, max int) []types.VirtualKey {
+	var keys []types.VirtualKey
+	for range 1 + r.IntN(max) {
+		key := pool[r.IntN(len(pool))]
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// randomBindings creates n bindings over few keys, so many share keys and precedence ties are common.
+// About one in five is a two-step sequence.
+func randomBindings(r *rand.Rand, n int) []KeyBindingAction {
+	bindings := make([]KeyBindingAction, n)
+	for i := range bindings {
+		id := bindingID(i)
+		action := func(context.Context, ActionContext) error { return id }
+		first := types.NewKeybinding(randomKeys(r, indexBindingKeys, 3)...)
+		if r.IntN(5) == 0 {
+			second := types.NewKeybinding(randomKeys(r, indexBindingKeys, 2)...)
+			bindings[i] = NewSequenceBindingAction([]types.KeyBinding{first, second}, action, true)
+		} else {
+			bindings[i] = NewBindingActionFromBinding(first, action)
+		}
+	}
+	return bindings
+}
+
+// linearLookup is the reference for Matcher.Lookup: a scan over every binding.
+func linearLookup(bindings []KeyBindingAction, keys []types.VirtualKey) int {
+	var best *KeyBindingAction
+	for i := range bindings {
+		binding := &bindings[i]
+		if binding.IsSequence() || !binding.Binding.Match(keys) {
+			continue
+		}
+		if best == nil || binding.outranks(best) {
+			best = binding
+		}
+	}
+	return identify(best, best != nil)
+}
+
+// linearSequenceStarts is the reference for the first step of Matcher.Press: the sequences whose first step is keys.
+func linearSequenceStarts(bindings []KeyBindingAction, keys []types.VirtualKey) []int {
+	var starts []int
+	for i := range bindings {
+		if bindings[i].IsSequence() && bindings[i].Prefix[0].Match(keys) {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// TestBindingIndexMatchesLinearScan verifies that the index selects the same binding as checking every binding
+// in registration order, including generic and side-specific modifiers, precedence ties and unindexed keys.
+func TestBindingIndexMatchesLinearScan(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	for round := range 20 {
+		bindings := randomBindings(r, 200)
+		matcher := NewMatcher()
+		matcher.SetBindings(bindings...)
+		index := newBindingIndex(bindings)
+
+		for range 500 {
+			keys := randomKeys(r, indexTestKeys, 4)
+			assert.Equal(t, linearLookup(bindings, keys), identify(matcher.Lookup(keys)), "round %d, keys %v", round, keys)
+
+			var starts []int
+			for _, i := range index.sequenceStarts(keys, new(lookupBuffers)) {
+				if bindings[i].Prefix[0].Match(keys) {
+					starts = append(starts, i)
+				}
+			}
+			assert.Equal(t, linearSequenceStarts(bindings, keys), starts, "round %d, keys %v", round, keys)
+		}
+	}
+}
+
+// TestBindingIndexCandidates verifies which bindings the index returns for some held keys.
+func TestBindingIndexCandidates(t *testing.T) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	bindings := []KeyBindingAction{
+		NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_1}, noop, false),                        // 0
+		NewBindingAction([]types.VirtualKey{types.VK_LMENU, types.VK_1}, noop, false),                       // 1
+		NewBindingAction([]types.VirtualKey{types.VK_RMENU, types.VK_1}, noop, false),                       // 2
+		NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_LMENU, types.VK_1}, noop, false),        // 3
+		NewBindingAction([]types.VirtualKey{types.VK_1}, noop, false),                                       // 4
+		NewBindingAction([]types.VirtualKey{unindexedKey, types.VK_1}, noop, false),                         // 5
+		NewSequenceBindingAction([]types.KeyBinding{{types.VK_MENU, types.VK_W}, {types.VK_1}}, noop, true), // 6
+	}
+	index := newBindingIndex(bindings)
+
+	tests := []struct {
+		name          string
+		keys          []types.VirtualKey
+		wantCombos    []int
+		wantSequences []int
+	}{
+		{
+			name:       "left Alt reaches generic and left bindings",
+			keys:       []types.VirtualKey{types.VK_LMENU, types.VK_1},
+			wantCombos: []int{0, 1, 3, 5},
+		},
+		{
+			name:       "both Alt keys reach the generic bindings once",
+			keys:       []types.VirtualKey{types.VK_LMENU, types.VK_RMENU, types.VK_1},
+			wantCombos: []int{0, 3, 5},
+		},
+		{
+			name:       "plain key",
+			keys:       []types.VirtualKey{types.VK_1},
+			wantCombos: []int{4, 5},
+		},
+		{
+			name:          "first step of a sequence",
+			keys:          []types.VirtualKey{types.VK_RMENU, types.VK_W},
+			wantCombos:    []int{5},
+			wantSequences: []int{6},
+		},
+		{
+			name:       "unindexed key held",
+			keys:       []types.VirtualKey{unindexedKey, types.VK_1},
+			wantCombos: []int{5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantCombos, index.combinations(tt.keys, new(lookupBuffers)))
+			assert.Equal(t, tt.wantSequences, index.sequenceStarts(tt.keys, new(lookupBuffers)))
+		})
+	}
+}
+
+// TestBindingIndexFollowsChanges verifies that bindings added or replaced later are found.
+func TestBindingIndexFollowsChanges(t *testing.T) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	altOne := []types.VirtualKey{types.VK_LMENU, types.VK_1}
+	matcher := NewMatcher()
+
+	_, ok := matcher.Lookup(altOne)
+	require.False(t, ok)
+
+	matcher.AddBindings(NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_1}, noop, false))
+	binding, ok := matcher.Lookup(altOne)
+	require.True(t, ok)
+	assert.Equal(t, types.KeyBinding{types.VK_MENU, types.VK_1}, binding.Binding)
+
+	matcher.SetBindings(NewBindingAction(altOne, noop, false))
+	binding, ok = matcher.Lookup(altOne)
+	require.True(t, ok)
+	assert.Equal(t, types.KeyBinding{types.VK_LMENU, types.VK_1}, binding.Binding)
+
+	matcher.SetModes(Mode{
+		Name:     "resize",
+		Bindings: []KeyBindingAction{NewBindingAction([]types.VirtualKey{types.VK_1}, noop, false)},
+	})
+	require.NoError(t, matcher.EnterMode("resize"))
+	binding, ok = matcher.Lookup([]types.VirtualKey{types.VK_1})
+	require.True(t, ok)
+	assert.Equal(t, types.KeyBinding{types.VK_1}, binding.Binding)
+}
+
+// TestBindingIndexLookupAllocations verifies that looking up bindings reuses its buffers.
+func TestBindingIndexLookupAllocations(t *testing.T) {
+	index := newBindingIndex(benchmarkBindings(1000))
+	keys := []types.VirtualKey{types.VK_LMENU, types.VK_LSHIFT, types.VK_K}
+	buf := new(lookupBuffers)
+	require.NotEmpty(t, index.combinations(keys, buf))
+	allocs := testing.AllocsPerRun(100, func() {
+		index.combinations(keys, buf)
+		index.sequenceStarts(keys, buf)
+	})
+	assert.Zero(t, allocs)
+}
+
+// benchmarkBindings creates n bindings of one or two modifiers and a letter, digit or function key,
+// spread over many key sets like a large real configuration.
+func benchmarkBindings(n int) []KeyBindingAction {
+	r := rand.New(rand.NewPCG(1, 2))
+	modifiers := []types.VirtualKey{
+		types.VK_MENU, types.VK_LMENU, types.VK_CONTROL, types.VK_RCONTROL, types.VK_SHIFT, types.VK_WIN, types.VK_LWIN,
+	}
+	var keys []types.VirtualKey
+	for vk := types.VK_0; vk <= types.VK_Z; vk++ {
+		keys = append(keys, vk)
+	}
+	for vk := types.VK_F1; vk <= types.VK_F24; vk++ {
+		keys = append(keys, vk)
+	}
+	noop := func(context.Context, ActionContext) error { return nil }
+	bindings := make([]KeyBindingAction, n)
+	for i := range bindings {
+		combo := append(randomKeys(r, modifiers, 2), keys[r.IntN(len(keys))])
+		bindings[i] = NewBindingAction(combo, noop, false)
+		if i%10 == 0 {
+			bindings[i] = NewSequenceBindingAction([]types.KeyBinding{combo, {keys[r.IntN(len(keys))]}}, noop, true)
+		}
+	}
+	return bindings
+}
+
+// BenchmarkMatcherMatch measures matching a release against tables of growing size;
+// the time per match should only grow with the bindings on the held keys.
+func BenchmarkMatcherMatch(b *testing.B) {
+	for _, n := range []int{10, 1000, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			matcher := NewMatcher()
+			matcher.SetBindings(benchmarkBindings(n)...)
+			event := KeyEvent{
+				KeyCode:     types.VK_K,
+				PressedKeys: []types.VirtualKey{types.VK_LMENU, types.VK_LSHIFT, types.VK_K},
+			}
+			b.ResetTimer()
+			for range b.N {
+				matcher.Match(event)
+			}
+		})
+	}
+}
+
+// BenchmarkMatcherPress measures starting a key sequence against tables of growing size.
+func BenchmarkMatcherPress(b *testing.B) {
+	for _, n := range []int{10, 1000, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			matcher := NewMatcher()
+			matcher.SetBindings(benchmarkBindings(n)...)
+			keys := []types.VirtualKey{types.VK_RWIN, types.VK_F5}
+			b.ResetTimer()
+			for range b.N {
+				matcher.Press(types.VK_F5, keys)
+				matcher.Cancel()
+			}
+		})
+	}
+}
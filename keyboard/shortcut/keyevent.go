@@ -6,6 +6,7 @@ type KeyEvent struct {
 	PressedKeys []types.VirtualKey // Current state of all pressed keys
 	KeyCode     types.VirtualKey   // The key involved in this event
 	KeyDown     bool                // Whether this is a key press (true) or release (false)
+	Repeat      bool                // Whether the press is an auto-repeat of a key that is already held
 }
 
 func (ke *KeyEvent) CurrentState() []types.VirtualKey {
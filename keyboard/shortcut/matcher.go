@@ -1,29 +1,136 @@
 package shortcut
 
+import (
+	"sync"
+	"time"
 
-// Matcher handles matching key events to registered shortcuts
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// DefaultSequenceTimeout is how long a started key sequence waits for its next step.
+const DefaultSequenceTimeout = time.Second
+
+// Matcher handles matching key events to registered shortcuts.
+// It holds a table of bindings per mode; only the table of the active mode is matched.
 type Matcher struct {
-	bindings []KeyBindingAction
+	mu        sync.RWMutex
+	tables    map[string]*Mode  // Binding tables by mode name, including DefaultMode
+	mode      string            // Name of the active table
+	modeTimer clock.Timer       // Leaves a mode with a timeout after inactivity, nil when not running
+	modeGen   uint64            // Incremented whenever the mode timeout restarts, so a stale timer is ignored
+	onMode    func(mode string) // Notified when the active mode changes
+
+	windows WindowProvider // Reports the foreground window for bindings scoped to windows
+
+	clock           clock.Clock
+	sequenceTimeout time.Duration
+	pending         *pendingSequence                                           // Sequence in progress, nil when idle
+	onPending       func(steps []types.KeyBinding, modifiers *types.Modifiers) // Notified when a sequence advances or ends
 }
 
 // NewMatcher creates a new Matcher instance
 func NewMatcher() *Matcher {
+	return NewMatcherWithClock(clock.New())
+}
+
+// NewMatcherWithClock creates a Matcher that times out key sequences on clk.
+func NewMatcherWithClock(clk clock.Clock) *Matcher {
 	return &Matcher{
-		bindings: make([]KeyBindingAction, 0),
+		tables:          map[string]*Mode{DefaultMode: {Name: DefaultMode, index: newBindingIndex(nil)}},
+		mode:            DefaultMode,
+		windows:         noWindows{},
+		clock:           clk,
+		sequenceTimeout: DefaultSequenceTimeout,
 	}
 }
 
-// AddBindings registers new key binding actions
+// AddBindings registers new key binding actions in the default table
 func (m *Matcher) AddBindings(bindings ...KeyBindingAction) {
-	m.bindings = append(m.bindings, bindings...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	table := m.tables[DefaultMode]
+	table.Bindings = append(table.Bindings, bindings...)
+	table.index = newBindingIndex(table.Bindings)
 }
 
-// Match checks if the event matches any registered shortcut
+// SetBindings atomically replaces all key binding actions of the default table.
+// Events matched concurrently see either the old or the new set, never a mix.
+// A sequence in progress is cancelled since its bindings may be gone.
+func (m *Matcher) SetBindings(bindings ...KeyBindingAction) {
+	replacement := make([]KeyBindingAction, len(bindings))
+	copy(replacement, bindings)
+
+	m.mu.Lock()
+	m.tables[DefaultMode] = &Mode{Name: DefaultMode, Bindings: replacement, index: newBindingIndex(replacement)}
+	cancelled := m.clearPending()
+	m.mu.Unlock()
+	if cancelled {
+		m.notifyPending(nil, nil)
+	}
+}
+
+// Match checks if the event matches any registered shortcut.
+// When several bindings match, bindings scoped to the foreground window win over global ones and
+// side-specific modifiers win over generic ones ("LAlt+1" over "Alt+1"); among equal bindings the first registered wins.
 func (m *Matcher) Match(event KeyEvent) (*KeyBindingAction, bool) {
-	for _, binding := range m.bindings {
-		if binding.Match(event) {
-			return &binding, true
+	return m.best(event.PressedKeys, func(binding *KeyBindingAction) bool {
+		return binding.Match(event)
+	})
+}
+
+// Lookup returns the binding for exactly the given keys regardless of when it triggers,
+// using the same precedence as Match.
+func (m *Matcher) Lookup(keys []types.VirtualKey) (*KeyBindingAction, bool) {
+	return m.best(keys, func(binding *KeyBindingAction) bool {
+		return !binding.IsSequence() && binding.Binding.Match(keys)
+	})
+}
+
+// LookupTrigger returns the binding for exactly the given keys that fires with the given kind of trigger,
+// using the same precedence as Match.
+func (m *Matcher) LookupTrigger(keys []types.VirtualKey, kind TriggerKind) (*KeyBindingAction, bool) {
+	return m.best(keys, func(binding *KeyBindingAction) bool {
+		return !binding.IsSequence() && binding.Trigger.Kind == kind && binding.Binding.Match(keys)
+	})
+}
+
+// LookupRepeat returns the binding an auto-repeat of the given keys may fire again: the binding for
+// exactly those keys that fires on press or release, using the same precedence as Match.
+func (m *Matcher) LookupRepeat(keys []types.VirtualKey) (*KeyBindingAction, bool) {
+	return m.best(keys, func(binding *KeyBindingAction) bool {
+		kind := binding.Trigger.Kind
+		return !binding.IsSequence() && (kind == TriggerUp || kind == TriggerDown) && binding.Binding.Match(keys)
+	})
+}
+
+// best returns a copy of the most specific combination binding for exactly keys accepted by match.
+// Only the bindings indexed under keys are checked, in registration order.
+func (m *Matcher) best(keys []types.VirtualKey, match func(*KeyBindingAction) bool) (*KeyBindingAction, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var best *KeyBindingAction
+	table := m.tables[m.mode]
+	applies := m.windowFilter()
+	buf := getLookupBuffers()
+	defer putLookupBuffers(buf)
+	for _, i := range table.index.combinations(keys, buf) {
+		binding := &table.Bindings[i]
+		if !match(binding) || !applies(binding) {
+			continue
+		}
+		if best == nil || binding.outranks(best) {
+			best = binding
 		}
 	}
-	return nil, false
+	if best == nil {
+		return nil, false
+	}
+	result := *best
+	return &result, true
+}
+
+// active returns the bindings of the active mode; m.mu must be held.
+func (m *Matcher) active() []KeyBindingAction {
+	return m.tables[m.mode].Bindings
 }
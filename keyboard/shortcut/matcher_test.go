@@ -1,60 +1,132 @@
 package shortcut
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"wincuts/keyboard/types"
 )
 
-// TestMatcherMatch_ExecutesActionOnMatch verifies that the Matcher executes the action when a matching key event is received.
-func TestMatcherMatch_ExecutesActionOnMatch(t *testing.T) {
-	assert := assert.New(t)
+// TestMatcherMatch verifies which binding, if any, the Matcher selects for the pressed keys.
+// Match only selects the binding; executing it is left to the Service.
+func TestMatcherMatch(t *testing.T) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	binding := func(keys ...types.VirtualKey) KeyBindingAction {
+		return NewBindingAction(keys, noop, false)
+	}
 
-	var executed bool
-	action := func() error {
-		executed = true
-		return nil
+	tests := []struct {
+		name     string
+		bindings []KeyBindingAction
+		pressed  []types.VirtualKey
+		want     types.KeyBinding // nil when no binding should match
+	}{
+		{
+			name:     "exact side-specific match",
+			bindings: []KeyBindingAction{binding(types.VK_LMENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_1},
+			want:     types.KeyBinding{types.VK_LMENU, types.VK_1},
+		},
+		{
+			name:     "side-specific binding ignores the other side",
+			bindings: []KeyBindingAction{binding(types.VK_LMENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_RMENU, types.VK_1},
+		},
+		{
+			name:     "different key combination",
+			bindings: []KeyBindingAction{binding(types.VK_LMENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_LSHIFT},
+		},
+		{
+			name:     "generic Alt matches left Alt",
+			bindings: []KeyBindingAction{binding(types.VK_MENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_1},
+			want:     types.KeyBinding{types.VK_MENU, types.VK_1},
+		},
+		{
+			name:     "generic Alt matches right Alt",
+			bindings: []KeyBindingAction{binding(types.VK_MENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_1, types.VK_RMENU},
+			want:     types.KeyBinding{types.VK_MENU, types.VK_1},
+		},
+		{
+			name:     "generic Win matches either Windows key",
+			bindings: []KeyBindingAction{binding(types.VK_WIN, types.VK_SHIFT, types.VK_LEFT)},
+			pressed:  []types.VirtualKey{types.VK_RWIN, types.VK_LSHIFT, types.VK_LEFT},
+			want:     types.KeyBinding{types.VK_WIN, types.VK_SHIFT, types.VK_LEFT},
+		},
+		{
+			name:     "generic modifier is still required",
+			bindings: []KeyBindingAction{binding(types.VK_CONTROL, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_1},
+		},
+		{
+			name:     "extra modifier prevents a generic match",
+			bindings: []KeyBindingAction{binding(types.VK_MENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_LSHIFT, types.VK_1},
+		},
+		{
+			name:     "both sides held satisfy one generic modifier",
+			bindings: []KeyBindingAction{binding(types.VK_SHIFT, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LSHIFT, types.VK_RSHIFT, types.VK_1},
+			want:     types.KeyBinding{types.VK_SHIFT, types.VK_1},
+		},
+		{
+			name:     "exact side wins over generic registered first",
+			bindings: []KeyBindingAction{binding(types.VK_MENU, types.VK_1), binding(types.VK_LMENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_1},
+			want:     types.KeyBinding{types.VK_LMENU, types.VK_1},
+		},
+		{
+			name:     "generic catches the side without an exact binding",
+			bindings: []KeyBindingAction{binding(types.VK_MENU, types.VK_1), binding(types.VK_LMENU, types.VK_1)},
+			pressed:  []types.VirtualKey{types.VK_RMENU, types.VK_1},
+			want:     types.KeyBinding{types.VK_MENU, types.VK_1},
+		},
+		{
+			name:     "first binding wins among equally specific matches",
+			bindings: []KeyBindingAction{binding(types.VK_LMENU, types.VK_1), binding(types.VK_1, types.VK_LMENU)},
+			pressed:  []types.VirtualKey{types.VK_LMENU, types.VK_1},
+			want:     types.KeyBinding{types.VK_LMENU, types.VK_1},
+		},
 	}
-	keys := []types.VirtualKey{types.VK_LMENU, types.VK_1}
-	binding := NewBindingAction(keys, action, false)
 
-	matcher := NewMatcher()
-	matcher.AddBindings(binding)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher := NewMatcher()
+			matcher.AddBindings(tt.bindings...)
 
-	// Create a key event that matches the binding. Since NewBindingAction sets onKeyDown = false by default,
-	// the event's KeyDown should be false and the PressedKeys should match keys.
-	event := KeyEvent{
-		KeyDown:     false,
-		PressedKeys: keys,
+			// NewBindingAction fires on key release, so the event has KeyDown false.
+			match, found := matcher.Match(KeyEvent{KeyDown: false, PressedKeys: tt.pressed})
+			if tt.want == nil {
+				assert.False(t, found, "Expected no binding to match")
+				return
+			}
+			require.True(t, found, "Expected a binding to match")
+			assert.Equal(t, tt.want, match.Binding)
+		})
 	}
-
-	matcher.Match(event)
-	assert.True(executed, "Expected binding action to be executed for matching event")
 }
 
-// TestMatcherMatch_DoesNotExecuteOnNoMatch verifies that the Matcher does not execute the action when a non-matching event is received.
-func TestMatcherMatch_DoesNotExecuteOnNoMatch(t *testing.T) {
+// TestMatcherSetBindings verifies that SetBindings replaces the previous bindings instead of adding to them.
+func TestMatcherSetBindings(t *testing.T) {
 	assert := assert.New(t)
 
-	var executed bool
-	action := func() error {
-		executed = true
-		return nil
-	}
-	keys := []types.VirtualKey{types.VK_LMENU, types.VK_1}
-	binding := NewBindingAction(keys, action, false)
+	oldKeys := []types.VirtualKey{types.VK_LMENU, types.VK_1}
+	newKeys := []types.VirtualKey{types.VK_LMENU, types.VK_2}
+	noop := func(context.Context, ActionContext) error { return nil }
 
 	matcher := NewMatcher()
-	matcher.AddBindings(binding)
+	matcher.AddBindings(NewBindingAction(oldKeys, noop, false))
+	matcher.SetBindings(NewBindingAction(newKeys, noop, false))
 
-	// Create a key event that does not match (different key combination).
-	event := KeyEvent{
-		KeyDown:     false,
-		PressedKeys: []types.VirtualKey{types.VK_LMENU, types.VK_LSHIFT},
-	}
+	_, found := matcher.Match(KeyEvent{PressedKeys: oldKeys})
+	assert.False(found, "Replaced binding should no longer match")
 
-	matcher.Match(event)
-	assert.False(executed, "Expected binding action not to be executed for non-matching event")
+	binding, found := matcher.Match(KeyEvent{PressedKeys: newKeys})
+	assert.True(found, "New binding should match")
+	assert.True(binding.Binding.Match(newKeys))
 }
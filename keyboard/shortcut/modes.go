@@ -0,0 +1,141 @@
+package shortcut
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMode names the binding table that is active outside of any mode.
+const DefaultMode = "default"
+
+// Mode is a named table of bindings that replaces the default bindings while it is active,
+// like the binding modes of the i3 window manager.
+type Mode struct {
+	Name     string
+	Timeout  time.Duration // Leave the mode after this long without a key press; zero stays until ExitMode
+	Bindings []KeyBindingAction
+
+	index *bindingIndex // Finds Bindings by their keys; rebuilt whenever Bindings change
+}
+
+// SetModes atomically replaces every mode other than the default table.
+// If the active mode no longer exists the default bindings become active again.
+func (m *Matcher) SetModes(modes ...Mode) {
+	m.mu.Lock()
+	tables := map[string]*Mode{DefaultMode: m.tables[DefaultMode]}
+	for _, mode := range modes {
+		mode.Bindings = append([]KeyBindingAction(nil), mode.Bindings...)
+		mode.index = newBindingIndex(mode.Bindings)
+		tables[mode.Name] = &mode
+	}
+	m.tables = tables
+	cancelled := m.clearPending()
+	changed := false
+	if _, ok := tables[m.mode]; !ok {
+		changed = m.switchMode(DefaultMode)
+	}
+	m.mu.Unlock()
+
+	if cancelled {
+		m.notifyPending(nil, nil)
+	}
+	if changed {
+		m.notifyMode(DefaultMode)
+	}
+}
+
+// EnterMode activates the bindings of the named mode.
+func (m *Matcher) EnterMode(name string) error {
+	m.mu.Lock()
+	if _, ok := m.tables[name]; !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("unknown mode %q", name)
+	}
+	cancelled := m.clearPending()
+	changed := m.switchMode(name)
+	m.mu.Unlock()
+
+	if cancelled {
+		m.notifyPending(nil, nil)
+	}
+	if changed {
+		m.notifyMode(name)
+	}
+	return nil
+}
+
+// ExitMode returns to the default bindings.
+func (m *Matcher) ExitMode() {
+	if err := m.EnterMode(DefaultMode); err != nil {
+		panic(err) // The default table always exists
+	}
+}
+
+// Mode returns the name of the active mode.
+func (m *Matcher) Mode() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mode
+}
+
+// OnModeChange registers a function called with the name of the mode that becomes active.
+func (m *Matcher) OnModeChange(fn func(mode string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onMode = fn
+}
+
+// switchMode makes name the active mode with m.mu held, restarting its timeout, and reports whether the mode changed.
+func (m *Matcher) switchMode(name string) bool {
+	changed := m.mode != name
+	m.mode = name
+	m.touchMode()
+	return changed
+}
+
+// touchMode restarts the inactivity timeout of the active mode; m.mu must be held.
+func (m *Matcher) touchMode() {
+	if m.modeTimer != nil {
+		m.modeTimer.Stop()
+		m.modeTimer = nil
+	}
+	m.modeGen++
+	timeout := m.tables[m.mode].Timeout
+	if m.mode == DefaultMode || timeout <= 0 {
+		return
+	}
+	gen := m.modeGen
+	m.modeTimer = m.clock.AfterFunc(timeout, func() {
+		m.expireMode(gen)
+	})
+}
+
+// expireMode leaves the active mode if the timeout started as generation gen is still current.
+func (m *Matcher) expireMode(gen uint64) {
+	m.mu.Lock()
+	expired := m.modeGen == gen
+	cancelled := false
+	if expired {
+		m.modeTimer = nil
+		cancelled = m.clearPending()
+		m.mode = DefaultMode
+	}
+	m.mu.Unlock()
+
+	if cancelled {
+		m.notifyPending(nil, nil)
+	}
+	if expired {
+		m.notifyMode(DefaultMode)
+	}
+}
+
+// notifyMode calls the OnModeChange function; it must be called without m.mu held.
+func (m *Matcher) notifyMode(mode string) {
+	m.mu.RLock()
+	fn := m.onMode
+	m.mu.RUnlock()
+	if fn != nil {
+		fn(mode)
+	}
+}
@@ -0,0 +1,91 @@
+package shortcut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// newModeMatcher creates a Matcher with Alt+R in the default table and a "resize" mode binding the bare H key.
+func newModeMatcher(timeout time.Duration) (*Matcher, *clock.Fake, *[]string) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	fake := clock.NewFake(time.Time{})
+	matcher := NewMatcherWithClock(fake)
+	matcher.AddBindings(NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_R}, noop, true))
+	matcher.SetModes(Mode{
+		Name:     "resize",
+		Timeout:  timeout,
+		Bindings: []KeyBindingAction{NewBindingAction([]types.VirtualKey{types.VK_H}, noop, true)},
+	})
+
+	var modes []string
+	matcher.OnModeChange(func(mode string) {
+		modes = append(modes, mode)
+	})
+	return matcher, fake, &modes
+}
+
+// TestMatcherModes verifies that only the bindings of the active mode are matched.
+func TestMatcherModes(t *testing.T) {
+	assert := assert.New(t)
+	matcher, _, modes := newModeMatcher(0)
+	altR := []types.VirtualKey{types.VK_LMENU, types.VK_R}
+	h := []types.VirtualKey{types.VK_H}
+
+	assert.Equal(DefaultMode, matcher.Mode())
+	_, found := matcher.Lookup(h)
+	assert.False(found, "Mode bindings should not apply outside the mode")
+	_, found = matcher.Lookup(altR)
+	assert.True(found)
+
+	require.NoError(t, matcher.EnterMode("resize"))
+	assert.Equal("resize", matcher.Mode())
+	_, found = matcher.Match(KeyEvent{PressedKeys: h})
+	assert.True(found, "Bare keys should match in the mode")
+	_, found = matcher.Lookup(altR)
+	assert.False(found, "Default bindings should not apply in a mode")
+
+	matcher.ExitMode()
+	assert.Equal(DefaultMode, matcher.Mode())
+	assert.Equal([]string{"resize", DefaultMode}, *modes)
+
+	assert.Error(matcher.EnterMode("move"), "Unknown modes cannot be entered")
+	assert.Equal(DefaultMode, matcher.Mode())
+}
+
+// TestMatcherModeTimeout verifies that a mode with a timeout is left after a period without key presses.
+func TestMatcherModeTimeout(t *testing.T) {
+	assert := assert.New(t)
+	matcher, fake, modes := newModeMatcher(5 * time.Second)
+
+	require.NoError(t, matcher.EnterMode("resize"))
+	fake.Advance(4 * time.Second)
+	matcher.Press(types.VK_H, []types.VirtualKey{types.VK_H})
+	fake.Advance(4 * time.Second)
+	assert.Equal("resize", matcher.Mode(), "A key press should restart the timeout")
+
+	fake.Advance(time.Second)
+	assert.Equal(DefaultMode, matcher.Mode())
+	assert.Equal([]string{"resize", DefaultMode}, *modes)
+	assert.Zero(fake.Pending(), "No timer should be left running")
+}
+
+// TestMatcherSetModesLeavesRemovedMode verifies that reloading without the active mode returns to the default bindings.
+func TestMatcherSetModesLeavesRemovedMode(t *testing.T) {
+	matcher, fake, modes := newModeMatcher(5 * time.Second)
+
+	require.NoError(t, matcher.EnterMode("resize"))
+	matcher.SetModes()
+	assert.Equal(t, DefaultMode, matcher.Mode())
+	assert.Equal(t, []string{"resize", DefaultMode}, *modes)
+	assert.Zero(t, fake.Pending())
+
+	_, found := matcher.Lookup([]types.VirtualKey{types.VK_LMENU, types.VK_R})
+	assert.True(t, found, "Default bindings should survive a mode reload")
+}
@@ -0,0 +1,63 @@
+package shortcut
+
+import (
+	"fmt"
+	"time"
+)
+
+// RepeatKind names what a binding does while Windows auto-repeats its held keys.
+type RepeatKind int
+
+const (
+	RepeatIgnore   RepeatKind = iota // Fire once per press, the default
+	RepeatFire                       // Fire again on every auto-repeat
+	RepeatThrottle                   // Fire on auto-repeats at most once per Interval
+)
+
+// Repeat is the auto-repeat policy of a binding. It applies to bindings triggered on press or release;
+// once a held binding has fired on a repeat, its release fires nothing.
+type Repeat struct {
+	Kind     RepeatKind
+	Interval time.Duration // Shortest time between two firings with RepeatThrottle
+}
+
+func (r Repeat) String() string {
+	switch r.Kind {
+	case RepeatFire:
+		return "fire"
+	case RepeatThrottle:
+		return fmt.Sprintf("throttle(%s)", r.Interval)
+	}
+	return "ignore"
+}
+
+// repeat returns the binding fired by the auto-repeat in event, if its repeat policy allows it.
+func (s *Service) repeat(event KeyEvent) (*KeyBindingAction, bool) {
+	binding, found := s.matcher.LookupRepeat(event.PressedKeys)
+	if !found || binding.Repeat.Kind == RepeatIgnore {
+		return nil, false
+	}
+
+	now := s.matcher.clock.Now()
+	s.timingMu.Lock()
+	throttled := binding.Repeat.Kind == RepeatThrottle && s.repeatKey == event.KeyCode &&
+		now.Sub(s.repeatAt) < binding.Repeat.Interval
+	if !throttled {
+		s.repeatKey, s.repeatAt = event.KeyCode, now
+	}
+	s.timingMu.Unlock()
+	if throttled {
+		return nil, false
+	}
+
+	s.cancelHold()
+	s.consume(event.KeyCode)
+	return binding, true
+}
+
+// resetRepeat starts the throttle over for a new press.
+func (s *Service) resetRepeat() {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	s.repeatKey, s.repeatAt = 0, time.Time{}
+}
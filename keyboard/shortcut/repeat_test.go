@@ -0,0 +1,64 @@
+package shortcut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"wincuts/keyboard/types"
+)
+
+// newRepeatService creates a service with a single Alt+Right binding.
+func newRepeatService(trigger Trigger, repeat Repeat) *triggerService {
+	binding := NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_RIGHT}, func(context.Context, ActionContext) error { return nil }, true)
+	binding.Trigger = trigger
+	binding.Repeat = repeat
+	return newTestService(binding)
+}
+
+// holdRight presses Alt+Right and auto-repeats Right every 30ms for the given number of repeats, then releases both.
+func (s *triggerService) holdRight(repeats int) {
+	s.press(types.VK_LMENU)
+	s.press(types.VK_RIGHT)
+	for range repeats {
+		s.clock.Advance(30 * time.Millisecond)
+		s.autoRepeat(types.VK_RIGHT)
+	}
+	s.release(types.VK_RIGHT)
+	s.release(types.VK_LMENU)
+}
+
+// TestRepeatPolicies verifies how often a held binding fires under each repeat policy.
+func TestRepeatPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger Trigger
+		repeat  Repeat
+		want    int
+	}{
+		{name: "ignore fires once on release", repeat: Repeat{}, want: 1},
+		{name: "ignore fires once on press", trigger: down, repeat: Repeat{}, want: 1},
+		{name: "fire replaces the release", repeat: Repeat{Kind: RepeatFire}, want: 10},
+		{name: "fire follows the press", trigger: down, repeat: Repeat{Kind: RepeatFire}, want: 11},
+		{name: "throttle", repeat: Repeat{Kind: RepeatThrottle, Interval: 100 * time.Millisecond}, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newRepeatService(tt.trigger, tt.repeat)
+			s.holdRight(10)
+			assert.Len(t, s.fired(), tt.want)
+		})
+	}
+}
+
+// TestRepeatThrottleRestartsOnPress verifies that a new press is not throttled by the repeats of the previous one.
+func TestRepeatThrottleRestartsOnPress(t *testing.T) {
+	s := newRepeatService(down, Repeat{Kind: RepeatThrottle, Interval: time.Second})
+
+	s.holdRight(1)
+	s.holdRight(1)
+	assert.Len(t, s.fired(), 4, "Each press and its first repeat should fire")
+}
@@ -0,0 +1,215 @@
+package shortcut
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// pendingSequence is a key sequence whose first steps have been pressed.
+type pendingSequence struct {
+	candidates []int              // Indices of the bindings whose steps so far match
+	steps      []types.KeyBinding // Steps pressed so far, as written in the first candidate
+	modifiers  *types.Modifiers   // Names the custom modifiers of steps, from the first candidate
+	timer      clock.Timer        // Cancels the sequence when the next step does not follow in time
+}
+
+// PressResult is the outcome of a key press for the key sequences of a Matcher.
+type PressResult struct {
+	Consumed  bool              // The press belongs to a sequence and must not be matched as a combination
+	Completed *KeyBindingAction // The sequence the press completed, if any
+}
+
+// SetSequenceTimeout sets how long a started sequence waits for its next step.
+func (m *Matcher) SetSequenceTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sequenceTimeout = timeout
+}
+
+// OnPendingChange registers a function called with the steps pressed so far whenever a sequence
+// advances, and with nil when it completes, times out or is cancelled. The table names the custom
+// modifiers of the steps, see FormatSteps.
+func (m *Matcher) OnPendingChange(fn func(steps []types.KeyBinding, modifiers *types.Modifiers)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onPending = fn
+}
+
+// Pending returns the steps of the sequence in progress, or nil when no sequence is pending.
+func (m *Matcher) Pending() []types.KeyBinding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.pending == nil {
+		return nil
+	}
+	return slices.Clone(m.pending.steps)
+}
+
+// Cancel abandons the sequence in progress, if any.
+func (m *Matcher) Cancel() {
+	m.mu.Lock()
+	cancelled := m.clearPending()
+	m.mu.Unlock()
+	if cancelled {
+		m.notifyPending(nil, nil)
+	}
+}
+
+// Press feeds the press of key, with keys being every key held including it, to the key sequences.
+// A press that starts or advances a sequence is consumed, as is the one completing it. While a sequence
+// is pending, Escape cancels it and any other key that does not continue it cancels it and is then
+// treated as a fresh press. Steps after the first ignore held keys they do not name other than
+// modifiers, so Alt+W followed by 3 also works while W or Alt are still down.
+func (m *Matcher) Press(key types.VirtualKey, keys []types.VirtualKey) PressResult {
+	m.mu.Lock()
+	m.touchMode() // Any key press counts as activity in a mode with a timeout
+	result, changed := m.press(key, keys)
+	var steps []types.KeyBinding
+	var modifiers *types.Modifiers
+	if m.pending != nil {
+		steps, modifiers = slices.Clone(m.pending.steps), m.pending.modifiers
+	}
+	m.mu.Unlock()
+
+	if changed {
+		m.notifyPending(steps, modifiers)
+	}
+	return result
+}
+
+// press implements Press with m.mu held and reports whether the pending steps changed.
+func (m *Matcher) press(key types.VirtualKey, keys []types.VirtualKey) (PressResult, bool) {
+	if m.pending == nil {
+		return m.advance(nil, key, keys), m.pending != nil
+	}
+
+	result := m.advance(m.pending.candidates, key, keys)
+	if result.Consumed {
+		return result, true
+	}
+
+	// The press does not continue the sequence: cancel it and start over.
+	m.clearPending()
+	if key == types.VK_ESCAPE {
+		return PressResult{Consumed: true}, true
+	}
+	return m.advance(nil, key, keys), true
+}
+
+// advance matches the press against the next step of the candidate bindings, or of the sequence
+// bindings of the active mode starting with keys when candidates is nil. A completed sequence wins over one that needs more steps.
+func (m *Matcher) advance(candidates []int, key types.VirtualKey, keys []types.VirtualKey) PressResult {
+	depth := 0
+	if m.pending != nil {
+		depth = len(m.pending.steps)
+	}
+	bindings := m.active()
+	if candidates == nil {
+		buf := getLookupBuffers()
+		defer putLookupBuffers(buf)
+		candidates = m.tables[m.mode].index.sequenceStarts(keys, buf)
+	}
+
+	var completed *KeyBindingAction
+	var next []int
+	applies := m.windowFilter()
+	for _, i := range candidates {
+		binding := &bindings[i]
+		steps := binding.Steps()
+		if !stepMatches(steps[depth], key, keys, depth > 0) || !applies(binding) {
+			continue
+		}
+		if depth == len(steps)-1 {
+			if completed == nil || binding.outranks(completed) {
+				completed = binding
+			}
+		} else {
+			next = append(next, i)
+		}
+	}
+
+	switch {
+	case completed != nil:
+		m.clearPending()
+		result := *completed
+		return PressResult{Consumed: true, Completed: &result}
+	case len(next) > 0:
+		first := &bindings[next[0]]
+		m.startPending(next, first.Steps()[:depth+1], first.Modifiers)
+		return PressResult{Consumed: true}
+	default:
+		return PressResult{}
+	}
+}
+
+// startPending records the candidates of a sequence in progress and restarts its timeout.
+func (m *Matcher) startPending(candidates []int, steps []types.KeyBinding, modifiers *types.Modifiers) {
+	m.clearPending()
+	pending := &pendingSequence{candidates: candidates, steps: steps, modifiers: modifiers}
+	pending.timer = m.clock.AfterFunc(m.sequenceTimeout, func() {
+		m.expire(pending)
+	})
+	m.pending = pending
+}
+
+// expire cancels the sequence if it is still the one the timer was started for.
+func (m *Matcher) expire(pending *pendingSequence) {
+	m.mu.Lock()
+	expired := m.pending == pending
+	if expired {
+		m.pending = nil
+	}
+	m.mu.Unlock()
+	if expired {
+		m.notifyPending(nil, nil)
+	}
+}
+
+// clearPending abandons the sequence in progress with m.mu held and reports whether there was one.
+func (m *Matcher) clearPending() bool {
+	if m.pending == nil {
+		return false
+	}
+	m.pending.timer.Stop()
+	m.pending = nil
+	return true
+}
+
+// notifyPending calls the OnPendingChange function; it must be called without m.mu held.
+func (m *Matcher) notifyPending(steps []types.KeyBinding, modifiers *types.Modifiers) {
+	m.mu.RLock()
+	fn := m.onPending
+	m.mu.RUnlock()
+	if fn != nil {
+		fn(steps, modifiers)
+	}
+}
+
+// stepMatches reports whether pressing key with keys held presses step.
+// Lenient matching, used after the first step, ignores held keys other than modifiers
+// and ignores modifiers the step does not name.
+func stepMatches(step types.KeyBinding, key types.VirtualKey, keys []types.VirtualKey, lenient bool) bool {
+	if lenient {
+		keys = slices.DeleteFunc(slices.Clone(keys), func(held types.VirtualKey) bool {
+			if held == key {
+				return false
+			}
+			return !held.IsModifier() || !slices.ContainsFunc(step, held.Satisfies)
+		})
+	}
+	return step.Match(keys)
+}
+
+// FormatSteps formats the steps of a sequence for display, e.g. "MENU + W, 3".
+// Custom modifiers are named by the table, which may be nil if the steps have none.
+func FormatSteps(steps []types.KeyBinding, modifiers *types.Modifiers) string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = modifiers.FormatBinding(step)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,224 @@
+package shortcut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// TestMatcherPress verifies how presses start, advance, complete and cancel key sequences.
+func TestMatcherPress(t *testing.T) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	sequence := func(steps ...types.KeyBinding) KeyBindingAction {
+		return NewSequenceBindingAction(steps, noop, true)
+	}
+	altW := types.KeyBinding{types.VK_MENU, types.VK_W}
+	three := types.KeyBinding{types.VK_3}
+	four := types.KeyBinding{types.VK_4}
+
+	type press struct {
+		wait         time.Duration      // Time to let pass before the press
+		keys         []types.VirtualKey // Held keys; the last one is the key being pressed
+		wantConsumed bool
+		wantDone     types.KeyBinding // Final step of the completed sequence, nil if none
+	}
+
+	tests := []struct {
+		name        string
+		bindings    []KeyBindingAction
+		presses     []press
+		wantPending []types.KeyBinding
+	}{
+		{
+			name:     "two steps complete a sequence",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_3}, wantConsumed: true, wantDone: three},
+			},
+		},
+		{
+			name:     "modifier still held during the next step",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W, types.VK_3}, wantConsumed: true, wantDone: three},
+			},
+		},
+		{
+			name:     "first step is pending",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_RMENU, types.VK_W}, wantConsumed: true},
+			},
+			wantPending: []types.KeyBinding{altW},
+		},
+		{
+			name:     "first step needs its modifiers",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_W}},
+				{keys: []types.VirtualKey{types.VK_3}},
+			},
+		},
+		{
+			name:     "timeout cancels the sequence",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{wait: time.Second, keys: []types.VirtualKey{types.VK_3}},
+			},
+		},
+		{
+			name:     "next step just before the timeout",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{wait: time.Second - time.Millisecond, keys: []types.VirtualKey{types.VK_3}, wantConsumed: true, wantDone: three},
+			},
+		},
+		{
+			name:     "escape cancels and is consumed",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_ESCAPE}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_3}},
+			},
+		},
+		{
+			name:     "escape passes without a pending sequence",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_ESCAPE}},
+			},
+		},
+		{
+			name:     "other key cancels and is not consumed",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_5}},
+				{keys: []types.VirtualKey{types.VK_3}},
+			},
+		},
+		{
+			name:     "cancelling key can start another sequence",
+			bindings: []KeyBindingAction{sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+			},
+			wantPending: []types.KeyBinding{altW},
+		},
+		{
+			name:     "sequences share a first step",
+			bindings: []KeyBindingAction{sequence(altW, three), sequence(altW, four)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_4}, wantConsumed: true, wantDone: four},
+			},
+		},
+		{
+			name:     "three steps",
+			bindings: []KeyBindingAction{sequence(altW, three, four)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{wait: 900 * time.Millisecond, keys: []types.VirtualKey{types.VK_3}, wantConsumed: true},
+				{wait: 900 * time.Millisecond, keys: []types.VirtualKey{types.VK_4}, wantConsumed: true, wantDone: four},
+			},
+		},
+		{
+			name:     "shorter sequence completes first",
+			bindings: []KeyBindingAction{sequence(altW, three, four), sequence(altW, three)},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}, wantConsumed: true},
+				{keys: []types.VirtualKey{types.VK_3}, wantConsumed: true, wantDone: three},
+			},
+		},
+		{
+			name: "combinations are left to Match",
+			bindings: []KeyBindingAction{
+				NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_W}, noop, true),
+			},
+			presses: []press{
+				{keys: []types.VirtualKey{types.VK_LMENU, types.VK_W}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := clock.NewFake(time.Time{})
+			matcher := NewMatcherWithClock(fake)
+			matcher.AddBindings(tt.bindings...)
+
+			for i, p := range tt.presses {
+				fake.Advance(p.wait)
+				result := matcher.Press(p.keys[len(p.keys)-1], p.keys)
+				assert.Equal(t, p.wantConsumed, result.Consumed, "press %d consumed", i)
+				if p.wantDone == nil {
+					assert.Nil(t, result.Completed, "press %d should not complete a sequence", i)
+				} else if assert.NotNil(t, result.Completed, "press %d should complete a sequence", i) {
+					assert.Equal(t, p.wantDone, result.Completed.Binding)
+				}
+			}
+			assert.Equal(t, tt.wantPending, matcher.Pending())
+		})
+	}
+}
+
+// TestMatcherPendingNotifications verifies the pending feedback for a sequence that advances and then times out.
+func TestMatcherPendingNotifications(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	matcher := NewMatcherWithClock(fake)
+	matcher.SetSequenceTimeout(500 * time.Millisecond)
+	altW := types.KeyBinding{types.VK_MENU, types.VK_W}
+	matcher.AddBindings(NewSequenceBindingAction([]types.KeyBinding{altW, {types.VK_3}, {types.VK_4}}, func(context.Context, ActionContext) error { return nil }, true))
+
+	var notified [][]types.KeyBinding
+	matcher.OnPendingChange(func(steps []types.KeyBinding, _ *types.Modifiers) {
+		notified = append(notified, steps)
+	})
+
+	matcher.Press(types.VK_W, []types.VirtualKey{types.VK_LMENU, types.VK_W})
+	matcher.Press(types.VK_3, []types.VirtualKey{types.VK_3})
+	fake.Advance(499 * time.Millisecond)
+	require.Len(t, notified, 2, "The sequence should still be pending")
+	fake.Advance(time.Millisecond)
+
+	assert.Equal(t, [][]types.KeyBinding{{altW}, {altW, {types.VK_3}}, nil}, notified)
+	assert.Nil(t, matcher.Pending())
+	assert.Zero(t, fake.Pending(), "No timer should be left running")
+}
+
+// TestMatcherSetBindingsCancelsSequence verifies that replacing the bindings abandons a sequence in progress.
+func TestMatcherSetBindingsCancelsSequence(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	matcher := NewMatcherWithClock(fake)
+	steps := []types.KeyBinding{{types.VK_MENU, types.VK_W}, {types.VK_3}}
+	matcher.AddBindings(NewSequenceBindingAction(steps, func(context.Context, ActionContext) error { return nil }, true))
+
+	require.True(t, matcher.Press(types.VK_W, []types.VirtualKey{types.VK_LMENU, types.VK_W}).Consumed)
+	matcher.SetBindings()
+	assert.Nil(t, matcher.Pending())
+	assert.Zero(t, fake.Pending())
+	assert.False(t, matcher.Press(types.VK_3, []types.VirtualKey{types.VK_3}).Consumed)
+}
+
+// TestFormatSteps verifies that steps are formatted with the custom modifiers of the given table.
+func TestFormatSteps(t *testing.T) {
+	modifiers := types.NewModifiers()
+	hyper, err := modifiers.Define("Hyper")
+	require.NoError(t, err)
+	steps := []types.KeyBinding{{types.VK_W, types.VK_MENU}, {hyper, types.VK_3}}
+
+	assert.Equal(t, "MENU + W, HYPER + 3", FormatSteps(steps, modifiers))
+	assert.Equal(t, types.KeyBinding{types.VK_W, types.VK_MENU}, steps[0], "Formatting should not reorder the steps")
+}
@@ -3,29 +3,37 @@ package shortcut
 import (
 	"log/slog"
 	"sync"
-)
+	"time"
 
-const (
-	blockChanBufferSize = 10 // Increased buffer size
+	"wincuts/keyboard/types"
 )
 
 // Service struct
 type Service struct {
-	shortcutChan chan *KeyBindingAction
+	shortcutChan chan ActionContext
 	matcher      *Matcher
+	executor     *Executor
+	desktopsMu   sync.Mutex
+	desktops     DesktopProvider // Reports the current desktop to actions, nil if unknown
 	wg           sync.WaitGroup
 	stopChan     chan struct{}
-	blockChan    chan bool // Channel to communicate blocking decisions
+	consumedMu   sync.Mutex
+	consumed     map[types.VirtualKey]bool // Keys whose press was taken by a key sequence or a timed trigger; their release is not matched
+	timingMu     sync.Mutex
+	hold         *timedPress      // Press of a hold binding that has not lasted long enough yet
+	tap          *timedPress      // First tap of a double-tap binding waiting for the second one
+	repeatKey    types.VirtualKey // Key whose auto-repeat last fired a binding
+	repeatAt     time.Time        // When repeatKey last fired, for throttling
 }
 
 // NewService creates a new KeybindingService
-func NewService(shortcutChan chan *KeyBindingAction, matcher *Matcher) *Service {
+func NewService(shortcutChan chan ActionContext, matcher *Matcher) *Service {
 	return &Service{
 		shortcutChan: shortcutChan,
 		matcher:      matcher,
+		executor:     NewExecutor(matcher.clock),
 		stopChan:     make(chan struct{}),
-		blockChan:    make(chan bool, blockChanBufferSize), // Larger buffer
-
+		consumed:     make(map[types.VirtualKey]bool),
 	}
 }
 
@@ -35,7 +43,56 @@ func (s *Service) RegisterKeyBindingActions(bindings ...KeyBindingAction) *Servi
 	return s
 }
 
-// Start starts the keybinding service to listen for events
+// ReplaceKeyBindingActions atomically swaps all registered key binding actions, e.g. after a config reload
+func (s *Service) ReplaceKeyBindingActions(bindings ...KeyBindingAction) *Service {
+	s.matcher.SetBindings(bindings...)
+	return s
+}
+
+// ReplaceModes atomically swaps the binding tables of every mode other than the default one
+func (s *Service) ReplaceModes(modes ...Mode) *Service {
+	s.matcher.SetModes(modes...)
+	return s
+}
+
+// EnterMode activates the bindings of the named mode.
+func (s *Service) EnterMode(name string) error {
+	if err := s.matcher.EnterMode(name); err != nil {
+		return err
+	}
+	slog.Info("entered mode", "mode", name)
+	return nil
+}
+
+// ExitMode returns to the default bindings.
+func (s *Service) ExitMode() {
+	s.matcher.ExitMode()
+	slog.Info("left mode")
+}
+
+// OnModeChange registers a function called with the name of the mode that becomes active,
+// DefaultMode when returning to the default bindings.
+func (s *Service) OnModeChange(fn func(mode string)) {
+	s.matcher.OnModeChange(fn)
+}
+
+// SetWindowProvider sets the source of the foreground window for bindings scoped to windows.
+func (s *Service) SetWindowProvider(provider WindowProvider) *Service {
+	s.matcher.SetWindowProvider(provider)
+	return s
+}
+
+// SetExecutionPolicy sets how the actions of fired bindings are run, see ExecutionPolicy.
+func (s *Service) SetExecutionPolicy(policy ExecutionPolicy) {
+	s.executor.SetPolicy(policy)
+}
+
+// OnActionFailure registers a function called with every action that fails, times out or panics.
+func (s *Service) OnActionFailure(fn func(binding *KeyBindingAction, err error)) {
+	s.executor.OnFailure(fn)
+}
+
+// Start starts the keybinding service to listen for events, handing fired bindings to the executor
 func (s *Service) Start() {
 	s.wg.Add(1)
 	go func() {
@@ -43,41 +100,118 @@ func (s *Service) Start() {
 		for {
 			select {
 			case <-s.stopChan:
+				s.drain()
 				return
-			case binding := <-s.shortcutChan:
-				go func() {
-					slog.Info("executing action", "binding", binding.Binding.PrettyString())
-					if err := binding.Execute(); err != nil {
-						slog.Error("failed to execute action", "error", err)
-					}
-				}()
+			case ac := <-s.shortcutChan:
+				s.describe(&ac)
+				s.executor.Submit(ac)
 			}
 		}
 	}()
 }
 
-// GetBlockDecision returns the blocking decision
-func (s *Service) GetBlockDecision() bool {
-	select {
-	case shouldBlock := <-s.blockChan:
-		return shouldBlock
-	default:
-		return false
+// drain hands the bindings already fired to the executor.
+func (s *Service) drain() {
+	for {
+		select {
+		case ac := <-s.shortcutChan:
+			s.describe(&ac)
+			s.executor.Submit(ac)
+		default:
+			return
+		}
+	}
+}
+
+// Press handles the press of a non-modifier key, with keys being every key held including it.
+// Presses that belong to a key sequence are consumed and a completed sequence is dispatched right away;
+// otherwise bindings triggered on press, hold or double tap are fired or timed.
+// It reports whether the press must be kept from the focused application.
+func (s *Service) Press(key types.VirtualKey, keys []types.VirtualKey) bool {
+	s.cancelHold() // Pressing another key interrupts a hold
+	s.resetRepeat()
+	result := s.matcher.Press(key, keys)
+	if !result.Consumed {
+		s.pressTriggers(key, keys)
+		return s.ShouldBlock(keys)
+	}
+
+	s.consume(key)
+	if result.Completed == nil {
+		return true // Intermediate steps never reach the application
+	}
+	s.Dispatch(result.Completed)
+	return result.Completed.ShouldBlock
+}
+
+// consume keeps the release of key from matching a binding.
+func (s *Service) consume(key types.VirtualKey) {
+	s.consumedMu.Lock()
+	s.consumed[key] = true
+	s.consumedMu.Unlock()
+}
+
+// Dispatch sends fired bindings to the shortcut channel without blocking, together with the state they fired in.
+// It is called on the keyboard hook thread, so it only captures what is cheap to read, see actionContext.
+func (s *Service) Dispatch(bindings ...*KeyBindingAction) {
+	for _, binding := range bindings {
+		select {
+		case s.shortcutChan <- s.actionContext(binding):
+			slog.Debug("sent shortcut", "binding", binding, "trigger", binding.Trigger)
+		default:
+			// Drop the event if the channel is full
+		}
 	}
 }
 
-func (s *Service) GetShortcutChan() chan *KeyBindingAction {
+// SetSequenceTimeout sets how long a started key sequence waits for its next step.
+func (s *Service) SetSequenceTimeout(timeout time.Duration) {
+	s.matcher.SetSequenceTimeout(timeout)
+}
+
+// OnSequencePending registers a function called with the steps of a key sequence in progress,
+// and with nil once the sequence completes, times out or is cancelled. The table names the custom modifiers of the steps.
+func (s *Service) OnSequencePending(fn func(steps []types.KeyBinding, modifiers *types.Modifiers)) {
+	s.matcher.OnPendingChange(fn)
+}
+
+// ShouldBlock reports whether the keys complete a shortcut that must not reach the focused application.
+func (s *Service) ShouldBlock(keys []types.VirtualKey) bool {
+	binding, found := s.matcher.Lookup(keys)
+	return found && binding.ShouldBlock
+}
+
+func (s *Service) GetShortcutChan() chan ActionContext {
 	return s.shortcutChan
 }
 
-// Stop stops the keybinding service
+// Stop stops the keybinding service once the bindings already fired have run or timed out
 func (s *Service) Stop() {
 	close(s.stopChan)
 	s.wg.Wait()
+	s.executor.Stop()
 }
 
-
+// Match returns the binding fired by the release or auto-repeat in event. The release of a key consumed by a key
+// sequence, a completed hold or double tap or a repeat matches nothing, and a binding of a key that may still be
+// double tapped is held back until the double tap is ruled out.
 func (s *Service) Match(event KeyEvent) (*KeyBindingAction, bool) {
-	return s.matcher.Match(event)
+	if event.Repeat {
+		return s.repeat(event)
+	}
+	if !event.KeyDown {
+		s.cancelHold()
+		s.consumedMu.Lock()
+		consumed := s.consumed[event.KeyCode]
+		delete(s.consumed, event.KeyCode)
+		s.consumedMu.Unlock()
+		if consumed {
+			return nil, false
+		}
+	}
+	binding, found := s.matcher.Match(event)
+	if found && s.deferTap(event.KeyCode, binding) {
+		return nil, false
+	}
+	return binding, found
 }
-
@@ -1,11 +1,14 @@
 package shortcut
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"wincuts/clock"
 	"wincuts/keyboard/types"
 )
 
@@ -16,7 +19,7 @@ func TestNewBindingAction(t *testing.T) {
 	require := require.New(t)
 
 	var called bool
-	action := func() error {
+	action := func(context.Context, ActionContext) error {
 		called = true
 		return nil
 	}
@@ -24,7 +27,7 @@ func TestNewBindingAction(t *testing.T) {
 	binding := NewBindingAction(keys, action, false)
 
 	// Execute the action to verify that the callback is correctly bound.
-	err := binding.Action()
+	err := binding.Action(context.Background(), ActionContext{})
 	require.NoError(err, "Binding action should not return an error")
 	assert.True(called, "Expected action function to be executed")
 }
@@ -33,12 +36,12 @@ func TestNewBindingAction(t *testing.T) {
 // This is important for ensuring that dynamic registration of shortcuts operates as expected in the application.
 func TestKeybindingServiceRegistration(t *testing.T) {
 	require := require.New(t)
-	dummyChan := make(chan *KeyBindingAction, 1)
+	dummyChan := make(chan ActionContext, 1)
 	svc := NewService(dummyChan, NewMatcher())
 
 	require.NotNil(svc, "NewKeybindingService should not return nil")
 
-	dummyAction := NewBindingAction([]types.VirtualKey{types.VK_LMENU}, func() error {
+	dummyAction := NewBindingAction([]types.VirtualKey{types.VK_LMENU}, func(context.Context, ActionContext) error {
 		return nil
 	}, false)
 
@@ -76,12 +79,12 @@ func MatchKeys(binding, input []types.VirtualKey) bool {
 func TestNewService(t *testing.T) {
 	require := require.New(t)
 
-	dummyChan := make(chan *KeyBindingAction, 1)
+	dummyChan := make(chan ActionContext, 1)
 	svc := NewService(dummyChan, NewMatcher())
 
 	require.NotNil(svc, "NewService should not return nil")
 
-	dummyAction := NewBindingAction([]types.VirtualKey{types.VK_LMENU}, func() error {
+	dummyAction := NewBindingAction([]types.VirtualKey{types.VK_LMENU}, func(context.Context, ActionContext) error {
 		return nil
 	}, false)
 
@@ -89,3 +92,89 @@ func TestNewService(t *testing.T) {
 		svc.RegisterKeyBindingActions(dummyAction)
 	}, "Registering binding actions should not panic")
 }
+
+// TestServiceShouldBlock verifies that only blocking bindings for exactly the given keys are reported,
+// independent of whether they trigger on press or release.
+func TestServiceShouldBlock(t *testing.T) {
+	assert := assert.New(t)
+	noop := func(context.Context, ActionContext) error { return nil }
+	svc := NewService(make(chan ActionContext, 1), NewMatcher())
+	svc.RegisterKeyBindingActions(
+		NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_1}, noop, true),
+		NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_2}, noop, false),
+		NewBindingAction([]types.VirtualKey{types.VK_LMENU, types.VK_3}, noop, false),
+		NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_3}, noop, true),
+	)
+
+	assert.True(svc.ShouldBlock([]types.VirtualKey{types.VK_RMENU, types.VK_1}), "Blocking binding should block")
+	assert.False(svc.ShouldBlock([]types.VirtualKey{types.VK_LMENU, types.VK_2}), "Non-blocking binding should pass")
+	assert.False(svc.ShouldBlock([]types.VirtualKey{types.VK_LMENU, types.VK_3}), "The most specific binding decides")
+	assert.True(svc.ShouldBlock([]types.VirtualKey{types.VK_RMENU, types.VK_3}))
+	assert.False(svc.ShouldBlock([]types.VirtualKey{types.VK_1}), "Unbound keys should pass")
+}
+
+// fixedDesktop is a DesktopProvider always showing the same desktop.
+type fixedDesktop int
+
+func (d fixedDesktop) CurrentDesktop() (int, error) {
+	return int(d), nil
+}
+
+// fakeDescriber is a WindowDescriber over a fixed set of windows, counting the windows it describes.
+type fakeDescriber struct {
+	foreground uintptr
+	windows    map[uintptr]Window
+	described  int
+}
+
+func (f *fakeDescriber) ForegroundWindow() (Window, error) {
+	return f.DescribeWindow(f.foreground)
+}
+
+func (f *fakeDescriber) ForegroundHandle() uintptr {
+	return f.foreground
+}
+
+func (f *fakeDescriber) DescribeWindow(handle uintptr) (Window, error) {
+	f.described++
+	return f.windows[handle], nil
+}
+
+// TestServiceActionContext verifies that an action sees the binding that fired and the window and mode it fired in,
+// not the ones when the service gets to it, and that firing only reads the window handle.
+func TestServiceActionContext(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	firedAt := clk.Now()
+	shortcutChan := make(chan ActionContext, 1)
+	svc := NewService(shortcutChan, NewMatcherWithClock(clk))
+	terminal := Window{Process: "WindowsTerminal.exe", Title: "PowerShell", Handle: 0x1234}
+	windows := &fakeDescriber{foreground: terminal.Handle, windows: map[uintptr]Window{
+		terminal.Handle: terminal,
+		0x5678:          {Process: "explorer.exe", Handle: 0x5678},
+	}}
+	svc.SetWindowProvider(windows)
+	svc.SetDesktopProvider(fixedDesktop(3))
+	svc.ReplaceModes(Mode{Name: "resize"})
+	require.NoError(t, svc.EnterMode("resize"))
+
+	contexts := make(chan ActionContext, 1)
+	binding := NewBindingAction([]types.VirtualKey{types.VK_H}, func(ctx context.Context, ac ActionContext) error {
+		contexts <- ac
+		return nil
+	}, true)
+	svc.Dispatch(&binding)
+	assert.Zero(t, windows.described, "Firing must not describe the window on the hook thread")
+
+	clk.Advance(time.Second)
+	windows.foreground = 0x5678
+	svc.ExitMode()
+	svc.Start()
+	svc.Stop()
+
+	ac := <-contexts
+	assert.Equal(t, types.KeyBinding{types.VK_H}, ac.Binding.Binding)
+	assert.Equal(t, firedAt, ac.Time)
+	assert.Equal(t, terminal, ac.Window)
+	assert.Equal(t, 3, ac.Desktop)
+	assert.Equal(t, "resize", ac.Mode)
+}
@@ -0,0 +1,161 @@
+package shortcut
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// TriggerKind names the moment a binding fires.
+type TriggerKind int
+
+const (
+	TriggerUp     TriggerKind = iota // Fire when the keys are released, the default
+	TriggerDown                      // Fire as soon as the keys are pressed
+	TriggerHold                      // Fire once the keys have been held for the trigger's duration
+	TriggerDouble                    // Fire on the second press of the keys within the trigger's duration
+)
+
+// Trigger decides when a binding fires. Hold and double triggers are timed by the Service on the
+// Matcher's clock; the zero Trigger fires on release.
+type Trigger struct {
+	Kind     TriggerKind
+	Duration time.Duration // How long a hold lasts, or the longest pause between the taps of a double tap
+}
+
+func (t Trigger) String() string {
+	switch t.Kind {
+	case TriggerDown:
+		return "down"
+	case TriggerHold:
+		return fmt.Sprintf("hold(%s)", t.Duration)
+	case TriggerDouble:
+		return fmt.Sprintf("double(%s)", t.Duration)
+	}
+	return "up"
+}
+
+// timedPress is a press waiting on the clock: a hold that has not lasted long enough yet,
+// or the first tap of a possible double tap.
+type timedPress struct {
+	key      types.VirtualKey
+	binding  *KeyBindingAction   // Fired when the hold or double tap completes
+	deferred []*KeyBindingAction // Down and up bindings of the same keys, fired once no second tap came
+	timer    clock.Timer
+}
+
+// pressTriggers fires or starts timing the down, hold and double bindings of a press that no key sequence took.
+// While a double-tap binding waits for its second tap, the down and up bindings of the same keys are held back
+// so a double tap does not also fire them.
+func (s *Service) pressTriggers(key types.VirtualKey, keys []types.VirtualKey) {
+	double, hasDouble := s.matcher.LookupTrigger(keys, TriggerDouble)
+	if tap := s.takeTap(); tap != nil {
+		if hasDouble && tap.key == key && sameBinding(tap.binding, double) {
+			s.consume(key)
+			s.Dispatch(double)
+			return
+		}
+		s.Dispatch(tap.deferred...) // Another press ends the tap early
+	}
+
+	if hasDouble {
+		s.startTap(key, double)
+	}
+	if hold, ok := s.matcher.LookupTrigger(keys, TriggerHold); ok {
+		s.startHold(key, hold)
+	}
+	if down, ok := s.matcher.LookupTrigger(keys, TriggerDown); ok && !s.deferTap(key, down) {
+		s.Dispatch(down)
+	}
+}
+
+// startHold fires binding after its duration unless a key is pressed or released first.
+func (s *Service) startHold(key types.VirtualKey, binding *KeyBindingAction) {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	press := &timedPress{key: key, binding: binding}
+	press.timer = s.matcher.clock.AfterFunc(binding.Trigger.Duration, func() {
+		s.fireHold(press)
+	})
+	s.hold = press
+}
+
+// fireHold dispatches a hold that lasted long enough. The release of its key then fires nothing.
+func (s *Service) fireHold(press *timedPress) {
+	s.timingMu.Lock()
+	current := s.hold == press
+	if current {
+		s.hold = nil
+	}
+	s.timingMu.Unlock()
+	if !current {
+		return
+	}
+	s.consume(press.key)
+	s.Dispatch(press.binding)
+}
+
+// cancelHold abandons a hold in progress.
+func (s *Service) cancelHold() {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	if s.hold != nil {
+		s.hold.timer.Stop()
+		s.hold = nil
+	}
+}
+
+// startTap remembers the first tap of a double tap until its interval runs out.
+func (s *Service) startTap(key types.VirtualKey, binding *KeyBindingAction) {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	tap := &timedPress{key: key, binding: binding}
+	tap.timer = s.matcher.clock.AfterFunc(binding.Trigger.Duration, func() {
+		s.expireTap(tap)
+	})
+	s.tap = tap
+}
+
+// expireTap fires the bindings held back by a tap that was not followed by a second one in time.
+func (s *Service) expireTap(tap *timedPress) {
+	s.timingMu.Lock()
+	current := s.tap == tap
+	if current {
+		s.tap = nil
+	}
+	s.timingMu.Unlock()
+	if current {
+		s.Dispatch(tap.deferred...)
+	}
+}
+
+// takeTap removes the pending tap, if any, and stops its timer.
+func (s *Service) takeTap() *timedPress {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	tap := s.tap
+	if tap != nil {
+		tap.timer.Stop()
+		s.tap = nil
+	}
+	return tap
+}
+
+// deferTap holds binding back until the pending tap of key expires, and reports whether it did.
+func (s *Service) deferTap(key types.VirtualKey, binding *KeyBindingAction) bool {
+	s.timingMu.Lock()
+	defer s.timingMu.Unlock()
+	if s.tap == nil || s.tap.key != key {
+		return false
+	}
+	s.tap.deferred = append(s.tap.deferred, binding)
+	return true
+}
+
+// sameBinding reports whether two copies returned by the Matcher refer to the same binding.
+func sameBinding(a, b *KeyBindingAction) bool {
+	return slices.Equal(a.Binding, b.Binding) && a.Window == b.Window && a.Trigger == b.Trigger
+}
@@ -0,0 +1,171 @@
+package shortcut
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"wincuts/clock"
+	"wincuts/keyboard/types"
+)
+
+// triggerService drives a Service the way the keyboard hook does, recording the trigger of every fired binding.
+type triggerService struct {
+	*Service
+	clock *clock.Fake
+	held  []types.VirtualKey
+}
+
+// newTriggerService creates a service with an Alt+1 binding for each trigger.
+func newTriggerService(triggers ...Trigger) *triggerService {
+	var bindings []KeyBindingAction
+	for _, trigger := range triggers {
+		binding := NewBindingAction([]types.VirtualKey{types.VK_MENU, types.VK_1}, func(context.Context, ActionContext) error { return nil }, true)
+		binding.Trigger = trigger
+		bindings = append(bindings, binding)
+	}
+	return newTestService(bindings...)
+}
+
+// newTestService creates a service with the given bindings on a fake clock.
+func newTestService(bindings ...KeyBindingAction) *triggerService {
+	fake := clock.NewFake(time.Time{})
+	service := NewService(make(chan ActionContext, 32), NewMatcherWithClock(fake))
+	service.RegisterKeyBindingActions(bindings...)
+	return &triggerService{Service: service, clock: fake}
+}
+
+func (s *triggerService) press(key types.VirtualKey) {
+	s.held = append(s.held, key)
+	if !key.IsModifier() {
+		s.Press(key, s.held)
+	}
+}
+
+// autoRepeat sends an auto-repeat of the held key.
+func (s *triggerService) autoRepeat(key types.VirtualKey) {
+	if binding, found := s.Match(KeyEvent{PressedKeys: s.held, KeyCode: key, KeyDown: true, Repeat: true}); found {
+		s.Dispatch(binding)
+	}
+}
+
+func (s *triggerService) release(key types.VirtualKey) {
+	if binding, found := s.Match(KeyEvent{PressedKeys: s.held, KeyCode: key}); found {
+		s.Dispatch(binding)
+	}
+	for i, held := range s.held {
+		if held == key {
+			s.held = append(s.held[:i], s.held[i+1:]...)
+			break
+		}
+	}
+}
+
+// tap presses and releases Alt+1.
+func (s *triggerService) tap() {
+	s.press(types.VK_LMENU)
+	s.press(types.VK_1)
+	s.release(types.VK_1)
+	s.release(types.VK_LMENU)
+}
+
+// fired returns the triggers of the bindings dispatched since the last call.
+func (s *triggerService) fired() []string {
+	var fired []string
+	for {
+		select {
+		case ac := <-s.shortcutChan:
+			fired = append(fired, ac.Binding.Trigger.String())
+		default:
+			return fired
+		}
+	}
+}
+
+var (
+	down   = Trigger{Kind: TriggerDown}
+	up     = Trigger{Kind: TriggerUp}
+	hold   = Trigger{Kind: TriggerHold, Duration: 500 * time.Millisecond}
+	double = Trigger{Kind: TriggerDouble, Duration: 300 * time.Millisecond}
+)
+
+// TestTriggerDownAndUp verifies that down bindings fire on the press and up bindings on the release.
+func TestTriggerDownAndUp(t *testing.T) {
+	assert := assert.New(t)
+	s := newTriggerService(down, up)
+
+	s.press(types.VK_LMENU)
+	s.press(types.VK_1)
+	assert.Equal([]string{"down"}, s.fired())
+	s.release(types.VK_1)
+	assert.Equal([]string{"up"}, s.fired())
+	s.release(types.VK_LMENU)
+	assert.Empty(s.fired())
+}
+
+// TestTriggerHold verifies that a hold fires once the keys are held long enough and replaces the release binding.
+func TestTriggerHold(t *testing.T) {
+	assert := assert.New(t)
+	s := newTriggerService(hold, up)
+
+	s.press(types.VK_LMENU)
+	s.press(types.VK_1)
+	s.clock.Advance(499 * time.Millisecond)
+	assert.Empty(s.fired(), "The hold should not fire early")
+	s.clock.Advance(time.Millisecond)
+	assert.Equal([]string{"hold(500ms)"}, s.fired())
+	s.release(types.VK_1)
+	s.release(types.VK_LMENU)
+	assert.Empty(s.fired(), "Releasing a completed hold should not fire the release binding")
+
+	s.tap()
+	assert.Equal([]string{"up"}, s.fired(), "A short press should only fire the release binding")
+	assert.Zero(s.clock.Pending(), "Releasing the keys should cancel the hold")
+}
+
+// TestTriggerHoldInterrupted verifies that pressing another key cancels a hold.
+func TestTriggerHoldInterrupted(t *testing.T) {
+	s := newTriggerService(hold)
+
+	s.press(types.VK_LMENU)
+	s.press(types.VK_1)
+	s.press(types.VK_2)
+	s.clock.Advance(time.Second)
+	assert.Empty(t, s.fired())
+}
+
+// TestTriggerDouble verifies that a second tap within the interval fires the double-tap binding instead of the others.
+func TestTriggerDouble(t *testing.T) {
+	assert := assert.New(t)
+	s := newTriggerService(double, down, up)
+
+	s.tap()
+	assert.Empty(s.fired(), "The first tap should wait for a second one")
+	s.clock.Advance(200 * time.Millisecond)
+	s.tap()
+	assert.Equal([]string{"double(300ms)"}, s.fired())
+	assert.Zero(s.clock.Pending())
+
+	s.tap()
+	s.clock.Advance(300 * time.Millisecond)
+	assert.Equal([]string{"down", "up"}, s.fired(), "A single tap should fire once the interval has passed")
+
+	s.tap()
+	s.press(types.VK_2)
+	assert.Equal([]string{"down", "up"}, s.fired(), "Another key should end the tap early")
+}
+
+// TestTriggerDoubleTooSlow verifies that taps further apart than the interval are two single taps.
+func TestTriggerDoubleTooSlow(t *testing.T) {
+	s := newTriggerService(double)
+
+	s.tap()
+	s.clock.Advance(400 * time.Millisecond)
+	s.tap()
+	assert.Empty(t, s.fired())
+	s.clock.Advance(200 * time.Millisecond)
+	s.tap()
+	assert.Equal(t, []string{"double(300ms)"}, s.fired())
+}
@@ -0,0 +1,156 @@
+package shortcut
+
+import (
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Window describes the foreground window for per-application bindings.
+type Window struct {
+	Process    string // Executable file name, e.g. "WindowsTerminal.exe"
+	Class      string // Window class name, e.g. "CASCADIA_HOSTING_WINDOW_CLASS"
+	Title      string
+	FullScreen bool    // Covers its whole monitor, e.g. a game or a full-screen video
+	Handle     uintptr // Native window handle (HWND), zero if unknown
+}
+
+// WindowProvider reports the window that has the keyboard focus.
+// The Matcher asks for it on the keyboard hook thread when a binding of the active mode is scoped to windows,
+// so it must answer from memory, e.g. a WindowCache, rather than query the process owning the window.
+// On error the provider may still return the parts of the window it could read.
+type WindowProvider interface {
+	ForegroundWindow() (Window, error)
+}
+
+// WindowRule matches windows by process, class and title. Empty parts match any window,
+// and a window must match every part that is set.
+type WindowRule struct {
+	Processes []string       // Executable file names, ignoring case
+	Classes   []string       // Window class names, ignoring case
+	Title     *regexp.Regexp // Matched against the window title
+}
+
+// Matches reports whether w satisfies the rule.
+func (r *WindowRule) Matches(w Window) bool {
+	equalFold := func(name string) func(string) bool {
+		return func(want string) bool { return strings.EqualFold(want, name) }
+	}
+	if len(r.Processes) > 0 && !slices.ContainsFunc(r.Processes, equalFold(w.Process)) {
+		return false
+	}
+	if len(r.Classes) > 0 && !slices.ContainsFunc(r.Classes, equalFold(w.Class)) {
+		return false
+	}
+	return r.Title == nil || r.Title.MatchString(w.Title)
+}
+
+// WindowCondition scopes a binding to the windows matching When and not matching Unless.
+// Either rule may be nil.
+type WindowCondition struct {
+	When   *WindowRule
+	Unless *WindowRule
+}
+
+// Allows reports whether a binding with the condition applies while w has the focus.
+func (c *WindowCondition) Allows(w Window) bool {
+	if c.When != nil && !c.When.Matches(w) {
+		return false
+	}
+	return c.Unless == nil || !c.Unless.Matches(w)
+}
+
+// WindowCache is a WindowProvider reporting a snapshot of the foreground window, taken by Refresh
+// whenever the foreground changes. Reading it never waits on the system, so it is safe on the keyboard hook thread.
+type WindowCache struct {
+	source WindowProvider
+
+	mu     sync.RWMutex
+	window Window
+	err    error
+}
+
+// NewWindowCache returns a cache of the window reported by source. It is empty until the first Refresh.
+func NewWindowCache(source WindowProvider) *WindowCache {
+	return &WindowCache{source: source}
+}
+
+// Refresh replaces the snapshot with the window source now reports. Call it off the keyboard hook thread.
+func (c *WindowCache) Refresh() {
+	w, err := c.source.ForegroundWindow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.window, c.err = w, err
+}
+
+// ForegroundWindow implements WindowProvider, returning the last snapshot.
+func (c *WindowCache) ForegroundWindow() (Window, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.window, c.err
+}
+
+// ForegroundHandle implements WindowDescriber. It asks source if that is a WindowDescriber, whose
+// handle lookup is cheap, and otherwise returns the handle of the snapshot.
+func (c *WindowCache) ForegroundHandle() uintptr {
+	if d, ok := c.source.(WindowDescriber); ok {
+		return d.ForegroundHandle()
+	}
+	w, _ := c.ForegroundWindow()
+	return w.Handle
+}
+
+// DescribeWindow implements WindowDescriber. The snapshot describes its own window;
+// any other window is described by source if it can, and is otherwise only known by its handle.
+func (c *WindowCache) DescribeWindow(handle uintptr) (Window, error) {
+	if w, err := c.ForegroundWindow(); w.Handle == handle {
+		return w, err
+	}
+	if d, ok := c.source.(WindowDescriber); ok {
+		return d.DescribeWindow(handle)
+	}
+	return Window{Handle: handle}, nil
+}
+
+// noWindows is the WindowProvider of a Matcher that was never given one; no window matches a When rule.
+type noWindows struct{}
+
+func (noWindows) ForegroundWindow() (Window, error) {
+	return Window{}, nil
+}
+
+// SetWindowProvider sets the source of the foreground window for bindings scoped to windows.
+func (m *Matcher) SetWindowProvider(provider WindowProvider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.windows = provider
+}
+
+// windowProvider returns the source of the foreground window.
+func (m *Matcher) windowProvider() WindowProvider {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.windows
+}
+
+// windowFilter returns a function reporting whether a binding applies to the foreground window.
+// The provider is asked at most once, and only if a scoped binding is checked; m.mu must be held.
+func (m *Matcher) windowFilter() func(*KeyBindingAction) bool {
+	var window *Window
+	return func(binding *KeyBindingAction) bool {
+		if binding.Window == nil {
+			return true
+		}
+		if window == nil {
+			w, err := m.windows.ForegroundWindow()
+			if err != nil {
+				// Unknown parts of the window only satisfy rules that exclude windows
+				slog.Debug("failed to get the foreground window", "error", err)
+			}
+			window = &w
+		}
+		return binding.Window.Allows(*window)
+	}
+}
@@ -0,0 +1,165 @@
+package shortcut
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"wincuts/keyboard/types"
+)
+
+// fakeWindows is a WindowProvider reporting a fixed window and counting the lookups.
+type fakeWindows struct {
+	window  Window
+	err     error
+	lookups int
+}
+
+func (f *fakeWindows) ForegroundWindow() (Window, error) {
+	f.lookups++
+	return f.window, f.err
+}
+
+// TestWindowRuleMatches verifies how rules match windows by process, class and title.
+func TestWindowRuleMatches(t *testing.T) {
+	terminal := Window{Process: "WindowsTerminal.exe", Class: "CASCADIA_HOSTING_WINDOW_CLASS", Title: "PowerShell"}
+
+	tests := []struct {
+		name string
+		rule WindowRule
+		want bool
+	}{
+		{name: "empty rule matches anything", rule: WindowRule{}, want: true},
+		{name: "process ignores case", rule: WindowRule{Processes: []string{"code.exe", "windowsterminal.exe"}}, want: true},
+		{name: "other process", rule: WindowRule{Processes: []string{"code.exe"}}},
+		{name: "class", rule: WindowRule{Classes: []string{"cascadia_hosting_window_class"}}, want: true},
+		{name: "title regex", rule: WindowRule{Title: regexp.MustCompile(`^Power`)}, want: true},
+		{name: "every part must match", rule: WindowRule{Processes: []string{"WindowsTerminal.exe"}, Title: regexp.MustCompile(`cmd`)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Matches(terminal))
+		})
+	}
+}
+
+// TestMatcherWindowScopes verifies that bindings scoped to windows only apply while such a window has the focus.
+func TestMatcherWindowScopes(t *testing.T) {
+	noop := func(context.Context, ActionContext) error { return nil }
+	altOne := []types.VirtualKey{types.VK_LMENU, types.VK_1}
+	terminal := &WindowRule{Processes: []string{"WindowsTerminal.exe"}}
+	scoped := func(keys []types.VirtualKey, condition WindowCondition) KeyBindingAction {
+		binding := NewBindingAction(keys, noop, true)
+		binding.Window = &condition
+		return binding
+	}
+
+	global := NewBindingAction([]types.VirtualKey{types.VK_LMENU, types.VK_1}, noop, true)
+	exceptTerminal := scoped(altOne, WindowCondition{Unless: terminal})
+	onlyTerminal := scoped([]types.VirtualKey{types.VK_MENU, types.VK_1}, WindowCondition{When: terminal})
+
+	tests := []struct {
+		name     string
+		bindings []KeyBindingAction
+		window   Window
+		err      error
+		want     *WindowCondition // Condition of the expected binding, nil for the global binding
+		wantNone bool
+	}{
+		{
+			name:     "excluded window lets the keys pass",
+			bindings: []KeyBindingAction{exceptTerminal},
+			window:   Window{Process: "WindowsTerminal.exe"},
+			wantNone: true,
+		},
+		{
+			name:     "other windows keep the binding",
+			bindings: []KeyBindingAction{exceptTerminal},
+			window:   Window{Process: "notepad.exe"},
+			want:     exceptTerminal.Window,
+		},
+		{
+			name:     "scoped binding wins over a more specific global one",
+			bindings: []KeyBindingAction{global, onlyTerminal},
+			window:   Window{Process: "WindowsTerminal.exe"},
+			want:     onlyTerminal.Window,
+		},
+		{
+			name:     "global binding applies outside the scope",
+			bindings: []KeyBindingAction{global, onlyTerminal},
+			window:   Window{Process: "notepad.exe"},
+		},
+		{
+			name:     "unknown window only keeps exclusions",
+			bindings: []KeyBindingAction{onlyTerminal, exceptTerminal},
+			window:   Window{Title: "Administrator: PowerShell"},
+			err:      errors.New("access denied"),
+			want:     exceptTerminal.Window,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := &fakeWindows{window: tt.window, err: tt.err}
+			matcher := NewMatcher()
+			matcher.SetWindowProvider(windows)
+			matcher.AddBindings(tt.bindings...)
+
+			match, found := matcher.Lookup(altOne)
+			assert.LessOrEqual(t, windows.lookups, 1, "The window should be looked up at most once per query")
+			if tt.wantNone {
+				assert.False(t, found)
+				return
+			}
+			if assert.True(t, found) {
+				assert.Equal(t, tt.want, match.Window)
+			}
+		})
+	}
+}
+
+// TestMatcherSkipsWindowLookup verifies that the foreground window is not queried without scoped bindings.
+func TestMatcherSkipsWindowLookup(t *testing.T) {
+	windows := &fakeWindows{}
+	matcher := NewMatcher()
+	matcher.SetWindowProvider(windows)
+	matcher.AddBindings(NewBindingAction([]types.VirtualKey{types.VK_LMENU, types.VK_1}, func(context.Context, ActionContext) error { return nil }, true))
+
+	_, found := matcher.Lookup([]types.VirtualKey{types.VK_LMENU, types.VK_1})
+	assert.True(t, found)
+	assert.Zero(t, windows.lookups)
+}
+
+// TestWindowCacheServesSnapshot verifies that matching reads the cached window and only Refresh asks the source.
+func TestWindowCacheServesSnapshot(t *testing.T) {
+	terminal := Window{Process: "WindowsTerminal.exe", Handle: 1}
+	source := &fakeWindows{window: terminal}
+	cache := NewWindowCache(source)
+	cache.Refresh()
+
+	binding := NewBindingAction([]types.VirtualKey{types.VK_LMENU, types.VK_1}, func(context.Context, ActionContext) error { return nil }, true)
+	binding.Window = &WindowCondition{When: &WindowRule{Processes: []string{"WindowsTerminal.exe"}}}
+	matcher := NewMatcher()
+	matcher.SetWindowProvider(cache)
+	matcher.AddBindings(binding)
+
+	_, found := matcher.Lookup([]types.VirtualKey{types.VK_LMENU, types.VK_1})
+	assert.True(t, found)
+	assert.Equal(t, 1, source.lookups, "Matching should not ask the source")
+
+	source.window = Window{Process: "code.exe", Handle: 2}
+	_, found = matcher.Lookup([]types.VirtualKey{types.VK_LMENU, types.VK_1})
+	assert.True(t, found, "The snapshot should hold until the next refresh")
+
+	cache.Refresh()
+	_, found = matcher.Lookup([]types.VirtualKey{types.VK_LMENU, types.VK_1})
+	assert.False(t, found)
+
+	w, err := cache.DescribeWindow(1)
+	assert.NoError(t, err)
+	assert.Equal(t, Window{Handle: 1}, w, "A window other than the snapshot is only known by its handle")
+}
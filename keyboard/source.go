@@ -0,0 +1,55 @@
+package keyboard
+
+import wtypes "wincuts/keyboard/types"
+
+// InjectedTag marks key events injected by WinCuts itself. It is passed as the extra info of every
+// synthetic event so the hook can recognise and ignore its own input.
+const InjectedTag uint32 = 0x57435453 // "WCTS"
+
+// MenuMaskKey is an unassigned virtual key tapped before releasing Alt or Win after a blocked shortcut.
+// Windows opens the menu bar or the Start menu when those keys are released without any other key in between;
+// the tap counts as that key without having any effect of its own.
+const MenuMaskKey wtypes.VirtualKey = 0xE8
+
+// RawEvent is a single key transition reported by an InputSource.
+type RawEvent struct {
+	VKCode    wtypes.VirtualKey // The key that changed state
+	KeyDown   bool              // Whether the key was pressed (true) or released (false)
+	ExtraInfo uint32            // Extra information attached by the sender, InjectedTag for our own input
+}
+
+// Decision tells an InputSource whether to deliver an event to the focused application.
+type Decision int
+
+const (
+	// Pass lets the event continue to the focused application.
+	Pass Decision = iota
+	// Block swallows the event so no application sees it.
+	Block
+)
+
+// EventHandler decides the fate of an event. It is called synchronously for every event,
+// so it must return quickly: Windows removes low-level hooks that take too long.
+type EventHandler func(event RawEvent) Decision
+
+// InputSource delivers raw keyboard events to a handler and applies its decisions.
+// The Windows low-level keyboard hook is the production implementation.
+// A source that also implements KeyStateQuery lets the hook recover keys whose release was lost.
+type InputSource interface {
+	// Install starts delivering events to handler.
+	Install(handler EventHandler) error
+	// Uninstall stops delivering events.
+	Uninstall() error
+}
+
+// KeyInjector sends synthetic key events to the system.
+type KeyInjector interface {
+	// SendKey injects a single key transition tagged with InjectedTag.
+	SendKey(vk wtypes.VirtualKey, down bool) error
+}
+
+// KeyStateQuery reports the key state kept by the system, which only knows about presses that were not blocked.
+type KeyStateQuery interface {
+	// IsKeyDown reports whether the system considers vk pressed.
+	IsKeyDown(vk wtypes.VirtualKey) bool
+}
@@ -0,0 +1,90 @@
+package keyboard
+
+import (
+	"log/slog"
+	"slices"
+
+	"wincuts/keyboard/shortcut"
+	wtypes "wincuts/keyboard/types"
+)
+
+// suppressor decides which key events to swallow so that matched shortcuts never reach the focused application.
+// It blocks the press that completes a blocking shortcut together with its auto-repeats and its release,
+// and masks the Alt and Win keys so releasing them afterwards does not open the menu bar or the Start menu.
+// Modifiers themselves are never blocked; they pass before it is known which shortcut they will be part of.
+type suppressor struct {
+	injector  KeyInjector
+	swallowed map[wtypes.VirtualKey]bool // Keys whose press was blocked; their repeats and release are blocked too
+}
+
+// newSuppressor creates a suppressor that uses injector for menu masking.
+func newSuppressor(injector KeyInjector) *suppressor {
+	return &suppressor{
+		injector:  injector,
+		swallowed: make(map[wtypes.VirtualKey]bool),
+	}
+}
+
+// decide returns the decision for event, whose PressedKeys are the keys held before it.
+// press is told about each new press of a non-modifier key, not its auto-repeats, together with all held keys, and reports
+// whether the press must be blocked, e.g. because it completes a blocking shortcut or is a step of a key sequence.
+func (s *suppressor) decide(event shortcut.KeyEvent, press func(key wtypes.VirtualKey, keys []wtypes.VirtualKey) bool) Decision {
+	vk := event.KeyCode
+	if !event.KeyDown {
+		if s.swallowed[vk] {
+			delete(s.swallowed, vk)
+			return Block
+		}
+		return Pass
+	}
+
+	if s.swallowed[vk] {
+		return Block // Auto-repeat of a swallowed key
+	}
+	if vk.IsModifier() || event.Repeat {
+		return Pass // Auto-repeats of a key that passed are not new presses
+	}
+
+	keys := append(slices.Clone(event.PressedKeys), vk)
+	if !press(vk, keys) {
+		return Pass
+	}
+
+	s.swallowed[vk] = true
+	if slices.ContainsFunc(event.PressedKeys, isMenuKey) {
+		s.maskMenu()
+	}
+	return Block
+}
+
+// forget forgets a swallowed key whose release was lost.
+func (s *suppressor) forget(vk wtypes.VirtualKey) {
+	delete(s.swallowed, vk)
+}
+
+// reset forgets all swallowed keys.
+func (s *suppressor) reset() {
+	clear(s.swallowed)
+}
+
+// maskMenu taps MenuMaskKey so the held Alt or Win key is no longer released on its own.
+func (s *suppressor) maskMenu() {
+	if s.injector == nil {
+		return
+	}
+	for _, down := range []bool{true, false} {
+		if err := s.injector.SendKey(MenuMaskKey, down); err != nil {
+			slog.Error("failed to mask menu key", "error", err)
+			return
+		}
+	}
+}
+
+// isMenuKey reports whether releasing vk on its own activates a menu.
+func isMenuKey(vk wtypes.VirtualKey) bool {
+	switch vk {
+	case wtypes.VK_LMENU, wtypes.VK_RMENU, wtypes.VK_LWIN, wtypes.VK_RWIN:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package keyboard
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"wincuts/keyboard/shortcut"
+	wtypes "wincuts/keyboard/types"
+)
+
+// recordingInjector is a KeyInjector that records injected events instead of sending them.
+type recordingInjector struct {
+	events []RawEvent
+}
+
+func (r *recordingInjector) SendKey(vk wtypes.VirtualKey, down bool) error {
+	r.events = append(r.events, RawEvent{VKCode: vk, KeyDown: down, ExtraInfo: InjectedTag})
+	return nil
+}
+
+// TestSuppressorDecide verifies the block decision for sequences of key events.
+func TestSuppressorDecide(t *testing.T) {
+	// Alt+1 and Win+Left are blocking shortcuts.
+	blocks := func(_ wtypes.VirtualKey, keys []wtypes.VirtualKey) bool {
+		return wtypes.NewKeybinding(wtypes.VK_MENU, wtypes.VK_1).Match(keys) ||
+			wtypes.NewKeybinding(wtypes.VK_WIN, wtypes.VK_LEFT).Match(keys)
+	}
+	type step struct {
+		key  wtypes.VirtualKey
+		down bool
+		want Decision
+	}
+
+	tests := []struct {
+		name      string
+		steps     []step
+		wantMasks int
+	}{
+		{
+			name: "shortcut key is blocked through its release",
+			steps: []step{
+				{wtypes.VK_RMENU, true, Pass},
+				{wtypes.VK_1, true, Block},
+				{wtypes.VK_1, true, Block},
+				{wtypes.VK_1, false, Block},
+				{wtypes.VK_RMENU, false, Pass},
+			},
+			wantMasks: 1,
+		},
+		{
+			name: "release after the modifier is still blocked",
+			steps: []step{
+				{wtypes.VK_LWIN, true, Pass},
+				{wtypes.VK_LEFT, true, Block},
+				{wtypes.VK_LWIN, false, Pass},
+				{wtypes.VK_LEFT, false, Block},
+			},
+			wantMasks: 1,
+		},
+		{
+			name: "extra modifier means no shortcut",
+			steps: []step{
+				{wtypes.VK_LMENU, true, Pass},
+				{wtypes.VK_LSHIFT, true, Pass},
+				{wtypes.VK_1, true, Pass},
+				{wtypes.VK_1, false, Pass},
+			},
+		},
+		{
+			name: "key pressed before the modifier is not blocked",
+			steps: []step{
+				{wtypes.VK_1, true, Pass},
+				{wtypes.VK_LMENU, true, Pass},
+				{wtypes.VK_1, false, Pass},
+				{wtypes.VK_LMENU, false, Pass},
+			},
+		},
+		{
+			name: "auto-repeat of a passed key is not a new press",
+			steps: []step{
+				{wtypes.VK_1, true, Pass},
+				{wtypes.VK_LMENU, true, Pass},
+				{wtypes.VK_1, true, Pass},
+				{wtypes.VK_1, false, Pass},
+				{wtypes.VK_LMENU, false, Pass},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := &recordingInjector{}
+			s := newSuppressor(injector)
+			var pressed []wtypes.VirtualKey
+			for i, step := range tt.steps {
+				repeat := step.down && slices.Contains(pressed, step.key)
+				event := shortcut.KeyEvent{PressedKeys: pressed, KeyCode: step.key, KeyDown: step.down, Repeat: repeat}
+				assert.Equal(t, step.want, s.decide(event, blocks), "step %d", i)
+				pressed = updatePressed(pressed, step.key, step.down)
+			}
+			assert.Len(t, injector.events, 2*tt.wantMasks, "each mask is a press and a release")
+			assert.Empty(t, s.swallowed, "every swallowed key should have been released")
+		})
+	}
+}
+
+// updatePressed applies a key transition to a list of pressed keys.
+func updatePressed(pressed []wtypes.VirtualKey, key wtypes.VirtualKey, down bool) []wtypes.VirtualKey {
+	var next []wtypes.VirtualKey
+	for _, vk := range pressed {
+		if vk != key {
+			next = append(next, vk)
+		}
+	}
+	if down {
+		next = append(next, key)
+	}
+	return next
+}
@@ -0,0 +1,194 @@
+package keyboard
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"wincuts/keyboard/shortcut"
+	wtypes "wincuts/keyboard/types"
+)
+
+// autoSuspendInterval is how often RunAutoSuspend checks the foreground window.
+const autoSuspendInterval = 500 * time.Millisecond
+
+// SuspendRule suspends the hook while the foreground window matches, e.g. while gaming.
+type SuspendRule struct {
+	FullScreen bool     // Suspend while the foreground window covers its whole monitor
+	Processes  []string // Suspend while one of these executables is in the foreground, ignoring case
+}
+
+// Matches reports whether the rule suspends the hook while w is in the foreground.
+func (r SuspendRule) Matches(w shortcut.Window) bool {
+	if r.FullScreen && w.FullScreen {
+		return true
+	}
+	return len(r.Processes) > 0 && (&shortcut.WindowRule{Processes: r.Processes}).Matches(w)
+}
+
+// suspension is the suspend state of a hook. The hook is suspended by the toggle keys or while the
+// auto-suspend rule applies; the toggle also resumes an automatic suspension until the rule changes its mind.
+type suspension struct {
+	toggle    wtypes.KeyBinding // Keys that suspend and resume, active even while suspended
+	toggleKey wtypes.VirtualKey // Key that completed the toggle, blocked until it is released
+	rule      SuspendRule
+	manual    bool // Suspended by the toggle
+	auto      bool // The rule applies to the foreground window
+	dismissed bool // The toggle resumed while the rule applied
+	onChange  func(suspended bool)
+}
+
+// suspended reports whether the hook is suspended.
+func (s *suspension) suspended() bool {
+	return s.manual || (s.auto && !s.dismissed)
+}
+
+// flip suspends or resumes as the toggle keys do.
+func (s *suspension) flip() {
+	if s.suspended() {
+		s.manual = false
+		s.dismissed = s.auto
+	} else {
+		s.manual = true
+	}
+}
+
+// setAuto records whether the rule applies to the foreground window.
+func (s *suspension) setAuto(auto bool) {
+	if auto != s.auto {
+		s.auto = auto
+		s.dismissed = false
+	}
+}
+
+// SetSuspendToggle sets the keys that suspend and resume the hook; no keys disables the toggle.
+// The toggle is matched before layers, remaps and bindings, so it works in every mode and while suspended.
+func (h *Hook) SetSuspendToggle(keys wtypes.KeyBinding) {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	h.suspension.toggle = keys
+}
+
+// SetSuspendRule sets the foreground windows that suspend the hook while RunAutoSuspend runs.
+func (h *Hook) SetSuspendRule(rule SuspendRule) {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	h.suspension.rule = rule
+}
+
+// OnSuspendChange registers a function to call when the hook is suspended or resumed.
+// It may be called on the hook thread, so it must return quickly.
+func (h *Hook) OnSuspendChange(fn func(suspended bool)) {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	h.suspension.onChange = fn
+}
+
+// Suspended reports whether the hook is suspended, letting every key but the toggle through untouched.
+func (h *Hook) Suspended() bool {
+	h.stateMutex.Lock()
+	defer h.stateMutex.Unlock()
+	return h.suspension.suspended()
+}
+
+// ToggleSuspend suspends the hook, or resumes it if it is suspended.
+func (h *Hook) ToggleSuspend() {
+	h.updateSuspension("toggled", (*suspension).flip)
+}
+
+// RunAutoSuspend checks the foreground window against the suspend rule until ctx is cancelled.
+func (h *Hook) RunAutoSuspend(ctx context.Context, provider shortcut.WindowProvider) {
+	ticker := time.NewTicker(autoSuspendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.checkAutoSuspend(provider)
+		}
+	}
+}
+
+// checkAutoSuspend suspends or resumes the hook as the suspend rule applies to the foreground window.
+func (h *Hook) checkAutoSuspend(provider shortcut.WindowProvider) {
+	h.stateMutex.Lock()
+	rule := h.suspension.rule
+	h.stateMutex.Unlock()
+
+	auto := false
+	if rule.FullScreen || len(rule.Processes) > 0 {
+		w, err := provider.ForegroundWindow()
+		if err != nil {
+			return // Keep the current state rather than flicker on a window that went away
+		}
+		auto = rule.Matches(w)
+	}
+	h.updateSuspension("foreground window", func(s *suspension) { s.setAuto(auto) })
+}
+
+// updateSuspension applies change and, if that suspends or resumes the hook, resyncs the keys tracked so
+// far with the system and reports the change. While suspended the hook tracks keys untranslated, so held
+// layers and remaps do not carry over from one state to the other, but keys the system knows to be down,
+// such as the modifiers of the toggle, stay held.
+func (h *Hook) updateSuspension(reason string, change func(s *suspension)) {
+	h.stateMutex.Lock()
+	was := h.suspension.suspended()
+	change(&h.suspension)
+	suspended := h.suspension.suspended()
+	onChange := h.suspension.onChange
+	if suspended != was {
+		h.keyState.resync(h.query)
+		h.resetTranslation()
+	}
+	h.stateMutex.Unlock()
+
+	if suspended == was {
+		return
+	}
+	if suspended {
+		slog.Info("shortcuts suspended", "reason", reason)
+	} else {
+		slog.Info("shortcuts resumed", "reason", reason)
+	}
+	if onChange != nil {
+		onChange(suspended)
+	}
+}
+
+// gate decides the events of the toggle keys, and every event while the hook is suspended, which then
+// passes untouched. It reports whether it decided the event.
+func (h *Hook) gate(raw RawEvent) (Decision, bool) {
+	h.stateMutex.Lock()
+	s := &h.suspension
+	if s.toggleKey != 0 && raw.VKCode == s.toggleKey {
+		if !raw.KeyDown {
+			s.toggleKey = 0
+		}
+		h.stateMutex.Unlock()
+		return Block, true
+	}
+
+	held := h.keyState.pressed()
+	toggled := raw.KeyDown && len(s.toggle) > 0 && !raw.VKCode.IsModifier() &&
+		!slices.Contains(held, raw.VKCode) && s.toggle.Match(append(held, raw.VKCode))
+	suspended := s.suspended()
+	if toggled {
+		s.toggleKey = raw.VKCode
+		if slices.ContainsFunc(held, isMenuKey) {
+			h.suppressor.maskMenu()
+		}
+	}
+	h.stateMutex.Unlock()
+
+	switch {
+	case toggled:
+		h.ToggleSuspend()
+		return Block, true
+	case suspended:
+		h.keyState.update(raw.VKCode, raw.KeyDown)
+		return Pass, true
+	}
+	return Pass, false
+}
@@ -0,0 +1,94 @@
+package types
+
+import "strings"
+
+// KeyAliases maps friendly key names to virtual key codes, complementing the Windows names in KeyNameToVKCode.
+// Names are upper case; lookups are case-insensitive. Side-neutral modifier names resolve to the generic
+// modifiers, which match either the left or the right key.
+var KeyAliases = map[string]VirtualKey{
+	// Modifiers
+	"ALT":     VK_MENU,
+	"LALT":    VK_LMENU,
+	"RALT":    VK_RMENU,
+	"ALTGR":   VK_RMENU,
+	"CTRL":    VK_CONTROL,
+	"LCTRL":   VK_LCONTROL,
+	"RCTRL":   VK_RCONTROL,
+	"WINDOWS": VK_WIN,
+	// Editing and navigation
+	"ESC":         VK_ESCAPE,
+	"ENTER":       VK_RETURN,
+	"BACKSPACE":   VK_BACK,
+	"DEL":         VK_DELETE,
+	"INS":         VK_INSERT,
+	"PGUP":        VK_PRIOR,
+	"PAGEUP":      VK_PRIOR,
+	"PGDN":        VK_NEXT,
+	"PAGEDOWN":    VK_NEXT,
+	"CAPSLOCK":    VK_CAPITAL,
+	"SCROLLLOCK":  VK_SCROLL,
+	"PRINTSCREEN": VK_SNAPSHOT,
+	"PRTSC":       VK_SNAPSHOT,
+	"CONTEXTMENU": VK_APPS,
+	// Punctuation on a US layout
+	";":      VK_OEM_1,
+	"=":      VK_OEM_PLUS,
+	"PLUS":   VK_OEM_PLUS,
+	",":      VK_OEM_COMMA,
+	"COMMA":  VK_OEM_COMMA,
+	"-":      VK_OEM_MINUS,
+	"MINUS":  VK_OEM_MINUS,
+	".":      VK_OEM_PERIOD,
+	"PERIOD": VK_OEM_PERIOD,
+	"/":      VK_OEM_2,
+	"`":      VK_OEM_3,
+	"[":      VK_OEM_4,
+	"\\":     VK_OEM_5,
+	"]":      VK_OEM_6,
+	"'":      VK_OEM_7,
+	// Numeric keypad
+	"NUM0":        VK_NUMPAD0,
+	"NUM1":        VK_NUMPAD1,
+	"NUM2":        VK_NUMPAD2,
+	"NUM3":        VK_NUMPAD3,
+	"NUM4":        VK_NUMPAD4,
+	"NUM5":        VK_NUMPAD5,
+	"NUM6":        VK_NUMPAD6,
+	"NUM7":        VK_NUMPAD7,
+	"NUM8":        VK_NUMPAD8,
+	"NUM9":        VK_NUMPAD9,
+	"NUMMULTIPLY": VK_MULTIPLY,
+	"NUMADD":      VK_ADD,
+	"NUMSUBTRACT": VK_SUBTRACT,
+	"NUMDECIMAL":  VK_DECIMAL,
+	"NUMDIVIDE":   VK_DIVIDE,
+}
+
+// KeySeparator joins the keys of a binding in the compact string syntax, e.g. "Ctrl+Alt+F12".
+const KeySeparator = "+"
+
+// LookupKey resolves a key name or alias to its virtual key code, ignoring case.
+// Custom modifiers are resolved by the Modifiers table that defines them.
+func LookupKey(name string) (VirtualKey, bool) {
+	return (*Modifiers)(nil).LookupKey(name)
+}
+
+// KeyNames returns every accepted key name, including aliases, mapped to its virtual key code.
+func KeyNames() map[string]VirtualKey {
+	return (*Modifiers)(nil).KeyNames()
+}
+
+// SplitKeys splits the compact syntax "Alt+Shift+1" into key names.
+// Whitespace around the separator is ignored, so the output of PrettyString can be split as well.
+func SplitKeys(combo string) []string {
+	parts := strings.Split(combo, KeySeparator)
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// ParseKeyBinding parses a key combination in the compact syntax, for example "Ctrl+Alt+F12".
+func ParseKeyBinding(combo string) (KeyBinding, error) {
+	return (*Modifiers)(nil).ParseKeyBinding(combo)
+}
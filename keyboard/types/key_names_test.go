@@ -0,0 +1,94 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   VirtualKey
+		wantOK bool
+	}{
+		{name: "F12", want: VK_F12, wantOK: true},
+		{name: "f12", want: VK_F12, wantOK: true},
+		{name: "LMENU", want: VK_LMENU, wantOK: true},
+		{name: "LAlt", want: VK_LMENU, wantOK: true},
+		{name: "Alt", want: VK_MENU, wantOK: true},
+		{name: "Shift", want: VK_SHIFT, wantOK: true},
+		{name: "Win", want: VK_WIN, wantOK: true},
+		{name: "Esc", want: VK_ESCAPE, wantOK: true},
+		{name: "PgUp", want: VK_PRIOR, wantOK: true},
+		{name: "Num1", want: VK_NUMPAD1, wantOK: true},
+		{name: " Space ", want: VK_SPACE, wantOK: true},
+		{name: "Hyper", wantOK: false},
+		{name: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vk, ok := LookupKey(tt.name)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, vk)
+			}
+		})
+	}
+}
+
+func TestParseKeyBinding(t *testing.T) {
+	tests := []struct {
+		combo   string
+		want    KeyBinding
+		wantErr bool
+	}{
+		{combo: "Alt+Shift+1", want: KeyBinding{VK_MENU, VK_SHIFT, VK_1}},
+		{combo: "ctrl + lalt + f12", want: KeyBinding{VK_CONTROL, VK_LMENU, VK_F12}},
+		{combo: "Ctrl+-", want: KeyBinding{VK_CONTROL, VK_OEM_MINUS}},
+		{combo: "N", want: KeyBinding{VK_N}},
+		{combo: "Alt+", wantErr: true},
+		{combo: "Alt+Bogus", wantErr: true},
+		{combo: "Alt+alt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.combo, func(t *testing.T) {
+			kb, err := ParseKeyBinding(tt.combo)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, kb)
+		})
+	}
+}
+
+// TestParseKeyBindingRoundTrip verifies that PrettyString output parses back to the same keys
+func TestParseKeyBindingRoundTrip(t *testing.T) {
+	for _, kb := range []KeyBinding{
+		{VK_1, VK_LMENU},
+		{VK_LCONTROL, VK_RMENU, VK_F12},
+		{VK_LWIN, VK_LSHIFT, VK_OEM_PLUS},
+		{VK_WIN, VK_MENU, VK_SHIFT, VK_CONTROL, VK_LEFT},
+		{VK_NUMPAD5},
+	} {
+		parsed, err := ParseKeyBinding(kb.PrettyString())
+		require.NoError(t, err, kb.PrettyString())
+		assert.ElementsMatch(t, kb, parsed, kb.PrettyString())
+	}
+}
+
+// TestKeyNames verifies that every canonical name and alias is accepted
+func TestKeyNames(t *testing.T) {
+	names := KeyNames()
+	for name := range KeyNameToVKCode {
+		assert.Contains(t, names, name)
+	}
+	for name, vk := range KeyAliases {
+		assert.Equal(t, vk, names[name], name)
+	}
+}
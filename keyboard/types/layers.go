@@ -0,0 +1,124 @@
+package types
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// firstCustomModifier is the pseudo key code of the first modifier defined in a Modifiers table.
+// Custom modifiers follow VK_WIN, above the range of real virtual key codes.
+const firstCustomModifier = VK_WIN + 1
+
+// Modifiers is a table of custom modifiers, such as a "Hyper" layer on CapsLock. Each config builds its own
+// table, so validating a config never changes the keys another one accepts, and whatever formats keys for
+// display is handed the table of the config it belongs to. A nil table has no modifiers.
+type Modifiers struct {
+	byName map[string]VirtualKey
+	names  map[VirtualKey]string
+}
+
+// NewModifiers returns an empty modifier table.
+func NewModifiers() *Modifiers {
+	return &Modifiers{byName: make(map[string]VirtualKey), names: make(map[VirtualKey]string)}
+}
+
+// Define returns the pseudo key code of the custom modifier with the given name, defining it on first use.
+// The name then works in bindings like Alt or Ctrl, e.g. "Hyper+H". It must not be the name of an existing key.
+// Codes are handed out in the order names are defined.
+func (m *Modifiers) Define(name string) (VirtualKey, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if vk, ok := m.byName[upper]; ok {
+		return vk, nil
+	}
+	if upper == "" || strings.Contains(upper, KeySeparator) {
+		return 0, fmt.Errorf("invalid modifier name %q", name)
+	}
+	if _, ok := KeyAliases[upper]; ok {
+		return 0, fmt.Errorf("modifier name %q is already a key name", name)
+	}
+	if _, ok := KeyNameToVKCode[upper]; ok {
+		return 0, fmt.Errorf("modifier name %q is already a key name", name)
+	}
+
+	vk := firstCustomModifier + VirtualKey(len(m.byName))
+	m.byName[upper] = vk
+	m.names[vk] = upper
+	return vk, nil
+}
+
+// LookupKey resolves a key name, alias or custom modifier of the table to its virtual key code, ignoring case.
+func (m *Modifiers) LookupKey(name string) (VirtualKey, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if vk, ok := KeyAliases[name]; ok {
+		return vk, true
+	}
+	if vk, ok := KeyNameToVKCode[name]; ok {
+		return vk, true
+	}
+	if m == nil {
+		return 0, false
+	}
+	vk, ok := m.byName[name]
+	return vk, ok
+}
+
+// KeyNames returns every accepted key name, including aliases and the custom modifiers of the table,
+// mapped to its virtual key code.
+func (m *Modifiers) KeyNames() map[string]VirtualKey {
+	names := make(map[string]VirtualKey, len(KeyNameToVKCode)+len(KeyAliases))
+	maps.Copy(names, KeyNameToVKCode)
+	maps.Copy(names, KeyAliases)
+	if m != nil {
+		maps.Copy(names, m.byName)
+	}
+	return names
+}
+
+// ParseKeyBinding parses a key combination in the compact syntax, for example "Hyper+Alt+F12",
+// accepting the custom modifiers of the table.
+func (m *Modifiers) ParseKeyBinding(combo string) (KeyBinding, error) {
+	var kb KeyBinding
+	for _, name := range SplitKeys(combo) {
+		if name == "" {
+			return nil, fmt.Errorf("empty key name in %q", combo)
+		}
+		vk, ok := m.LookupKey(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q in %q", name, combo)
+		}
+		if kb.Contains(vk) {
+			return nil, fmt.Errorf("duplicate key %q in %q", name, combo)
+		}
+		kb = append(kb, vk)
+	}
+	return kb, nil
+}
+
+// IsCustomModifier reports whether vk is the pseudo key code of a custom modifier.
+func (vk VirtualKey) IsCustomModifier() bool {
+	return vk >= firstCustomModifier
+}
+
+// KeyName returns the name of vk like VirtualKey.KeybindName, naming the custom modifiers of the table too.
+func (m *Modifiers) KeyName(vk VirtualKey) string {
+	if m != nil {
+		if name, ok := m.names[vk]; ok {
+			return name
+		}
+	}
+	return vk.KeybindName()
+}
+
+// FormatBinding formats kb like KeyBinding.PrettyString, naming the custom modifiers of the table too.
+// Unlike PrettyString, it leaves kb in its order.
+func (m *Modifiers) FormatBinding(kb KeyBinding) string {
+	keys := slices.Clone(kb)
+	KeybindSort(keys)
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = m.KeyName(key)
+	}
+	return strings.Join(names, " + ")
+}
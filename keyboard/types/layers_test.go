@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModifiers verifies that a custom modifier works like a built-in one in the table that defines it.
+func TestModifiers(t *testing.T) {
+	assert := assert.New(t)
+
+	modifiers := NewModifiers()
+	hyper, err := modifiers.Define("Hyper")
+	require.NoError(t, err)
+	again, err := modifiers.Define(" hyper ")
+	require.NoError(t, err)
+	assert.Equal(hyper, again, "Defining a modifier twice should return the same code")
+	assert.Greater(hyper, VK_WIN)
+
+	assert.True(hyper.IsModifier())
+	assert.True(hyper.IsCustomModifier())
+	assert.False(VK_LMENU.IsCustomModifier())
+
+	binding, err := modifiers.ParseKeyBinding("h+Hyper+LAlt")
+	require.NoError(t, err)
+	assert.Equal(KeyBinding{VK_H, hyper, VK_LMENU}, binding)
+	assert.True(binding.Match([]VirtualKey{VK_LMENU, hyper, VK_H}))
+	assert.False(binding.Match([]VirtualKey{VK_LMENU, VK_H}))
+	assert.Contains(modifiers.KeyNames(), "HYPER")
+
+	for _, name := range []string{"", "Esc", "F1", "Hyper+Super"} {
+		_, err := modifiers.Define(name)
+		assert.Error(err, "%q should not be accepted as a modifier name", name)
+	}
+
+	// Another table, like the one of a config being validated, knows nothing of the modifier
+	_, err = NewModifiers().ParseKeyBinding("Hyper+H")
+	assert.Error(err)
+	_, err = ParseKeyBinding("Hyper+H")
+	assert.Error(err)
+	assert.NotContains(KeyNames(), "HYPER")
+
+	assert.Equal("HYPER", modifiers.KeyName(hyper))
+	assert.Equal("LMENU", modifiers.KeyName(VK_LMENU))
+	assert.Equal("LMENU + HYPER + H", modifiers.FormatBinding(binding), "Custom modifiers should sort after built-in ones and before keys")
+	assert.Equal(KeyBinding{VK_H, hyper, VK_LMENU}, binding, "Formatting should not reorder the binding")
+	assert.NotEqual("HYPER", NewModifiers().KeyName(hyper), "Only the defining table should name the modifier")
+}
@@ -0,0 +1,141 @@
+package types
+
+import "slices"
+
+// VK_WIN is a pseudo key code for either Windows key. Windows defines generic codes for Shift, Ctrl and Alt
+// but not for the Windows key, so it is placed above the range of real virtual key codes.
+const VK_WIN VirtualKey = 0x100
+
+// GenericModifiers maps each side-specific modifier to its side-agnostic counterpart.
+var GenericModifiers = map[VirtualKey]VirtualKey{
+	VK_LSHIFT:   VK_SHIFT,
+	VK_RSHIFT:   VK_SHIFT,
+	VK_LCONTROL: VK_CONTROL,
+	VK_RCONTROL: VK_CONTROL,
+	VK_LMENU:    VK_MENU,
+	VK_RMENU:    VK_MENU,
+	VK_LWIN:     VK_WIN,
+	VK_RWIN:     VK_WIN,
+}
+
+func init() {
+	KeyNameToVKCode["WIN"] = VK_WIN
+	VKCodeToKeyName[VK_WIN] = "WIN"
+	for _, generic := range GenericModifiers {
+		VKModifierMap[generic] = true
+	}
+}
+
+// Generic returns the side-agnostic modifier for a side-specific one, e.g. VK_MENU for VK_RMENU.
+func (vk VirtualKey) Generic() (VirtualKey, bool) {
+	generic, ok := GenericModifiers[vk]
+	return generic, ok
+}
+
+// IsGeneric reports whether the key is a side-agnostic modifier that matches either side.
+func (vk VirtualKey) IsGeneric() bool {
+	return vk == VK_SHIFT || vk == VK_CONTROL || vk == VK_MENU || vk == VK_WIN
+}
+
+// Satisfies reports whether pressing vk satisfies the binding key want:
+// either they are the same key or want is the generic form of vk.
+func (vk VirtualKey) Satisfies(want VirtualKey) bool {
+	if vk == want {
+		return true
+	}
+	generic, ok := vk.Generic()
+	return ok && generic == want
+}
+
+// keyGroup is what a binding asks of one key or of the two sides of one modifier.
+type keyGroup struct {
+	generic bool         // The generic modifier is part of the binding, so either side satisfies it
+	sides   []VirtualKey // Side-specific keys, or the key itself for keys without sides
+}
+
+// groups gathers the keys of the binding by their generic form.
+func (kb KeyBinding) groups() map[VirtualKey]*keyGroup {
+	groups := make(map[VirtualKey]*keyGroup)
+	group := func(key VirtualKey) *keyGroup {
+		if groups[key] == nil {
+			groups[key] = &keyGroup{}
+		}
+		return groups[key]
+	}
+	for _, key := range kb {
+		switch generic, ok := key.Generic(); {
+		case key.IsGeneric():
+			group(key).generic = true
+		case ok:
+			group(generic).sides = append(group(generic).sides, key)
+		default:
+			group(key).sides = append(group(key).sides, key)
+		}
+	}
+	return groups
+}
+
+// subsetOf reports whether every key of keys is in other.
+func subsetOf(keys, other []VirtualKey) bool {
+	for _, key := range keys {
+		if !slices.Contains(other, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// Overlaps reports whether some combination of pressed keys matches both bindings.
+// "Alt+1" and "LAlt+1" overlap since both match LAlt+1, while "LAlt+1" and "RAlt+1" never match the same keys.
+func (kb KeyBinding) Overlaps(other KeyBinding) bool {
+	groups, otherGroups := kb.groups(), other.groups()
+	if len(groups) != len(otherGroups) {
+		return false
+	}
+	for key, group := range groups {
+		otherGroup, ok := otherGroups[key]
+		if !ok {
+			return false
+		}
+		switch {
+		case group.generic && otherGroup.generic:
+		case group.generic:
+			// Only the side-specific keys of other may be pressed, so they must include those kb asks for
+			if !subsetOf(group.sides, otherGroup.sides) {
+				return false
+			}
+		case otherGroup.generic:
+			if !subsetOf(otherGroup.sides, group.sides) {
+				return false
+			}
+		default:
+			if !subsetOf(group.sides, otherGroup.sides) || !subsetOf(otherGroup.sides, group.sides) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// CoveredBy reports whether every combination of pressed keys matching kb also matches other,
+// e.g. "LAlt+1" is covered by "Alt+1" but not the other way around.
+func (kb KeyBinding) CoveredBy(other KeyBinding) bool {
+	groups, otherGroups := kb.groups(), other.groups()
+	if len(groups) != len(otherGroups) {
+		return false
+	}
+	for key, group := range groups {
+		otherGroup, ok := otherGroups[key]
+		if !ok {
+			return false
+		}
+		if otherGroup.generic {
+			if !subsetOf(otherGroup.sides, group.sides) {
+				return false
+			}
+		} else if group.generic || !subsetOf(group.sides, otherGroup.sides) || !subsetOf(otherGroup.sides, group.sides) {
+			return false
+		}
+	}
+	return true
+}
@@ -35,9 +35,10 @@ func (vk VirtualKey) KeybindName() string {
 	return fmt.Sprintf("%#x", vk)
 }
 
+// IsModifier reports whether vk is Shift, Ctrl, Alt, Win or a custom modifier.
 func (vk VirtualKey) IsModifier() bool {
 	_, exists := VKModifierMap[vk]
-	return exists
+	return exists || vk.IsCustomModifier()
 }
 
 type KeyBinding []VirtualKey
@@ -47,15 +48,19 @@ func NewKeybinding(vks ...VirtualKey) KeyBinding {
 }
 
 
-// ExactMatch checks if the keybinding only contains the same keys and nothing else.
-// The keys may be in any order.
+// Match checks if exactly the keys of the binding are pressed and nothing else.
+// The keys may be in any order. A generic modifier in the binding (VK_MENU, VK_SHIFT, VK_CONTROL, VK_WIN)
+// is satisfied by either the left or the right key.
 func (kb KeyBinding) Match(match []VirtualKey) bool {
-	if len(kb) != len(match) {
-		return false
+	for _, key := range kb {
+		if !slices.ContainsFunc(match, func(pressed VirtualKey) bool { return pressed.Satisfies(key) }) {
+			return false
+		}
 	}
 
-	for _, key := range kb {
-		if !slices.Contains[KeyBinding](match,key){
+	// Every pressed key must be accounted for by the binding.
+	for _, pressed := range match {
+		if !slices.ContainsFunc(kb, pressed.Satisfies) {
 			return false
 		}
 	}
@@ -63,6 +68,18 @@ func (kb KeyBinding) Match(match []VirtualKey) bool {
 	return true
 }
 
+// Specificity counts the side-specific keys of the binding.
+// When several bindings match the same keys, the most specific one wins, so "LAlt+1" beats "Alt+1".
+func (kb KeyBinding) Specificity() int {
+	specific := 0
+	for _, key := range kb {
+		if !key.IsGeneric() {
+			specific++
+		}
+	}
+	return specific
+}
+
 func (kb KeyBinding) SubsetOf(match KeyBinding) bool {
 	if len(kb) > len(match) {
 		return false
@@ -118,7 +135,7 @@ func (kb KeyBinding) KeybindName() string {
 }
 
 // VirtualKeySorter sorts VirtualKey in a nice printable order. 
-// So that modifiers are first and by alphabetical order. 
+// So that modifiers are first and by alphabetical order, custom modifiers such as Hyper after the built-in ones. 
 // Then the rest of the keys are sorted alphabetically.
 func KeybindSort(vks []VirtualKey) {
 	sort.Slice(vks, func(i, j int) bool {
@@ -132,7 +149,7 @@ func KeybindSort(vks []VirtualKey) {
 	})
 }
 
+// GetVirtualKey resolves a key name or alias, see LookupKey.
 func GetVirtualKey(name string) (VirtualKey, bool) {
-	vk, exists := KeyNameToVKCode[name]
-	return vk, exists
+	return LookupKey(name)
 }
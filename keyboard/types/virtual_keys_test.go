@@ -87,6 +87,39 @@ func (s *KeyBindingTestSuite) TestKeyBindingMatch() {
 	s.False(binding.Match([]VirtualKey{VK_LMENU}), "Should not match subset of keys")
 }
 
+func (s *KeyBindingTestSuite) TestKeyBindingMatchGeneric() {
+	binding := NewKeybinding(VK_MENU, VK_1)
+
+	s.True(binding.Match([]VirtualKey{VK_LMENU, VK_1}), "Generic Alt should match left Alt")
+	s.True(binding.Match([]VirtualKey{VK_RMENU, VK_1}), "Generic Alt should match right Alt")
+	s.False(binding.Match([]VirtualKey{VK_LCONTROL, VK_1}), "Generic Alt should not match Ctrl")
+	s.False(NewKeybinding(VK_LMENU, VK_1).Match([]VirtualKey{VK_RMENU, VK_1}), "Left Alt should not match right Alt")
+}
+
+func (s *KeyBindingTestSuite) TestKeyBindingSpecificity() {
+	s.Equal(2, NewKeybinding(VK_LMENU, VK_1).Specificity())
+	s.Equal(1, NewKeybinding(VK_MENU, VK_1).Specificity())
+	s.Equal(0, NewKeybinding(VK_WIN, VK_SHIFT).Specificity())
+}
+
+func (s *KeyBindingTestSuite) TestKeyBindingOverlaps() {
+	altOne := NewKeybinding(VK_MENU, VK_1)
+	leftAltOne := NewKeybinding(VK_LMENU, VK_1)
+	rightAltOne := NewKeybinding(VK_RMENU, VK_1)
+
+	s.True(altOne.Overlaps(leftAltOne), "Alt+1 and LAlt+1 both match LAlt+1")
+	s.True(leftAltOne.Overlaps(altOne))
+	s.False(leftAltOne.Overlaps(rightAltOne), "Left and right Alt are never the same keys")
+	s.False(altOne.Overlaps(NewKeybinding(VK_MENU, VK_2)))
+	s.False(altOne.Overlaps(NewKeybinding(VK_MENU, VK_SHIFT, VK_1)), "Extra keys make a different combination")
+	s.True(NewKeybinding(VK_MENU, VK_LMENU, VK_1).Overlaps(NewKeybinding(VK_LMENU, VK_RMENU, VK_1)))
+
+	s.True(leftAltOne.CoveredBy(altOne), "Whatever matches LAlt+1 matches Alt+1")
+	s.False(altOne.CoveredBy(leftAltOne), "RAlt+1 matches Alt+1 but not LAlt+1")
+	s.True(altOne.CoveredBy(NewKeybinding(VK_1, VK_MENU)), "Order does not matter")
+	s.False(leftAltOne.CoveredBy(rightAltOne))
+}
+
 func (s *KeyBindingTestSuite) TestKeyBindingSubsetOf() {
 	subset := NewKeybinding(VK_LMENU)
 	fullSet := NewKeybinding(VK_LMENU, VK_1)
@@ -6,17 +6,21 @@ package systray
 import (
 	"context"
 	"log/slog"
+	"strings"
 	"sync"
 	"wincuts/config"
 )
 
 // Service manages the system tray icon and updates
 type Service struct {
-	icon    *Icon
-	current int
-	mu      sync.RWMutex
-	ctx     context.Context
-	cancel  context.CancelFunc
+	icon      *Icon
+	current   int
+	mode      string // Active binding mode, empty for the default bindings
+	pending   string // Steps of a key sequence in progress
+	suspended bool   // Shortcuts are suspended
+	mu        sync.RWMutex
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 // NewService creates a new system tray service
@@ -59,6 +63,65 @@ func (s *Service) UpdateDesktop(num int) error {
 	return nil
 }
 
+// SetPending shows the steps of a key sequence in progress in the tooltip; an empty string clears them.
+func (s *Service) SetPending(steps string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = steps
+	return s.icon.SetStatus(s.status(), s.current)
+}
+
+// SetMode shows the active binding mode in the tooltip; an empty string clears it.
+func (s *Service) SetMode(mode string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mode = mode
+	return s.icon.SetStatus(s.status(), s.current)
+}
+
+// SetSuspended greys out the icon and notes in the tooltip that shortcuts are suspended, or restores both.
+func (s *Service) SetSuspended(suspended bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.suspended = suspended
+	if err := s.icon.SetGreyed(suspended, s.current); err != nil {
+		return err
+	}
+	return s.icon.SetStatus(s.status(), s.current)
+}
+
+// OnSuspendMenu adds a "Suspend shortcuts" entry to the tray menu that calls fn, checked while suspended.
+// fn is called on the tray's own thread.
+func (s *Service) OnSuspendMenu(fn func()) {
+	s.icon.SetOnSuspend(fn)
+}
+
+// status builds the tooltip status line from the suspend state, the mode and the pending key sequence.
+func (s *Service) status() string {
+	var parts []string
+	if s.suspended {
+		parts = append(parts, "Suspended")
+	}
+	if s.mode != "" {
+		parts = append(parts, "Mode: "+s.mode)
+	}
+	if s.pending != "" {
+		parts = append(parts, s.pending+" …")
+	}
+	return strings.Join(parts, " | ")
+}
+
+// UpdateConfig applies new tray icon styling and redraws the current desktop number
+func (s *Service) UpdateConfig(cfg config.TrayIconConfig) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.icon.SetConfig(cfg, s.current)
+}
+
 // Stop cleans up resources and removes the system tray icon
 func (s *Service) Stop() error {
 	s.mu.Lock()
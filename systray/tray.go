@@ -11,6 +11,7 @@ import (
 	"image/color"
 	"image/draw"
 	"log/slog"
+	"runtime"
 	"sync"
 	"syscall"
 	"unsafe"
@@ -27,9 +28,12 @@ type Icon struct {
 	hwnd        win.HWND
 	nid         *win.NOTIFYICONDATA
 	currentText string
+	status      string            // Extra tooltip line, such as a pending key sequence
 	iconCache   map[int]win.HICON // Cache for rendered icons
 	mu          sync.Mutex
 	config      config.TrayIconConfig
+	greyed      bool   // Shortcuts are suspended, so the icon is drawn in grey
+	onSuspend   func() // Called by the "Suspend shortcuts" menu entry; without it the icon has no menu
 }
 
 const (
@@ -47,6 +51,8 @@ const (
 	nifTip     = win.NIF_TIP
 	// System commands
 	scMinimize = 0xF020
+	// Menu commands
+	menuSuspend = 1
 	// Icon size
 	iconSize     = 22  // Slightly larger for better visibility
 	cornerRadius = 4   // Rounded corners
@@ -140,6 +146,9 @@ func drawRoundedRect(img *image.RGBA, col color.Color, radius int) {
 // createIconWithNumber creates an HICON with the desktop number drawn on it
 func (i *Icon) createIconWithNumber(number int) (win.HICON, error) {
 	cfg := i.config
+	if i.greyed {
+		cfg = greyedConfig(cfg)
+	}
 	// Create a new RGBA image with slightly larger size for better text rendering
 	size := cfg.Size
 	img := image.NewRGBA(image.Rect(0, 0, size, size))
@@ -185,6 +194,19 @@ func (i *Icon) createIconWithNumber(number int) (win.HICON, error) {
 	return createHICONFromImage(img), nil
 }
 
+// greyedConfig desaturates the icon colors and fades the background, for the icon shown while shortcuts are suspended.
+func greyedConfig(cfg config.TrayIconConfig) config.TrayIconConfig {
+	grey := func(c color.RGBA) color.RGBA {
+		y := uint8((299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000)
+		return color.RGBA{y, y, y, c.A}
+	}
+	cfg.BgColor = grey(cfg.BgColor)
+	cfg.TextColor = grey(cfg.TextColor)
+	cfg.ShadowColor = grey(cfg.ShadowColor)
+	cfg.BgOpacity /= 2
+	return cfg
+}
+
 // createHICONFromImage converts an RGBA image to HICON
 func createHICONFromImage(img *image.RGBA) win.HICON {
 	// Create bitmap info header
@@ -244,54 +266,128 @@ func createHICONFromImage(img *image.RGBA) win.HICON {
 }
 
 // windowProc handles window messages
-func windowProc(hwnd win.HWND, msg uint32, wparam, lparam uintptr) uintptr {
+func (i *Icon) windowProc(hwnd win.HWND, msg uint32, wparam, lparam uintptr) uintptr {
 	switch msg {
 	case win.WM_DESTROY:
 		win.PostQuitMessage(0)
 		return 0
 	case wmTrayCallback:
+		// The mouse message on the icon is in the low word
+		if uint32(lparam&0xFFFF) == win.WM_RBUTTONUP {
+			i.showMenu(hwnd)
+		}
 		return 0
 	default:
 		return win.DefWindowProc(hwnd, msg, wparam, lparam)
 	}
 }
 
-// New creates a new system tray icon
-func New(cfg config.TrayIconConfig) (*Icon, error) {
-	// Register window class
-	className := syscall.StringToUTF16Ptr("WinCutsSystemTray")
-	wc := win.WNDCLASSEX{
-		CbSize:        uint32(unsafe.Sizeof(win.WNDCLASSEX{})),
-		LpfnWndProc:   syscall.NewCallback(windowProc),
-		HInstance:     win.GetModuleHandle(nil),
-		LpszClassName: className,
+// showMenu shows the tray menu at the cursor and runs the chosen entry.
+func (i *Icon) showMenu(hwnd win.HWND) {
+	i.mu.Lock()
+	suspended, onSuspend := i.greyed, i.onSuspend
+	i.mu.Unlock()
+	if onSuspend == nil {
+		return
 	}
 
-	if atom := win.RegisterClassEx(&wc); atom == 0 {
-		return nil, fmt.Errorf("failed to register window class")
+	menu := win.CreatePopupMenu()
+	defer win.DestroyMenu(menu)
+	item := win.MENUITEMINFO{
+		FMask:      win.MIIM_ID | win.MIIM_STRING | win.MIIM_STATE,
+		WID:        menuSuspend,
+		DwTypeData: syscall.StringToUTF16Ptr("Suspend shortcuts"),
+	}
+	if suspended {
+		item.FState = win.MFS_CHECKED
+	}
+	item.CbSize = uint32(unsafe.Sizeof(item))
+	win.InsertMenuItem(menu, 0, true, &item)
+
+	var cursor win.POINT
+	win.GetCursorPos(&cursor)
+	// The menu only closes when clicking elsewhere if its window is in the foreground
+	win.SetForegroundWindow(hwnd)
+	command := win.TrackPopupMenu(menu, win.TPM_RETURNCMD|win.TPM_RIGHTBUTTON, cursor.X, cursor.Y, 0, hwnd, nil)
+	win.PostMessage(hwnd, win.WM_NULL, 0, 0)
+	if command == menuSuspend {
+		onSuspend()
 	}
+}
+
+// SetOnSuspend adds a "Suspend shortcuts" entry to the menu opened by right-clicking the icon, which calls fn.
+// The entry is checked while the icon is greyed out. fn is called on the thread of the icon's window.
+func (i *Icon) SetOnSuspend(fn func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.onSuspend = fn
+}
 
-	// Create window
-	hwnd := win.CreateWindowEx(
-		0,
-		className,
-		syscall.StringToUTF16Ptr("WinCuts"),
-		0,
-		0, 0, 0, 0,
-		0,
-		0,
-		win.GetModuleHandle(nil),
-		nil)
+// startWindow creates the window receiving the messages of the icon. Window messages are delivered to the
+// thread that created the window, so it is created on a thread of its own pumping them until it is destroyed.
+func (i *Icon) startWindow() (win.HWND, error) {
+	started := make(chan win.HWND)
+	failed := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		// Register window class
+		className := syscall.StringToUTF16Ptr("WinCutsSystemTray")
+		wc := win.WNDCLASSEX{
+			CbSize:        uint32(unsafe.Sizeof(win.WNDCLASSEX{})),
+			LpfnWndProc:   syscall.NewCallback(i.windowProc),
+			HInstance:     win.GetModuleHandle(nil),
+			LpszClassName: className,
+		}
+		if atom := win.RegisterClassEx(&wc); atom == 0 {
+			failed <- fmt.Errorf("failed to register window class")
+			return
+		}
+
+		// Create window
+		hwnd := win.CreateWindowEx(
+			0,
+			className,
+			syscall.StringToUTF16Ptr("WinCuts"),
+			0,
+			0, 0, 0, 0,
+			0,
+			0,
+			win.GetModuleHandle(nil),
+			nil)
+		if hwnd == 0 {
+			failed <- fmt.Errorf("failed to create window")
+			return
+		}
+		started <- hwnd
 
-	if hwnd == 0 {
-		return nil, fmt.Errorf("failed to create window")
+		var msg win.MSG
+		for win.GetMessage(&msg, 0, 0, 0) > 0 {
+			win.TranslateMessage(&msg)
+			win.DispatchMessage(&msg)
+		}
+	}()
+
+	select {
+	case hwnd := <-started:
+		return hwnd, nil
+	case err := <-failed:
+		return 0, err
 	}
+}
 
+// New creates a new system tray icon
+func New(cfg config.TrayIconConfig) (*Icon, error) {
 	icon := &Icon{
-		hwnd:      hwnd,
 		iconCache: make(map[int]win.HICON),
 		config:    cfg,
 	}
+	hwnd, err := icon.startWindow()
+	if err != nil {
+		return nil, err
+	}
+	icon.hwnd = hwnd
 
 	// Create initial icon
 	hIcon, err := icon.createIconWithNumber(1)
@@ -324,7 +420,7 @@ func (i *Icon) UpdateText(desktopNum int) error {
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
-	text := fmt.Sprintf("Desktop %d", desktopNum)
+	text := i.tooltip(desktopNum)
 	if text == i.currentText {
 		return nil
 	}
@@ -344,7 +440,7 @@ func (i *Icon) UpdateText(desktopNum int) error {
 
 	// Update icon and tooltip
 	i.nid.HIcon = hIcon
-	copy(i.nid.SzTip[:], syscall.StringToUTF16(text))
+	i.setTip(text)
 
 	if !win.Shell_NotifyIcon(win.NIM_MODIFY, i.nid) {
 		return fmt.Errorf("failed to update system tray icon")
@@ -355,6 +451,92 @@ func (i *Icon) UpdateText(desktopNum int) error {
 	return nil
 }
 
+// SetStatus shows status on a second tooltip line below the desktop number; an empty status removes the line.
+func (i *Icon) SetStatus(status string, desktopNum int) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.status = status
+	text := i.tooltip(desktopNum)
+	if text == i.currentText {
+		return nil
+	}
+
+	i.setTip(text)
+	if !win.Shell_NotifyIcon(win.NIM_MODIFY, i.nid) {
+		return fmt.Errorf("failed to update system tray tooltip")
+	}
+
+	i.currentText = text
+	return nil
+}
+
+// tooltip builds the tooltip text for the desktop number and the current status.
+func (i *Icon) tooltip(desktopNum int) string {
+	text := fmt.Sprintf("Desktop %d", desktopNum)
+	if i.status != "" {
+		text += "\n" + i.status
+	}
+	return text
+}
+
+// setTip copies text into the tooltip buffer, truncating it so the terminating NUL always fits.
+func (i *Icon) setTip(text string) {
+	tip := syscall.StringToUTF16(text)
+	if len(tip) > len(i.nid.SzTip) {
+		tip = append(tip[:len(i.nid.SzTip)-1], 0)
+	}
+	copy(i.nid.SzTip[:], tip)
+}
+
+// SetConfig replaces the icon styling and redraws the icon for the given desktop number.
+// Cached icons were rendered with the old style, so they are destroyed once the new icon is shown.
+func (i *Icon) SetConfig(cfg config.TrayIconConfig, desktopNum int) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.config = cfg
+	return i.redraw(desktopNum)
+}
+
+// SetGreyed switches between the normal icon and the grey one shown while shortcuts are suspended.
+func (i *Icon) SetGreyed(greyed bool, desktopNum int) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.greyed == greyed {
+		return nil
+	}
+	i.greyed = greyed
+	return i.redraw(desktopNum)
+}
+
+// redraw shows a newly rendered icon for the desktop number and destroys the cached ones,
+// which were rendered with the old style. The caller holds i.mu.
+func (i *Icon) redraw(desktopNum int) error {
+	stale := i.iconCache
+	i.iconCache = make(map[int]win.HICON)
+
+	hIcon, err := i.createIconWithNumber(desktopNum)
+	if err != nil {
+		return fmt.Errorf("failed to create icon: %w", err)
+	}
+	i.iconCache[desktopNum] = hIcon
+
+	i.nid.HIcon = hIcon
+	if !win.Shell_NotifyIcon(win.NIM_MODIFY, i.nid) {
+		return fmt.Errorf("failed to update system tray icon")
+	}
+
+	for _, old := range stale {
+		if old != 0 {
+			win.DestroyIcon(old)
+		}
+	}
+	slog.Debug("redrew tray icon", "desktop", desktopNum, "greyed", i.greyed)
+	return nil
+}
+
 // Close removes the system tray icon and cleans up resources
 func (i *Icon) Close() error {
 	i.mu.Lock()
@@ -371,6 +553,7 @@ func (i *Icon) Close() error {
 		}
 	}
 
-	win.DestroyWindow(i.hwnd)
+	// Only the window's own thread can destroy it
+	win.PostMessage(i.hwnd, win.WM_CLOSE, 0, 0)
 	return nil
 }
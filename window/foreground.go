@@ -0,0 +1,178 @@
+//go:build windows
+
+package window
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"wincuts/keyboard/shortcut"
+
+	"github.com/lxn/win"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32DLL          = windows.NewLazySystemDLL("user32.dll")
+	procGetWindowTextW = user32DLL.NewProc("GetWindowTextW")
+)
+
+// ForegroundProvider reports the foreground window to per-application bindings.
+// It only needs user32 and kernel32, unlike Service which also loads VirtualDesktopAccessor.dll.
+type ForegroundProvider struct{}
+
+// ForegroundWindow implements shortcut.WindowProvider.
+func (p ForegroundProvider) ForegroundWindow() (shortcut.Window, error) {
+	return p.DescribeWindow(p.ForegroundHandle())
+}
+
+// ForegroundHandle implements shortcut.WindowDescriber. It is a single user32 call, safe on the keyboard hook thread.
+func (ForegroundProvider) ForegroundHandle() uintptr {
+	return uintptr(windows.GetForegroundWindow())
+}
+
+// DescribeWindow implements shortcut.WindowDescriber, reading the class, title and process of the window.
+func (ForegroundProvider) DescribeWindow(handle uintptr) (shortcut.Window, error) {
+	hwnd := windows.HWND(handle)
+	if hwnd == 0 {
+		return shortcut.Window{}, fmt.Errorf("no foreground window")
+	}
+
+	w := shortcut.Window{Handle: handle}
+	var class [256]uint16
+	if n, err := windows.GetClassName(hwnd, &class[0], int32(len(class))); err == nil {
+		w.Class = windows.UTF16ToString(class[:n])
+	}
+
+	var title [512]uint16
+	n, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	w.Title = windows.UTF16ToString(title[:n])
+
+	w.FullScreen = isFullScreen(hwnd, w.Class)
+
+	process, err := processName(hwnd)
+	if err != nil {
+		return w, err
+	}
+	w.Process = process
+	return w, nil
+}
+
+// WatchForeground calls onChange whenever another window comes to the foreground or the foreground window
+// changes its title, until stop is called. onChange runs on the watcher's own thread, never on the keyboard hook,
+// so it may describe the window, e.g. WindowCache.Refresh.
+func WatchForeground(onChange func()) (stop func(), err error) {
+	eventProc := func(_ win.HWINEVENTHOOK, event uint32, hwnd win.HWND, idObject, _ int32, _, _ uint32) uintptr {
+		// Title changes of other windows and of their child objects don't matter
+		if event == win.EVENT_OBJECT_NAMECHANGE && (idObject != win.OBJID_WINDOW || hwnd != win.GetForegroundWindow()) {
+			return 0
+		}
+		onChange()
+		return 0
+	}
+	wndProc := func(hwnd win.HWND, msg uint32, wParam, lParam uintptr) uintptr {
+		if msg == win.WM_DESTROY {
+			win.PostQuitMessage(0)
+			return 0
+		}
+		return win.DefWindowProc(hwnd, msg, wParam, lParam)
+	}
+
+	started := make(chan win.HWND)
+	failed := make(chan error)
+	go func() {
+		// Out-of-context events are delivered to the message loop of the thread that set the hook
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		className := syscall.StringToUTF16Ptr("WinCutsForegroundWatcher")
+		wc := win.WNDCLASSEX{
+			CbSize:        uint32(unsafe.Sizeof(win.WNDCLASSEX{})),
+			LpfnWndProc:   syscall.NewCallback(wndProc),
+			HInstance:     win.GetModuleHandle(nil),
+			LpszClassName: className,
+		}
+		if atom := win.RegisterClassEx(&wc); atom == 0 {
+			failed <- fmt.Errorf("failed to register foreground watcher window class")
+			return
+		}
+		hwnd := win.CreateWindowEx(0, className, nil, 0, 0, 0, 0, 0, win.HWND_MESSAGE, 0, win.GetModuleHandle(nil), nil)
+		if hwnd == 0 {
+			failed <- fmt.Errorf("failed to create foreground watcher window")
+			return
+		}
+		var hooks []win.HWINEVENTHOOK
+		for _, event := range []uint32{win.EVENT_SYSTEM_FOREGROUND, win.EVENT_OBJECT_NAMECHANGE} {
+			hook, err := win.SetWinEventHook(event, event, 0, eventProc, 0, 0, win.WINEVENT_OUTOFCONTEXT)
+			if hook == 0 {
+				for _, h := range hooks {
+					win.UnhookWinEvent(h)
+				}
+				win.DestroyWindow(hwnd)
+				failed <- fmt.Errorf("failed to hook foreground events: %w", err)
+				return
+			}
+			hooks = append(hooks, hook)
+		}
+		defer func() {
+			for _, h := range hooks {
+				win.UnhookWinEvent(h)
+			}
+		}()
+		started <- hwnd
+
+		var msg win.MSG
+		for win.GetMessage(&msg, 0, 0, 0) > 0 {
+			win.TranslateMessage(&msg)
+			win.DispatchMessage(&msg)
+		}
+	}()
+
+	select {
+	case hwnd := <-started:
+		return func() { win.PostMessage(hwnd, win.WM_CLOSE, 0, 0) }, nil
+	case err := <-failed:
+		return nil, err
+	}
+}
+
+// isFullScreen reports whether hwnd covers its whole monitor. The desktop itself does too, but is never full-screen.
+func isFullScreen(hwnd windows.HWND, class string) bool {
+	if class == "Progman" || class == "WorkerW" {
+		return false
+	}
+	var rect win.RECT
+	if !win.GetWindowRect(win.HWND(hwnd), &rect) {
+		return false
+	}
+	monitor := win.MonitorFromWindow(win.HWND(hwnd), win.MONITOR_DEFAULTTONEAREST)
+	info := win.MONITORINFO{CbSize: uint32(unsafe.Sizeof(win.MONITORINFO{}))}
+	if !win.GetMonitorInfo(monitor, &info) {
+		return false
+	}
+	m := info.RcMonitor
+	return rect.Left <= m.Left && rect.Top <= m.Top && rect.Right >= m.Right && rect.Bottom >= m.Bottom
+}
+
+// processName returns the executable file name of the process owning hwnd.
+func processName(hwnd windows.HWND) (string, error) {
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return "", fmt.Errorf("failed to get window process: %w", err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(process)
+
+	var path [windows.MAX_PATH]uint16
+	size := uint32(len(path))
+	if err := windows.QueryFullProcessImageName(process, 0, &path[0], &size); err != nil {
+		return "", fmt.Errorf("failed to get process image name: %w", err)
+	}
+	return filepath.Base(windows.UTF16ToString(path[:size])), nil
+}